@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/graph"
+	"github.com/sid-technologies/pilum/lib/output"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+func AffectedCmd() *cobra.Command {
+	var since string
+
+	cmd := &cobra.Command{
+		Use:   "affected [services...]",
+		Short: "List the services affected by changes since a git ref",
+		Long: `List the services --only-changed would pick, and why: the changed files a service watches
+(its directory, watch_paths, Dockerfile and project recipe file, or the Go packages its
+main packages import, with the go detector), or the depends_on path to a changed service.
+With --json, the list is printed as JSON for CI scripts.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if !git.IsGitRepository() {
+				return errors.New("pilum affected needs a git repository")
+			}
+
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+
+			recipes, err := loadRecipes()
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			// Dependencies are followed through every service, then the selection is reported
+			filterOpts := filterOptions(ws, nil)
+			filterOpts.Names = nil
+			filterOpts.NoResolve = true // Only names, dependencies and paths are needed
+			all, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
+			if err != nil {
+				return errors.Wrap(err, "error finding services")
+			}
+
+			changes, err := serviceinfo.AffectedServices(all, since, changeDetectors(ws, recipes, since))
+			if err != nil {
+				return err
+			}
+
+			if selectors := append(slices.Clone(args), Selectors()...); len(selectors) > 0 {
+				selected, err := serviceinfo.Select(all, selectors, ws.Groups)
+				if err != nil {
+					return err
+				}
+				changes = slices.DeleteFunc(changes, func(change serviceinfo.Change) bool {
+					return !slices.ContainsFunc(selected, func(svc serviceinfo.ServiceInfo) bool { return svc.Name == change.Service })
+				})
+			}
+
+			if output.IsJSON() {
+				if changes == nil {
+					changes = []serviceinfo.Change{}
+				}
+				data, err := json.MarshalIndent(changes, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "error encoding affected services")
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			listAffected(changes)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&since, "since", "", "Git ref to compare against (default: main or master)")
+
+	return cmd
+}
+
+func listAffected(changes []serviceinfo.Change) {
+	if len(changes) == 0 {
+		output.Info("No services affected")
+		return
+	}
+
+	output.Header("%d service(s) affected:", len(changes))
+	for _, change := range changes {
+		fmt.Printf("  %s•%s %s\n", output.Primary, output.Reset, change.Service)
+		if change.Direct() {
+			for _, file := range change.Files {
+				fmt.Printf("      %schanged%s %s\n", output.Muted, output.Reset, file)
+			}
+			if len(change.Files) == 0 {
+				fmt.Printf("      %sreported by%s %s\n", output.Muted, output.Reset, strings.Join(change.Detectors, ", "))
+			}
+			continue
+		}
+		fmt.Printf("      %svia%s %s\n", output.Muted, output.Reset, graph.FormatPath(change.Dependency))
+	}
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(AffectedCmd())
+}
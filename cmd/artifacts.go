@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+
+	"github.com/spf13/cobra"
+)
+
+func ArtifactsCmd() *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "artifacts",
+		Short: "List the artifacts produced by the last run",
+		Long: "List the binaries, archives, images and other files recorded in " + artifact.DefaultPath + ".\n" +
+			"The manifest is written at the end of every run and covers the steps that succeeded.",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if _, err := os.Stat(file); os.IsNotExist(err) {
+				return errors.New("no artifact manifest at %s - run a build first", file)
+			}
+
+			manifest, err := artifact.Read(file)
+			if err != nil {
+				return err
+			}
+
+			if output.IsJSON() {
+				data, err := json.MarshalIndent(manifest, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "error encoding artifacts")
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			listArtifacts(manifest)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&file, "file", artifact.DefaultPath, "Artifact manifest to read")
+
+	return cmd
+}
+
+func listArtifacts(manifest *artifact.Manifest) {
+	if len(manifest.Artifacts) == 0 {
+		output.Info("No artifacts recorded")
+		return
+	}
+
+	output.Header("Found %d artifacts for tag %s:", len(manifest.Artifacts), manifest.Tag)
+	service := ""
+	for _, a := range manifest.Artifacts {
+		if a.Service != service {
+			if service != "" {
+				fmt.Println()
+			}
+			service = a.Service
+			fmt.Printf("  %s•%s %s\n", output.Primary, output.Reset, service)
+		}
+
+		location := a.Path
+		if a.Image != "" {
+			location = a.Image
+		}
+		fmt.Printf("      %s%-8s%s %s", output.Muted, a.Kind, output.Reset, location)
+		if a.Platform != "" {
+			fmt.Printf(" %s(%s)%s", output.Muted, a.Platform, output.Reset)
+		}
+		if a.Size > 0 {
+			fmt.Printf(" %s%s%s", output.Muted, formatSize(a.Size), output.Reset)
+		}
+		fmt.Println()
+	}
+}
+
+// formatSize formats a byte count for humans, e.g. "4.2 MB".
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(ArtifactsCmd())
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/cache"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+func CacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the step cache",
+		Long: "Steps that declare `inputs:` are skipped when their inputs, command and env are unchanged and their\n" +
+			"`outputs:` still exist. Results are recorded in " + cache.DefaultDir + "; use --no-cache to bypass it for one run.\n" +
+			"A remote cache configured in " + workspace.FileName + " shares results between machines.",
+	}
+
+	cmd.AddCommand(cacheCleanCmd())
+	cmd.AddCommand(cacheServeCmd())
+
+	return cmd
+}
+
+func cacheCleanCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove all cached step results",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			store := cache.NewStore(cache.DefaultDir)
+			count, err := store.Clean()
+			if err != nil {
+				return errors.Wrap(err, "error cleaning cache")
+			}
+
+			if count == 0 {
+				output.Info("Cache is already empty")
+				return nil
+			}
+			output.Success("Removed %d cached step(s) from %s", count, store.Dir())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func cacheServeCmd() *cobra.Command {
+	var addr, dir, tokenEnv string
+	var readOnly bool
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run a remote cache server backed by a directory",
+		Long: "Serve bundles from a directory over the remote cache protocol (GET/PUT /cache/<hash>).\n" +
+			"If the --token-env variable is set, clients must send its value as a bearer token.",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			token := os.Getenv(tokenEnv)
+			if token == "" {
+				output.Warning("%s is not set; the cache accepts unauthenticated requests", tokenEnv)
+			}
+
+			server := &http.Server{
+				Addr:              addr,
+				Handler:           cache.NewServer(dir, token, readOnly),
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			output.Info("Serving cache from %s on %s", dir, addr)
+			if err := server.ListenAndServe(); err != nil {
+				return errors.Wrap(err, "cache server failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":8080", "Address to listen on")
+	cmd.Flags().StringVar(&dir, "dir", ".pilum/remote-cache", "Directory to store bundles in")
+	cmd.Flags().StringVar(&tokenEnv, "token-env", workspace.DefaultCacheTokenEnv, "Environment variable holding the required bearer token")
+	cmd.Flags().BoolVar(&readOnly, "read-only", false, "Reject uploads")
+
+	return cmd
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(CacheCmd())
+}
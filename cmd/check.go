@@ -1,93 +1,219 @@
 package cmd
 
 import (
+	"sort"
+	"strings"
+
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/output"
 	"github.com/sid-technologies/pilum/lib/providers"
 	"github.com/sid-technologies/pilum/lib/recepie"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 	"github.com/sid-technologies/pilum/lib/suggest"
+	"github.com/sid-technologies/pilum/lib/workspace"
 
 	"github.com/spf13/cobra"
 )
 
 func CheckCmd() *cobra.Command {
+	var env string
+
 	cmd := &cobra.Command{
 		Use:     "check [services...]",
 		Aliases: []string{"validate"},
 		Short:   "Check the configuration of the services",
-		Long:    "Check the configuration of the services against their recipe requirements. Optionally specify service names to check only those services.",
+		Long: "Check the configuration of the services against their recipe requirements. Optionally specify service names to check only those services.\n" +
+			"The base configuration is checked, then each declared environment separately; use --env to check only one.",
 		RunE: func(_ *cobra.Command, args []string) error {
 			output.Info("Checking configuration of the services")
 
-			// Find services
-			services, err := serviceinfo.FindAndFilterServices(".", args)
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+
+			// Load recipes first: their fields are the keys pilum.yaml may use
+			recipes, err := loadRecipes()
 			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			// Find services, collecting problems in every pilum.yaml
+			filterOpts := filterOptions(ws, args)
+			filterOpts.Environment = env
+			filterOpts.KnownKeys = recipeKeys(recipes)
+			services, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
+			var problems serviceinfo.Problems
+			if err != nil && !errors.As(err, &problems) {
 				return errors.Wrap(err, "error finding services")
 			}
+			reported := make(map[string]bool)
+			failures := reportProblems(problems, reported)
 
 			if len(services) == 0 {
+				if failures > 0 {
+					return errors.New("found %d problem(s)", failures)
+				}
 				output.Warning("No services found")
 				return nil
 			}
 
-			// Load recipes
-			recipes, err := recepie.LoadEmbeddedRecipes()
-			if err != nil {
-				return errors.Wrap(err, "error loading recipes")
-			}
-
 			if len(recipes) == 0 {
 				output.Warning("No recipes found")
 				return nil
 			}
 
-			// Index recipes by provider-service key (e.g., "gcp-cloud-run")
-			recipeMap := make(map[string]*recepie.Recipe)
-			for i := range recipes {
-				key := recipes[i].Provider
-				if recipes[i].Service != "" {
-					key = recipes[i].Provider + "-" + recipes[i].Service
+			// The base configs, then each environment's (unless --env picked one)
+			var environments []string
+			if env == "" {
+				environments = declaredEnvironments(services, ws)
+			}
+			if len(environments) > 0 {
+				output.Header("Base configuration")
+			}
+			failures += checkServices(services, recipes)
+
+			for _, name := range environments {
+				output.Header("Environment %s", name)
+				filterOpts.Environment = name
+				envServices, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
+				var envProblems serviceinfo.Problems
+				if err != nil && !errors.As(err, &envProblems) {
+					return errors.Wrap(err, "error finding services")
 				}
-				recipeMap[key] = &recipes[i].Recipe
+				failures += reportProblems(envProblems, reported)
+				failures += checkServices(envServices, recipes)
 			}
 
-			// Validate each service
-			for _, service := range services {
-				recipeKey := service.RecipeKey()
-				output.Dimmed("  Checking service %s (recipe: %s)", service.Name, recipeKey)
+			if failures > 0 {
+				return errors.New("found %d problem(s)", failures)
+			}
+			output.Success("All services are valid")
+			return nil
+		},
+	}
 
-				// Base validation
-				if err := service.Validate(); err != nil {
-					return errors.Wrap(err, "error checking service %s", service.Name)
-				}
+	cmd.Flags().StringVar(&env, "env", "", "Only check this environment")
 
-				// Recipe-specific validation
-				recipe, exists := recipeMap[recipeKey]
-				if !exists {
-					// Use providers registry for suggestions
-					suggestion := suggest.FormatSuggestion(recipeKey, providers.GetAllRecipeKeys())
-					if suggestion != "" {
-						output.Warning("    No recipe found for '%s' - %s", recipeKey, suggestion)
-					} else {
-						output.Warning("    No recipe found for '%s'", recipeKey)
-					}
-					continue
-				}
+	return cmd
+}
+
+// reportProblems prints the problems not reported yet and returns how many
+// there were. Checking each environment finds the same file problems again.
+func reportProblems(problems serviceinfo.Problems, reported map[string]bool) int {
+	count := 0
+	for _, problem := range problems {
+		if reported[problem.String()] {
+			continue
+		}
+		reported[problem.String()] = true
+		output.Error(problem.String())
+		count++
+	}
+	return count
+}
+
+// declaredEnvironments returns every environment declared by the services or
+// the workspace, sorted.
+func declaredEnvironments(services []serviceinfo.ServiceInfo, ws *workspace.Config) []string {
+	seen := make(map[string]bool)
+	for _, svc := range services {
+		for _, name := range svc.Environments {
+			seen[name] = true
+		}
+	}
+	for name := range ws.Environments {
+		seen[name] = true
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
 
-				if err := recipe.ValidateService(&service); err != nil {
-					return errors.Wrap(err, "error checking service %s", service.Name)
+// recipeKeys returns the top-level pilum.yaml keys the recipes' fields use,
+// e.g. "cloud_run" for cloud_run.memory.
+func recipeKeys(recipes []recepie.RecipeInfo) []string {
+	seen := make(map[string]bool)
+	keys := []string{}
+	for _, info := range recipes {
+		for _, fields := range [][]recepie.Field{info.Recipe.RequiredFields, info.Recipe.OptionalFields} {
+			for _, field := range fields {
+				key, _, _ := strings.Cut(field.Name, ".")
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
 				}
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
 
-				output.Success("    %s: valid", service.Name)
+// checkServices validates services against their recipes and returns how
+// many failed. Every service is checked, even after a failure.
+func checkServices(services []serviceinfo.ServiceInfo, recipes []recepie.RecipeInfo) int {
+	// Index recipes by provider-service key (e.g., "gcp-cloud-run"), highest version first
+	recipeMap := recepie.Index(recipes, func(info recepie.RecipeInfo) string {
+		if info.Service != "" {
+			return info.Provider + "-" + info.Service
+		}
+		return info.Provider
+	})
+
+	failures := 0
+	for _, service := range services {
+		recipeKey := service.RecipeKey()
+		if service.Recipe != "" {
+			recipeKey = service.Recipe
+		}
+		output.Dimmed("  Checking service %s (recipe: %s)", service.Name, recipeKey)
+
+		// Base validation
+		if err := service.Validate(); err != nil {
+			output.Error("    %s (%s): %v", service.Name, service.File, err)
+			failures++
+			continue
+		}
+
+		// Services pinning a recipe must resolve to a matching version
+		if service.Recipe != "" {
+			info, err := recepie.Select(recipes, service.Recipe)
+			if err != nil {
+				output.Error("    %s (%s): %v", service.Name, service.File, err)
+				failures++
+				continue
+			}
+			recipeMap[recipeKey] = info
+		}
+
+		// Recipe-specific validation
+		info, exists := recipeMap[recipeKey]
+		if !exists {
+			// Use providers registry for suggestions
+			suggestion := suggest.FormatSuggestion(recipeKey, providers.GetAllRecipeKeys())
+			if suggestion != "" {
+				output.Warning("    No recipe found for '%s' - %s", recipeKey, suggestion)
+			} else {
+				output.Warning("    No recipe found for '%s'", recipeKey)
 			}
+			continue
+		}
 
-			output.Success("All services are valid")
-			return nil
-		},
+		if err := info.Recipe.ValidateService(&service); err != nil {
+			output.Error("    %s (%s): %v", service.Name, service.File, err)
+			failures++
+			continue
+		}
+
+		output.Success("    %s: valid", service.Name)
 	}
 
-	return cmd
+	return failures
 }
 
 // nolint: gochecknoinits // Standard Cobra pattern for initializing commands
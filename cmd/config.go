@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+func ConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect service configuration",
+		Long: "A service's effective config is its pilum.yaml merged over the defaults in " + workspace.FileName + ",\n" +
+			"then the --env overrides and --set values, with recipe defaults filling in any field nothing sets.",
+	}
+
+	cmd.AddCommand(configShowCmd())
+
+	return cmd
+}
+
+func configShowCmd() *cobra.Command {
+	var env, target string
+	var set []string
+
+	cmd := &cobra.Command{
+		Use:   "show <service>",
+		Short: "Print a service's effective config and where each value came from",
+		Long: "Print a service's fully resolved config. Each value is annotated with its source: a file position,\n" +
+			"an environment override, a targets entry, --set, a regions expansion (matrix) or a recipe default.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+
+			overrides, err := serviceinfo.ParseOverrides(set)
+			if err != nil {
+				return err
+			}
+
+			filterOpts := filterOptions(ws, args)
+			filterOpts.Environment = env
+			filterOpts.Overrides = overrides
+			filterOpts.Target = target
+			services, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
+			if err != nil {
+				return errors.Wrap(err, "error finding services")
+			}
+			if len(services) == 0 {
+				return errors.New("service '%s' not found", args[0])
+			}
+
+			recipes, err := loadRecipes()
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			sourceOpts := serviceinfo.SourceOptions{Environment: env, Overrides: overrides}
+			if _, err := os.Stat(workspace.FileName); err == nil {
+				sourceOpts.WorkspaceFile = workspace.FileName
+			}
+
+			reports := make([]configReport, 0, len(services))
+			for _, svc := range services {
+				if info, ok := recepie.ForService(recipes, svc); ok {
+					svc = info.Recipe.ApplyDefaults(svc)
+				}
+				report, err := newConfigReport(svc, sourceOpts)
+				if err != nil {
+					return err
+				}
+				reports = append(reports, report)
+			}
+
+			if output.IsJSON() {
+				data, err := json.MarshalIndent(reports, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "error encoding config")
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			for i, report := range reports {
+				if i > 0 {
+					fmt.Println()
+				}
+				showConfig(report)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "Apply this environment's overrides")
+	cmd.Flags().StringArrayVar(&set, "set", nil, "Override a value, e.g. --set cloud_run.min_instances=2 (repeatable)")
+	cmd.Flags().StringVar(&target, "target", "", "Only show this target of a service with a targets: list")
+
+	return cmd
+}
+
+// configReport is a service's effective config with the source of each value.
+type configReport struct {
+	Service     string         `json:"service"`
+	File        string         `json:"file"`
+	Environment string         `json:"environment,omitempty"`
+	Config      map[string]any `json:"config"`
+	Values      []configValue  `json:"values"`
+}
+
+// configValue is one leaf of the effective config.
+type configValue struct {
+	Key    string             `json:"key"`
+	Value  any                `json:"value"`
+	Source serviceinfo.Source `json:"source"`
+}
+
+func newConfigReport(svc serviceinfo.ServiceInfo, opts serviceinfo.SourceOptions) (configReport, error) {
+	sources, err := serviceinfo.ConfigSources(svc, opts)
+	if err != nil {
+		return configReport{}, err
+	}
+
+	config := configutil.CloneMap(svc.Config)
+	if svc.IsMultiRegion {
+		config["region"] = svc.Region
+	}
+
+	values := configutil.Flatten(config)
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	report := configReport{
+		Service:     svc.DisplayName(),
+		File:        svc.File,
+		Environment: svc.Environment,
+		Config:      config,
+		Values:      make([]configValue, 0, len(keys)),
+	}
+	for _, key := range keys {
+		report.Values = append(report.Values, configValue{Key: key, Value: values[key], Source: sources[key]})
+	}
+	return report, nil
+}
+
+// showConfig prints each leaf of a service's config with its source.
+func showConfig(report configReport) {
+	formatted := make([]string, len(report.Values))
+	keyWidth, valueWidth := 0, 0
+	for i, v := range report.Values {
+		formatted[i] = formatConfigValue(v.Value)
+		keyWidth = max(keyWidth, len(v.Key))
+		valueWidth = max(valueWidth, len(formatted[i]))
+	}
+
+	if report.Environment != "" {
+		output.Header("%s (%s, environment %s)", report.Service, report.File, report.Environment)
+	} else {
+		output.Header("%s (%s)", report.Service, report.File)
+	}
+	for i, v := range report.Values {
+		fmt.Printf("  %-*s  %-*s  %s# %s%s\n", keyWidth, v.Key, valueWidth, formatted[i], output.Muted, v.Source, output.Reset)
+	}
+}
+
+// formatConfigValue renders a config leaf on one line, e.g. "[us-east1, europe-west1]".
+func formatConfigValue(value any) string {
+	switch v := value.(type) {
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatConfigValue(item)
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any, map[any]any:
+		return "{}"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(ConfigCmd())
+}
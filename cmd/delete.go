@@ -7,6 +7,7 @@ import (
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/output"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/workspace"
 
 	"github.com/spf13/cobra"
 )
@@ -18,7 +19,12 @@ func DeleteBuildsCmd() *cobra.Command {
 		Short:   "Delete builds for services",
 		Long:    "Delete dist/ directories for one or more services, or all services if none specified.",
 		RunE: func(_ *cobra.Command, args []string) error {
-			services, err := serviceinfo.FindAndFilterServices(".", args)
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+
+			services, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOptions(ws, args))
 			if err != nil {
 				return errors.Wrap(err, "error finding services")
 			}
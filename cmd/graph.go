@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/graph"
+	"github.com/sid-technologies/pilum/lib/orchestrator"
+	"github.com/sid-technologies/pilum/lib/output"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/suggest"
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/spf13/cobra"
+)
+
+func GraphCmd() *cobra.Command {
+	var format, since string
+	var affectedBy []string
+	var steps bool
+
+	cmd := &cobra.Command{
+		Use:   "graph [services...]",
+		Short: "Print the service dependency graph",
+		Long: `Print the services and their depends_on edges as a Graphviz (dot), Mermaid or JSON graph.
+An edge from A to B means A depends on B. With --steps, each service's recipe steps are
+added in the order they run.
+
+--since and --affected-by highlight the services that changed and the services that depend
+on them. A dependency cycle is drawn in red and reported as an error.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if format == "" {
+				format = graph.FormatDOT
+				if output.IsJSON() {
+					format = graph.FormatJSON
+				}
+			}
+			if !slices.Contains(graph.Formats, format) {
+				return errors.New("unknown graph format '%s' (use %s)", format, strings.Join(graph.Formats, ", "))
+			}
+
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+
+			// Changes are detected across every service, then the selection is drawn
+			filterOpts := filterOptions(ws, nil)
+			filterOpts.Names = nil
+			filterOpts.NoResolve = true // Only names, dependencies and paths are needed
+			all, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
+			if err != nil {
+				return errors.Wrap(err, "error finding services")
+			}
+
+			status, err := graphStatus(ws, all, since, affectedBy)
+			if err != nil {
+				return err
+			}
+
+			selected := all
+			if selectors := append(slices.Clone(args), Selectors()...); len(selectors) > 0 {
+				selected, err = serviceinfo.Select(all, selectors, ws.Groups)
+				if err != nil {
+					return err
+				}
+			}
+
+			export := serviceinfo.BuildDependencyGraph(selected).Export(status)
+			if steps {
+				if err := addGraphSteps(&export, selected); err != nil {
+					return err
+				}
+			}
+
+			rendered, err := export.Render(format)
+			if err != nil {
+				return err
+			}
+			fmt.Print(rendered)
+
+			if len(export.Cycle) > 0 {
+				return errors.New("circular dependency detected: %s", graph.FormatPath(export.Cycle))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "", "Output format: dot, mermaid or json (default dot, or json with --json)")
+	cmd.Flags().StringVar(&since, "since", "", "Highlight services changed since this git ref, and their dependents")
+	cmd.Flags().StringArrayVar(&affectedBy, "affected-by", nil, "Highlight this service and the services that depend on it (repeatable)")
+	cmd.Flags().BoolVar(&steps, "steps", false, "Add each service's recipe steps as nodes")
+
+	return cmd
+}
+
+// graphStatus returns the graph status of each changed or affected service:
+// those changed since the ref or named by --affected-by, and their dependents.
+func graphStatus(ws *workspace.Config, services []serviceinfo.ServiceInfo, since string, affectedBy []string) (map[string]string, error) {
+	g := serviceinfo.BuildDependencyGraph(services)
+
+	changed := make(map[string]bool)
+	for _, name := range affectedBy {
+		if !g.HasNode(name) {
+			if suggestion := suggest.FormatSuggestion(name, g.Nodes()); suggestion != "" {
+				return nil, errors.New("service '%s' not found - %s", name, suggestion)
+			}
+			return nil, errors.New("service '%s' not found", name)
+		}
+		changed[name] = true
+	}
+
+	if since != "" {
+		if !git.IsGitRepository() {
+			return nil, errors.New("--since needs a git repository")
+		}
+		recipes, err := loadRecipes()
+		if err != nil {
+			return nil, errors.Wrap(err, "error loading recipes")
+		}
+		changes, err := serviceinfo.AffectedServices(services, since, changeDetectors(ws, recipes, since))
+		if err != nil {
+			return nil, err
+		}
+		for _, change := range changes {
+			if change.Direct() {
+				changed[change.Service] = true
+			}
+		}
+	}
+
+	status := make(map[string]string)
+	for name := range g.PropagateChanges(changed) {
+		status[name] = graph.StatusAffected
+	}
+	for name := range changed {
+		status[name] = graph.StatusChanged
+	}
+	return status, nil
+}
+
+// addGraphSteps adds the recipe steps of each service to export. Services
+// deployed to several regions or targets share a node, and show the steps of
+// the first.
+func addGraphSteps(export *graph.Export, services []serviceinfo.ServiceInfo) error {
+	recipes, err := loadRecipes()
+	if err != nil {
+		return errors.Wrap(err, "error loading recipes")
+	}
+
+	runner := orchestrator.NewRunner(services, recipes, orchestrator.RunnerOptions{Hermetic: true})
+	added := make(map[string]bool)
+	for _, svc := range services {
+		if added[svc.Name] {
+			continue
+		}
+		added[svc.Name] = true
+		export.AddSteps(svc.Name, runner.StepNames(svc))
+	}
+	return nil
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(GraphCmd())
+}
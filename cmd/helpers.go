@@ -1,13 +1,19 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
+	"github.com/sid-technologies/pilum/lib/cache"
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/orchestrator"
 	"github.com/sid-technologies/pilum/lib/output"
 	"github.com/sid-technologies/pilum/lib/recepie"
+	"github.com/sid-technologies/pilum/lib/registry"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/workspace"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,31 +21,46 @@ import (
 
 // deploymentOptions holds parsed flag values for deployment commands.
 type deploymentOptions struct {
-	Tag         string
-	Debug       bool
-	Timeout     int
-	Retries     int
-	DryRun      bool
-	MaxWorkers  int
-	OnlyTags    []string
-	ExcludeTags []string
-	OnlyChanged bool
-	Since       string
+	Tag           string
+	Debug         bool
+	Timeout       int
+	Retries       int
+	DryRun        bool
+	MaxWorkers    int
+	OnlyTags      []string
+	ExcludeTags   []string
+	OnlyChanged   bool
+	Since         string
+	NoCache       bool
+	CacheReadOnly bool // Fetch from the remote cache without uploading to it
+	ReuseImages   bool // Use image names recorded in the artifact manifest
+	Env           string
+	Set           []string // --set key=value overrides
+	Target        string   // Only deploy this targets entry
 }
 
+// envKey is the viper key of the --env flag. Binding it as "env" would let
+// viper.AutomaticEnv read $ENV, which many shells set.
+const envKey = "pilum-env"
+
 // getDeploymentOptions extracts all standard deployment flags from viper.
 func getDeploymentOptions() deploymentOptions {
 	return deploymentOptions{
-		Tag:         viper.GetString("tag"),
-		Debug:       viper.GetBool("debug"),
-		Timeout:     viper.GetInt("timeout"),
-		Retries:     viper.GetInt("retries"),
-		DryRun:      viper.GetBool("dry-run"),
-		MaxWorkers:  viper.GetInt("max-workers"),
-		OnlyTags:    parseCommaSeparated(viper.GetString("only-tags")),
-		ExcludeTags: parseCommaSeparated(viper.GetString("exclude-tags")),
-		OnlyChanged: viper.GetBool("only-changed"),
-		Since:       viper.GetString("since"),
+		Tag:           viper.GetString("tag"),
+		Debug:         viper.GetBool("debug"),
+		Timeout:       viper.GetInt("timeout"),
+		Retries:       viper.GetInt("retries"),
+		DryRun:        viper.GetBool("dry-run"),
+		MaxWorkers:    viper.GetInt("max-workers"),
+		OnlyTags:      parseCommaSeparated(viper.GetString("only-tags")),
+		ExcludeTags:   parseCommaSeparated(viper.GetString("exclude-tags")),
+		OnlyChanged:   viper.GetBool("only-changed"),
+		Since:         viper.GetString("since"),
+		NoCache:       viper.GetBool("no-cache"),
+		CacheReadOnly: viper.GetBool("cache-read-only"),
+		Env:           viper.GetString(envKey),
+		Set:           viper.GetStringSlice("set"),
+		Target:        viper.GetString("target"),
 	}
 }
 
@@ -54,6 +75,8 @@ func (o deploymentOptions) toRunnerOptions() orchestrator.RunnerOptions {
 		MaxWorkers:  o.MaxWorkers,
 		OnlyTags:    o.OnlyTags,
 		ExcludeTags: o.ExcludeTags,
+		NoCache:     o.NoCache,
+		ReuseImages: o.ReuseImages,
 	}
 }
 
@@ -69,6 +92,10 @@ func bindFlagsForDeploymentCommands(cmd *cobra.Command) error {
 		"exclude-tags",
 		"only-changed",
 		"since",
+		"no-cache",
+		"cache-read-only",
+		"set",
+		"target",
 	}
 
 	for _, flag := range flagBindings {
@@ -79,6 +106,12 @@ func bindFlagsForDeploymentCommands(cmd *cobra.Command) error {
 		}
 	}
 
+	if f := cmd.Flags().Lookup("env"); f != nil {
+		if err := viper.BindPFlag(envKey, f); err != nil {
+			return errors.Wrap(err, "error binding env flag")
+		}
+	}
+
 	return nil
 }
 
@@ -94,22 +127,44 @@ func addCommandFlags(cmd *cobra.Command, includeDryRun bool) {
 	cmd.Flags().String("exclude-tags", "", "Exclude steps with these tags (comma-separated)")
 	cmd.Flags().Bool("only-changed", false, "Only deploy services with changes since base branch")
 	cmd.Flags().String("since", "", "Git ref to compare against (default: main or master)")
+	cmd.Flags().Bool("no-cache", false, "Run every step, even if its inputs are unchanged")
+	cmd.Flags().Bool("cache-read-only", false, "Fetch from the remote cache without uploading to it")
+	cmd.Flags().String("env", "", "Environment whose overrides to apply (from environments: blocks)")
+	cmd.Flags().StringArray("set", nil, "Override a config value, e.g. --set cloud_run.min_instances=2 (repeatable)")
+	cmd.Flags().String("target", "", "Only deploy this target of services with a targets: list (e.g. lambda)")
 
 	if includeDryRun {
 		cmd.Flags().BoolP("dry-run", "D", false, "Perform a dry run without executing the build")
 	}
 }
 
-// runPipeline executes the common deployment pipeline: find services → load recipes → run.
+// runPipeline executes the common deployment pipeline: load recipes → find services → run.
 // The noServicesMsg is shown as a warning if no services are found.
 func runPipeline(args []string, opts deploymentOptions, noServicesMsg string) error {
-	filterOpts := serviceinfo.FilterOptions{
-		Names:       args,
-		OnlyChanged: opts.OnlyChanged,
-		Since:       opts.Since,
-		NoGitIgnore: NoGitIgnore(),
+	ws, err := workspace.Load(".")
+	if err != nil {
+		return err
 	}
 
+	overrides, err := serviceinfo.ParseOverrides(opts.Set)
+	if err != nil {
+		return err
+	}
+
+	// Load recipes first: a change to a service's recipe counts as a change to the service
+	recipes, err := loadRecipes()
+	if err != nil {
+		return errors.Wrap(err, "error loading recipes")
+	}
+
+	filterOpts := filterOptions(ws, args)
+	filterOpts.OnlyChanged = opts.OnlyChanged
+	filterOpts.Since = opts.Since
+	filterOpts.Environment = opts.Env
+	filterOpts.Overrides = overrides
+	filterOpts.Target = opts.Target
+	filterOpts.ChangeDetectors = changeDetectors(ws, recipes, opts.Since)
+
 	services, err := serviceinfo.FindAndFilterServicesWithOptions(".", filterOpts)
 	if err != nil {
 		return errors.Wrap(err, "error finding services")
@@ -120,20 +175,169 @@ func runPipeline(args []string, opts deploymentOptions, noServicesMsg string) er
 		return nil
 	}
 
-	recipes, err := recepie.LoadEmbeddedRecipes()
-	if err != nil {
-		return errors.Wrap(err, "error loading recipes")
-	}
-
 	if len(recipes) == 0 {
 		output.Warning("No recipes found")
 		return nil
 	}
 
-	runner := orchestrator.NewRunner(services, recipes, opts.toRunnerOptions())
+	runnerOpts := opts.toRunnerOptions()
+	runnerOpts.RemoteCache = remoteCache(ws, opts)
+
+	runner := orchestrator.NewRunner(services, recipes, runnerOpts)
 	return runner.Run()
 }
 
+// filterOptions returns the service filter for names (plus any --select
+// selectors) with the workspace's defaults, groups, shared environments and discovery settings applied.
+func filterOptions(ws *workspace.Config, names []string) serviceinfo.FilterOptions {
+	return serviceinfo.FilterOptions{
+		Names:        append(slices.Clone(names), Selectors()...),
+		NoGitIgnore:  NoGitIgnore(),
+		Environments: ws.Environments,
+		Defaults:     ws.Defaults,
+		Groups:       ws.Groups,
+		MaxDepth:     ws.Discovery.MaxDepth,
+		Include:      ws.Discovery.Include,
+		Exclude:      ws.Discovery.Exclude,
+		Filenames:    ws.Discovery.Filenames,
+	}
+}
+
+// changeDetectors returns the workspace's change detectors, which decide
+// which services changed since the ref.
+func changeDetectors(ws *workspace.Config, recipes []recepie.RecipeInfo, since string) []serviceinfo.ChangeDetector {
+	var detectors []serviceinfo.ChangeDetector
+	for _, name := range ws.ChangeDetection.DetectorNames() {
+		switch name {
+		case workspace.DetectorPaths:
+			detectors = append(detectors, serviceinfo.PathDetector{
+				WatchPaths: ws.WatchPaths,
+				WatchFiles: buildWatchFiles(recipes),
+			})
+		case workspace.DetectorGo:
+			detectors = append(detectors, serviceinfo.GoDetector{Dir: ws.ChangeDetection.GoDir})
+		case workspace.DetectorCommand:
+			detectors = append(detectors, serviceinfo.CommandDetector{Command: ws.ChangeDetection.Command, Since: since})
+		}
+	}
+	return detectors
+}
+
+// buildWatchFiles returns a serviceinfo.PathDetector WatchFiles function: a
+// change to the project recipe file a service uses, or to the Dockerfile its
+// docker/build steps build from, affects the service. Embedded and user
+// recipe files live outside the project and are never reported.
+func buildWatchFiles(recipes []recepie.RecipeInfo) func(serviceinfo.ServiceInfo) []string {
+	cmdRegistry := registry.NewCommandRegistry()
+	registry.RegisterDefaultHandlers(cmdRegistry)
+
+	return func(svc serviceinfo.ServiceInfo) []string {
+		info, ok := recepie.ForService(recipes, svc)
+		if !ok {
+			return nil
+		}
+
+		var files []string
+		if info.Source == recepie.LayerProject && info.Path != "" {
+			files = append(files, relativePath(info.Path))
+		}
+		for _, step := range info.Recipe.Steps {
+			id := step.Uses
+			if id == "" {
+				id, _ = cmdRegistry.AliasID(step.Name, svc.Provider)
+			}
+			if id != "docker/build" {
+				continue
+			}
+			if _, set := step.With["dockerfile"]; !set && svc.Template == "" {
+				continue // No template to build from
+			}
+			dockerfile := registry.Dockerfile(svc, orchestrator.DefaultTemplatePath, step.With)
+			if strings.Contains(dockerfile, "${") {
+				output.Debugf("Not watching Dockerfile %s of %s: it depends on ${...} values", dockerfile, svc.DisplayName())
+				continue
+			}
+			files = append(files, relativePath(dockerfile))
+		}
+		return files
+	}
+}
+
+// relativePath returns path relative to the working directory if it's absolute.
+func relativePath(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil {
+				return rel
+			}
+		}
+	}
+	return path
+}
+
+// remoteCache returns a client for the workspace's remote cache, or nil if
+// none is configured.
+func remoteCache(ws *workspace.Config, opts deploymentOptions) *cache.Remote {
+	remote := ws.Cache.Remote
+	if remote == nil || opts.NoCache {
+		return nil
+	}
+
+	return cache.NewRemote(cache.RemoteOptions{
+		URL:      remote.URL,
+		Token:    remote.Token(),
+		ReadOnly: remote.ReadOnly || opts.CacheReadOnly,
+	})
+}
+
+// loadRecipes loads recipes from all layers: embedded, remote, user, then project,
+// with `extends:` resolved.
+func loadRecipes() ([]recepie.RecipeInfo, error) {
+	opts, err := recipeLoadOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	recipes, err := recepie.LoadLayeredRecipes(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdRegistry := registry.NewCommandRegistry()
+	registry.RegisterDefaultHandlers(cmdRegistry)
+	if err := recepie.CheckHandlers(recipes, cmdRegistry.HasHandler, cmdRegistry.HandlerIDs()); err != nil {
+		return nil, err
+	}
+
+	return recipes, nil
+}
+
+// recipeLoadOptions returns the recipe layer locations.
+// Remote sources come from pilum.workspace.yaml and are verified against pilum.lock.
+// The project layer is the --recipe-path directory, or ./recepies if not set.
+func recipeLoadOptions() (recepie.LoadOptions, error) {
+	opts := recepie.DefaultLoadOptions()
+
+	ws, err := workspace.Load(".")
+	if err != nil {
+		return opts, err
+	}
+	opts.RemoteDirs, err = workspace.RecipeDirs(".", ws)
+	if err != nil {
+		return opts, err
+	}
+
+	if path := RecipePath(); path != "" {
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return opts, errors.New("recipe path '%s' is not a directory", path)
+		}
+		opts.ProjectDir = path
+	}
+
+	return opts, nil
+}
+
 // parseCommaSeparated splits a comma-separated string into a slice, trimming whitespace.
 func parseCommaSeparated(s string) []string {
 	if s == "" {
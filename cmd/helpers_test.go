@@ -3,6 +3,9 @@ package cmd
 import (
 	"testing"
 
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
 	"github.com/stretchr/testify/require"
 )
 
@@ -128,3 +131,24 @@ func TestDeploymentOptionsToRunnerOptionsDefaults(t *testing.T) {
 	require.Nil(t, runnerOpts.OnlyTags)
 	require.Nil(t, runnerOpts.ExcludeTags)
 }
+
+func TestBuildWatchFiles(t *testing.T) {
+	t.Parallel()
+
+	recipes := []recepie.RecipeInfo{
+		{Provider: "gcp", Source: recepie.LayerEmbedded, Path: "gcp.yaml", Recipe: recepie.Recipe{Steps: []recepie.RecipeStep{
+			{Name: "build docker image"},
+			{Name: "deploy", Command: "gcloud run deploy"},
+		}}},
+		{Provider: "custom", Source: recepie.LayerProject, Path: "recepies/custom.yaml", Recipe: recepie.Recipe{Steps: []recepie.RecipeStep{
+			{Name: "image", Uses: "docker/build", With: map[string]any{"dockerfile": "docker/${name}.Dockerfile"}},
+			{Name: "package", Uses: "docker/build", With: map[string]any{"dockerfile": "docker/Dockerfile"}},
+		}}},
+	}
+	watchFiles := buildWatchFiles(recipes)
+
+	require.Equal(t, []string{"./_templates/gcp-cloud-run"}, watchFiles(serviceinfo.ServiceInfo{Name: "api", Provider: "gcp", Template: "gcp-cloud-run"}))
+	require.Empty(t, watchFiles(serviceinfo.ServiceInfo{Name: "api", Provider: "gcp"}), "no template")
+	require.Equal(t, []string{"recepies/custom.yaml", "docker/Dockerfile"}, watchFiles(serviceinfo.ServiceInfo{Name: "api", Provider: "custom"}))
+	require.Empty(t, watchFiles(serviceinfo.ServiceInfo{Name: "api", Provider: "aws"}), "no recipe")
+}
@@ -54,7 +54,7 @@ func runInit(providerFlag, serviceFlag string) error {
 	}
 
 	// Load available recipes
-	recipes, err := recepie.LoadEmbeddedRecipes()
+	recipes, err := loadRecipes()
 	if err != nil {
 		return errors.Wrap(err, "failed to load recipes")
 	}
@@ -4,25 +4,29 @@ import (
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/path"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/workspace"
 
 	"github.com/spf13/cobra"
 )
 
 func ListCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:     "list",
+		Use:     "list [selectors...]",
 		Aliases: []string{"ls"},
-		Short:   "List all discovered services",
-		RunE: func(_ *cobra.Command, _ []string) error {
+		Short:   "List discovered services",
+		Long:    "List discovered services, or those matching the selectors (see --select).",
+		RunE: func(_ *cobra.Command, args []string) error {
 			root, err := path.FindProjectRoot()
 			if err != nil {
 				return errors.Wrap(err, "error finding project root")
 			}
 
-			opts := serviceinfo.DefaultDiscoveryOptions()
-			opts.NoGitIgnore = NoGitIgnore()
+			ws, err := workspace.Load(root)
+			if err != nil {
+				return err
+			}
 
-			services, err := serviceinfo.FindServicesWithOptions(root, opts)
+			services, err := serviceinfo.FindAndFilterServicesWithOptions(root, filterOptions(ws, args))
 			if err != nil {
 				return errors.Wrap(err, "error finding services")
 			}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/plugin"
+
+	"github.com/spf13/cobra"
+)
+
+// describeTimeout bounds each plugin's describe request in `plugin list`.
+const describeTimeout = 5 * time.Second
+
+func PluginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "plugin",
+		Aliases: []string{"plugins"},
+		Short:   "Inspect step handler plugins",
+		Long: "Step handler plugins are executables that generate commands for recipe steps with\n" +
+			"`handler: exec:<executable>`. Plugins named " + plugin.NamePrefix + "* on PATH are discovered automatically.",
+	}
+
+	cmd.AddCommand(pluginListCmd())
+
+	return cmd
+}
+
+func pluginListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List handler plugins found on PATH",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			plugins := plugin.Discover()
+
+			summaries := make([]pluginSummary, 0, len(plugins))
+			for _, p := range plugins {
+				summary := pluginSummary{Name: p.Name, Path: p.Path, Handler: plugin.HandlerPrefix + p.Name}
+				description, err := plugin.Describe(p.Path, describeTimeout)
+				if err != nil {
+					summary.Error = err.Error()
+				}
+				summary.Description = description
+				summaries = append(summaries, summary)
+			}
+
+			if output.IsJSON() {
+				data, err := json.MarshalIndent(summaries, "", "  ")
+				if err != nil {
+					return errors.Wrap(err, "error encoding plugins")
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			listPlugins(summaries)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// pluginSummary is the JSON form of a plugin in `plugin list`.
+type pluginSummary struct {
+	Name        string `json:"name"`
+	Handler     string `json:"handler"`
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func listPlugins(plugins []pluginSummary) {
+	if len(plugins) == 0 {
+		output.Info("No %s* plugins found on PATH", plugin.NamePrefix)
+		return
+	}
+
+	output.Header("Found %d plugins:", len(plugins))
+	for _, p := range plugins {
+		fmt.Printf("  %s•%s %s\n", output.Primary, output.Reset, p.Name)
+		if p.Description != "" {
+			fmt.Printf("      %s\n", p.Description)
+		}
+		fmt.Printf("      %sHandler:%s %s\n", output.Muted, output.Reset, p.Handler)
+		fmt.Printf("      %sPath:%s    %s\n", output.Muted, output.Reset, p.Path)
+		if p.Error != "" {
+			fmt.Printf("      %sError:%s   %s\n", output.Muted, output.Reset, p.Error)
+		}
+		fmt.Println()
+	}
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(PluginCmd())
+}
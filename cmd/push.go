@@ -20,6 +20,8 @@ func PushCmd() *cobra.Command {
 			if len(opts.OnlyTags) == 0 {
 				opts.OnlyTags = []string{"push"}
 			}
+			// Push the images an earlier build recorded
+			opts.ReuseImages = true
 
 			return runPipeline(args, opts, "No services found to push")
 		},
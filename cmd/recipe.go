@@ -0,0 +1,616 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/gitignore"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	"github.com/sid-technologies/pilum/lib/recipetest"
+	"github.com/sid-technologies/pilum/lib/registry"
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func RecipeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "recipe",
+		Aliases: []string{"recipes"},
+		Short:   "Inspect deployment recipes",
+		Long:    "Inspect the recipes available to this project. Recipes are loaded in layers: embedded, remote sources (pilum.workspace.yaml), user (~/.config/pilum/recipes), then project (--recipe-path or ./recepies).",
+	}
+
+	cmd.AddCommand(recipeListCmd())
+	cmd.AddCommand(recipeShowCmd())
+	cmd.AddCommand(recipeLintCmd())
+	cmd.AddCommand(recipeFetchCmd())
+	cmd.AddCommand(recipeTestCmd())
+	cmd.AddCommand(recipeNewCmd())
+
+	return cmd
+}
+
+func recipeListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List effective recipes with their versions, fields and steps",
+		RunE: func(_ *cobra.Command, _ []string) error {
+			recipes, err := loadRecipes()
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			if output.IsJSON() {
+				return printRecipesJSON(recipes)
+			}
+
+			listRecipes(recipes)
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func recipeShowCmd() *cobra.Command {
+	var resolved bool
+
+	cmd := &cobra.Command{
+		Use:   "show <recipe>[@version]",
+		Short: "Print a recipe definition",
+		Long: "Print a recipe as YAML. Without a version, the highest version is shown; use name@2 to pick another.\n" +
+			"With --resolved, `extends:` is flattened and step patches are applied.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			opts, err := recipeLoadOptions()
+			if err != nil {
+				return err
+			}
+
+			recipes, err := recepie.LoadRecipeLayers(opts)
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			if resolved {
+				recipes, err = recepie.ResolveRecipes(recipes)
+				if err != nil {
+					return errors.Wrap(err, "error resolving recipes")
+				}
+			}
+
+			info, err := recepie.Select(recipes, args[0])
+			if err != nil {
+				return err
+			}
+
+			data, err := yaml.Marshal(info.Recipe)
+			if err != nil {
+				return errors.Wrap(err, "error encoding recipe")
+			}
+
+			output.Dimmed("# %s (%s)", info.Path, info.Source)
+			if others := otherVersions(recipes, info); len(others) > 0 {
+				output.Dimmed("# other versions: %s", strings.Join(others, ", "))
+			}
+			fmt.Print(string(data))
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&resolved, "resolved", false, "Show the recipe with inheritance resolved")
+
+	return cmd
+}
+
+func recipeLintCmd() *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "lint [paths...]",
+		Short: "Validate recipe files against the recipe schema",
+		Long: `Validate recipe files against the recipe schema. Paths may be files or directories;
+with no paths, the project recipe directory (--recipe-path or ./recepies) is linted.
+
+Reports unknown keys, wrong value types, invalid execution modes, duplicate steps,
+steps with neither a command nor a registered handler, and unused fields.`,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if format == "" {
+				format = recepie.FormatText
+				if output.IsJSON() {
+					format = recepie.FormatJSON
+				}
+			}
+
+			paths := args
+			if len(paths) == 0 {
+				opts, err := recipeLoadOptions()
+				if err != nil {
+					return err
+				}
+				paths = []string{opts.ProjectDir}
+			}
+
+			files, err := expandRecipePaths(paths)
+			if err != nil {
+				return err
+			}
+
+			cmdRegistry := registry.NewCommandRegistry()
+			registry.RegisterDefaultHandlers(cmdRegistry)
+			lintOpts := recepie.LintOptions{HasHandler: cmdRegistry.HasHandler, HandlerIDs: cmdRegistry.HandlerIDs()}
+
+			var diags []recepie.Diagnostic
+			for _, file := range files {
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return errors.Wrap(err, "error reading %s", file)
+				}
+				diags = append(diags, recepie.LintRecipe(data, file, lintOpts)...)
+			}
+
+			report, err := recepie.FormatDiagnostics(diags, format, version)
+			if err != nil {
+				return err
+			}
+			fmt.Print(report)
+
+			if recepie.HasErrors(diags) {
+				return errors.New("recipe lint failed")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&format, "format", "f", "", "Output format: text, json or sarif")
+
+	return cmd
+}
+
+func recipeFetchCmd() *cobra.Command {
+	var update bool
+
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Fetch remote recipe sources and write pilum.lock",
+		Long: "Fetch the recipe_sources declared in " + workspace.FileName + " into " + workspace.CacheDir + " and record\n" +
+			"the exact commit or checksum of each in " + workspace.LockFileName + ". Sources already in the lockfile are\n" +
+			"fetched at their locked version; use --update to re-resolve refs.",
+		Args: cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			ws, err := workspace.Load(".")
+			if err != nil {
+				return err
+			}
+			if len(ws.RecipeSources) == 0 {
+				output.Warning("No recipe_sources in %s", workspace.FileName)
+				return nil
+			}
+
+			lock, err := workspace.Fetch(".", ws, workspace.FetchOptions{Update: update})
+			if err != nil {
+				return err
+			}
+
+			for _, src := range lock.Sources {
+				version := src.Commit
+				if version == "" {
+					version = "sha256:" + src.SHA256
+				}
+				output.Success("%s %s%s%s", src.Name, output.Muted, version, output.Reset)
+			}
+			output.Info("Wrote %s", workspace.LockFileName)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&update, "update", false, "Re-resolve refs and ignore versions recorded in the lockfile")
+
+	return cmd
+}
+
+func recipeTestCmd() *cobra.Command {
+	var fixtures string
+	var update bool
+
+	cmd := &cobra.Command{
+		Use:   "test <recipe>[@version]",
+		Short: "Check the commands a recipe generates against golden files",
+		Long: `Plan the recipe for every fixture pilum.yaml under the fixtures directory and compare
+each step's command, working directory, env and cache inputs and outputs with the
+fixture's golden.yaml.
+
+Fixtures are planned with the tag "` + recipetest.Tag + `", a placeholder git commit and no environment
+variables, so golden files only change when the recipe or the ingredients do.
+The fixtures directory defaults to <recipe-path>/testdata/<recipe>.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			recipes, err := loadRecipes()
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+
+			info, err := recepie.Select(recipes, args[0])
+			if err != nil {
+				return err
+			}
+
+			if fixtures == "" {
+				opts, err := recipeLoadOptions()
+				if err != nil {
+					return err
+				}
+				fixtures = filepath.Join(opts.ProjectDir, "testdata", info.Recipe.Name)
+			}
+
+			results, err := recipetest.Run(info, fixtures, recipetest.Options{Update: update})
+			if err != nil {
+				return err
+			}
+
+			return reportRecipeTests(info.Recipe.Name, results)
+		},
+	}
+
+	cmd.Flags().StringVar(&fixtures, "fixtures", "", "Fixtures directory (default: <recipe-path>/testdata/<recipe>)")
+	cmd.Flags().BoolVar(&update, "update", false, "Regenerate golden files instead of comparing")
+
+	return cmd
+}
+
+// reportRecipeTests prints each fixture's result and fails if any fixture failed.
+func reportRecipeTests(recipe string, results []recipetest.Result) error {
+	failed := 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			output.Warning("%s: %v", r.Fixture, r.Err)
+		case r.Status == recipetest.StatusFail:
+			failed++
+			output.Warning("%s: generated commands differ from %s", r.Fixture, recipetest.GoldenFileName)
+			fmt.Print(r.Diff)
+		case r.Status == recipetest.StatusUpdated:
+			output.Success("%s: updated %s", r.Fixture, recipetest.GoldenFileName)
+		default:
+			output.Success("%s", r.Fixture)
+		}
+	}
+
+	if failed > 0 {
+		return errors.New("%d of %d fixtures failed for recipe '%s' - run with --update if the change is intended",
+			failed, len(results), recipe)
+	}
+	output.Info("%d fixtures passed for recipe '%s'", len(results), recipe)
+	return nil
+}
+
+func recipeNewCmd() *cobra.Command {
+	var provider, service string
+
+	cmd := &cobra.Command{
+		Use:   "new <name>",
+		Short: "Scaffold a recipe with a test fixture",
+		Long: `Create <recipe-path>/<name>.yaml with example fields and steps, plus a fixture in
+<recipe-path>/testdata/<name>/basic with its golden file. Edit the recipe, then run
+'pilum recipe test <name> --update' and review the golden file changes.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			name := args[0]
+			if problem := recepie.InvalidName(name); problem != "" {
+				return errors.New("%s", problem)
+			}
+			if provider == "" {
+				provider = name
+			}
+
+			opts, err := recipeLoadOptions()
+			if err != nil {
+				return err
+			}
+
+			recipes, err := loadRecipes()
+			if err != nil {
+				return errors.Wrap(err, "error loading recipes")
+			}
+			for _, r := range recipes {
+				if r.Recipe.Name == name {
+					return errors.New("recipe '%s' already exists (%s layer, %s)", name, r.Source, r.Path)
+				}
+			}
+
+			recipePath := filepath.Join(opts.ProjectDir, name+".yaml")
+			fixtureDir := filepath.Join(opts.ProjectDir, "testdata", name, "basic")
+			for _, path := range []string{recipePath, fixtureDir} {
+				if _, err := os.Stat(path); err == nil {
+					return errors.New("%s already exists", path)
+				}
+			}
+
+			if err := os.MkdirAll(fixtureDir, 0o755); err != nil {
+				return errors.Wrap(err, "error creating "+fixtureDir)
+			}
+			files := map[string]string{
+				recipePath:                              generateRecipeYAML(name, provider, service),
+				filepath.Join(fixtureDir, "pilum.yaml"): generateFixtureYAML(name, provider),
+			}
+			for path, content := range files {
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil { //nolint:gosec // recipes are committed to the project
+					return errors.Wrap(err, "error writing "+path)
+				}
+			}
+
+			// Generate the golden file from the new recipe
+			loaded, err := recepie.LoadRecipesFromDirectory(opts.ProjectDir)
+			if err != nil {
+				return err
+			}
+			info, err := recepie.Select(loaded, name)
+			if err != nil {
+				return err
+			}
+			results, err := recipetest.Run(info, filepath.Dir(fixtureDir), recipetest.Options{Update: true})
+			if err != nil {
+				return err
+			}
+			for _, r := range results {
+				if r.Err != nil {
+					return r.Err
+				}
+			}
+
+			output.Success("Created %s", recipePath)
+			output.Success("Created %s", filepath.Join(fixtureDir, "pilum.yaml"))
+			output.Success("Created %s", filepath.Join(fixtureDir, recipetest.GoldenFileName))
+
+			// Fixtures are pilum.yaml files too; keep them out of discovery
+			pattern, err := ignoreFixtures(filepath.Join(opts.ProjectDir, "testdata"))
+			if err != nil {
+				return err
+			}
+			if pattern != "" {
+				output.Success("Added %s to .pilumignore", pattern)
+			}
+			output.Dimmed("Run 'pilum recipe test %s' after changing the recipe", name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&provider, "provider", "", "Provider the recipe deploys to (default: the recipe name)")
+	cmd.Flags().StringVar(&service, "service", "", "Service type identifier")
+
+	return cmd
+}
+
+// ignoreFixtures adds dir to the .pilumignore in the current directory,
+// unless it's already ignored or outside the current directory. It returns
+// the pattern it added, if any.
+func ignoreFixtures(dir string) (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get working directory")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid recipe path")
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", nil
+	}
+	rel = filepath.ToSlash(rel)
+
+	patterns, err := gitignore.ReadFile(".pilumignore", "")
+	if err != nil {
+		return "", err
+	}
+	ignore := &gitignore.Matcher{}
+	ignore.Add(patterns...)
+	if ignore.Ignored(rel, true) {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(".pilumignore")
+	if err != nil && !os.IsNotExist(err) {
+		return "", errors.Wrap(err, "error reading .pilumignore")
+	}
+	content := string(data)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += rel + "/\n"
+	if err := os.WriteFile(".pilumignore", []byte(content), 0o644); err != nil { //nolint:gosec // committed to the project
+		return "", errors.Wrap(err, "error writing .pilumignore")
+	}
+	return rel + "/", nil
+}
+
+// generateRecipeYAML returns a starter recipe that passes 'pilum recipe lint'.
+func generateRecipeYAML(name, provider, service string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("name: %s\n", name))
+	sb.WriteString("version: 1\n")
+	sb.WriteString(fmt.Sprintf("description: Deploy to %s\n", provider))
+	sb.WriteString(fmt.Sprintf("provider: %s\n", provider))
+	if service != "" {
+		sb.WriteString(fmt.Sprintf("service: %s\n", service))
+	}
+
+	sb.WriteString(`
+required_fields:
+  - name: region
+    description: Region to deploy to
+    type: string
+
+optional_fields:
+  - name: replicas
+    description: Number of instances to run
+    type: int
+    default: "1"
+    min: 1
+
+steps:
+  - name: build
+    command: ["echo", "build ${name}:${tag}"]
+    execution_mode: service_dir
+    tags: [build]
+
+  - name: deploy
+    command: ["echo", "deploy ${name}:${tag} to ${region} with ${replicas} replicas"]
+    execution_mode: root
+    tags: [deploy]
+`)
+
+	return sb.String()
+}
+
+// generateFixtureYAML returns the service used by a new recipe's basic fixture.
+func generateFixtureYAML(name, provider string) string {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("# Fixture for 'pilum recipe test %s'\n", name))
+	sb.WriteString("name: example\n")
+	sb.WriteString(fmt.Sprintf("recipe: %s\n", name))
+	sb.WriteString(fmt.Sprintf("provider: %s\n", provider))
+	sb.WriteString("region: us-east-1\n")
+
+	return sb.String()
+}
+
+// expandRecipePaths expands directories into the recipe YAML files they contain.
+func expandRecipePaths(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, errors.New("recipe path '%s' does not exist", p)
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading %s", p)
+		}
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	return files, nil
+}
+
+// otherVersions lists the versions of info's recipe other than info itself.
+func otherVersions(recipes []recepie.RecipeInfo, info recepie.RecipeInfo) []string {
+	var versions []string
+	for _, r := range recipes {
+		if r.Recipe.Name == info.Recipe.Name && r.Recipe.ID() != info.Recipe.ID() {
+			versions = append(versions, r.Recipe.VersionString())
+		}
+	}
+	return versions
+}
+
+// recipeSummary is the JSON representation of an effective recipe.
+type recipeSummary struct {
+	Name           string   `json:"name"`
+	Version        string   `json:"version"`
+	RequiresPilum  string   `json:"requires_pilum,omitempty"`
+	Description    string   `json:"description,omitempty"`
+	Provider       string   `json:"provider"`
+	Service        string   `json:"service,omitempty"`
+	Source         string   `json:"source"`
+	Path           string   `json:"path"`
+	RequiredFields []string `json:"required_fields"`
+	OptionalFields []string `json:"optional_fields"`
+	Steps          []string `json:"steps"`
+}
+
+func printRecipesJSON(recipes []recepie.RecipeInfo) error {
+	summaries := make([]recipeSummary, 0, len(recipes))
+	for _, r := range recipes {
+		summaries = append(summaries, recipeSummary{
+			Name:           r.Recipe.Name,
+			Version:        r.Recipe.VersionString(),
+			RequiresPilum:  r.Recipe.RequiresPilum,
+			Description:    r.Recipe.Description,
+			Provider:       r.Provider,
+			Service:        r.Service,
+			Source:         r.Source,
+			Path:           r.Path,
+			RequiredFields: fieldNames(r.Recipe.RequiredFields),
+			OptionalFields: fieldNames(r.Recipe.OptionalFields),
+			Steps:          stepNames(r.Recipe.Steps),
+		})
+	}
+
+	data, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error encoding recipes")
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+func listRecipes(recipes []recepie.RecipeInfo) {
+	output.Header("Found %d recipes:", len(recipes))
+	for _, r := range recipes {
+		fmt.Printf("  %s•%s %s%s@%s [%s]%s\n", output.Primary, output.Reset, r.Recipe.Name,
+			output.Muted, r.Recipe.VersionString(), r.Source, output.Reset)
+		if r.Recipe.Description != "" {
+			fmt.Printf("      %s\n", r.Recipe.Description)
+		}
+		fmt.Printf("      %sProvider:%s %s\n", output.Muted, output.Reset, r.Provider)
+		if r.Service != "" {
+			fmt.Printf("      %sService:%s  %s\n", output.Muted, output.Reset, r.Service)
+		}
+		if r.Recipe.RequiresPilum != "" {
+			fmt.Printf("      %sPilum:%s    %s\n", output.Muted, output.Reset, r.Recipe.RequiresPilum)
+		}
+		if names := fieldNames(r.Recipe.RequiredFields); len(names) > 0 {
+			fmt.Printf("      %sRequired:%s %s\n", output.Muted, output.Reset, strings.Join(names, ", "))
+		}
+		if names := fieldNames(r.Recipe.OptionalFields); len(names) > 0 {
+			fmt.Printf("      %sOptional:%s %s\n", output.Muted, output.Reset, strings.Join(names, ", "))
+		}
+		if names := stepNames(r.Recipe.Steps); len(names) > 0 {
+			fmt.Printf("      %sSteps:%s    %s\n", output.Muted, output.Reset, strings.Join(names, " → "))
+		}
+		fmt.Printf("      %sPath:%s     %s\n", output.Muted, output.Reset, r.Path)
+		fmt.Println()
+	}
+}
+
+func fieldNames(fields []recepie.Field) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func stepNames(steps []recepie.RecipeStep) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+	return names
+}
+
+// nolint: gochecknoinits // Standard Cobra pattern for initializing commands
+func init() {
+	rootCmd.AddCommand(RecipeCmd())
+}
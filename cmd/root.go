@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/recepie"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -18,6 +19,8 @@ var (
 	quietFlag       bool
 	jsonFlag        bool
 	noGitIgnoreFlag bool
+	recipePathFlag  string
+	selectFlag      []string
 )
 
 // version is set at build time via ldflags:
@@ -50,6 +53,7 @@ func Execute() {
 func init() {
 	cobra.OnInitialize(initConfig, initOutputMode)
 	rootCmd.Version = version
+	recepie.PilumVersion = version
 	rootCmd.SetVersionTemplate("pilum {{ .Version }}\n")
 	defaultHelpFunc := rootCmd.HelpFunc()
 	rootCmd.SetHelpFunc(func(cmd *cobra.Command, args []string) {
@@ -65,6 +69,8 @@ func init() {
 	rootCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "Minimal output (CI-friendly)")
 	rootCmd.PersistentFlags().BoolVar(&jsonFlag, "json", false, "Output as JSON for scripting")
 	rootCmd.PersistentFlags().BoolVar(&noGitIgnoreFlag, "no-gitignore", false, "Don't read .gitignore for ignore patterns")
+	rootCmd.PersistentFlags().StringArrayVar(&selectFlag, "select", nil, "Select services by name, glob, key=value, label:key=value, @group or !negation (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&recipePathFlag, "recipe-path", "", "Path to project recipe definitions (default: ./recepies)")
 
 	// Mark flags as mutually exclusive
 	rootCmd.MarkFlagsMutuallyExclusive("verbose", "quiet", "json")
@@ -100,3 +106,13 @@ func initConfig() {
 func NoGitIgnore() bool {
 	return noGitIgnoreFlag
 }
+
+// Selectors returns the values of the --select flag.
+func Selectors() []string {
+	return selectFlag
+}
+
+// RecipePath returns the value of the --recipe-path flag.
+func RecipePath() string {
+	return recipePathFlag
+}
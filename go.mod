@@ -3,6 +3,7 @@ module github.com/sid-technologies/pilum
 go 1.23.0
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.9.1
 	github.com/stretchr/testify v1.10.0
@@ -1,5 +1,52 @@
 package docker
 
+import (
+	"encoding/json"
+	"os/exec"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
 func GenerateDockerPushCommand(imageName string) []string {
 	return []string{"docker", "push", imageName}
 }
+
+// ImageDigest returns the registry digest ("sha256:...") of a pushed image,
+// from the repo digests docker records when it pushes or pulls an image. An
+// image that was only built locally has none, and yields "".
+func ImageDigest(imageName string) (string, error) {
+	out, err := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", imageName).Output()
+	if err != nil {
+		return "", errors.Wrap(err, "docker image inspect failed for "+imageName)
+	}
+	var repoDigests []string
+	if err := json.Unmarshal(out, &repoDigests); err != nil {
+		return "", errors.Wrap(err, "failed to parse repo digests of "+imageName)
+	}
+	return RepoDigest(imageName, repoDigests), nil
+}
+
+// RepoDigest picks the digest of imageName's repository from an image's repo
+// digests ("repository@sha256:..."), or returns "" if it has none.
+func RepoDigest(imageName string, repoDigests []string) string {
+	repo := repository(imageName)
+	for _, repoDigest := range repoDigests {
+		name, digest, ok := strings.Cut(repoDigest, "@")
+		if ok && repository(name) == repo {
+			return digest
+		}
+	}
+	return ""
+}
+
+// repository strips the tag and digest from an image reference, and the
+// Docker Hub prefixes docker leaves out of repo digests.
+func repository(imageName string) string {
+	name, _, _ := strings.Cut(imageName, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "docker.io/")
+	return strings.TrimPrefix(name, "library/")
+}
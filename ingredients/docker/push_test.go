@@ -77,3 +77,20 @@ func TestGenerateDockerPushCommandFormat(t *testing.T) {
 	require.Equal(t, "docker", cmd[0])
 	require.Equal(t, "push", cmd[1])
 }
+
+func TestRepoDigest(t *testing.T) {
+	t.Parallel()
+
+	repoDigests := []string{
+		"mirror.example.com/myservice@sha256:aaa",
+		"gcr.io/my-project/myservice@sha256:bbb",
+		"username/image@sha256:ccc",
+	}
+
+	require.Equal(t, "sha256:bbb", docker.RepoDigest("gcr.io/my-project/myservice:v1.0.0", repoDigests))
+	require.Equal(t, "sha256:aaa", docker.RepoDigest("mirror.example.com/myservice", repoDigests))
+	require.Equal(t, "sha256:ccc", docker.RepoDigest("docker.io/username/image:latest", repoDigests))
+	require.Equal(t, "sha256:bbb", docker.RepoDigest("gcr.io/my-project/myservice@sha256:old", repoDigests))
+	require.Empty(t, docker.RepoDigest("gcr.io/other/myservice:v1.0.0", repoDigests))
+	require.Empty(t, docker.RepoDigest("localhost:5000/myservice", nil))
+}
@@ -6,10 +6,11 @@ import (
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 )
 
-// GenerateArchiveCommand creates archives for all built binaries.
+// GenerateArchiveCommand creates archives for all built binaries. The
+// binaries are kept, so a cached build step isn't rerun to recreate them.
 func GenerateArchiveCommand(svc serviceinfo.ServiceInfo, tag string, outputDir string) string {
 	pattern := fmt.Sprintf("%s_%s_*", svc.Name, tag)
-	return fmt.Sprintf(`cd %s && for f in %s; do [ -f "$f" ] && tar -czf "${f}.tar.gz" "$f" && rm "$f"; done`,
+	return fmt.Sprintf(`cd %s && for f in %s; do case "$f" in *.tar.gz) continue ;; esac; [ -f "$f" ] && tar -czf "${f}.tar.gz" "$f"; done`,
 		outputDir, pattern)
 }
 
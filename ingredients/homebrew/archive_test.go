@@ -51,8 +51,10 @@ func TestGenerateArchiveCommand(t *testing.T) {
 			require.Contains(t, result, "tar -czf")
 			require.Contains(t, result, ".tar.gz")
 
-			// Should remove original binary after archiving
-			require.Contains(t, result, "rm \"$f\"")
+			// Should keep the binaries, which the build step caches, and
+			// not archive existing archives
+			require.NotContains(t, result, "rm \"$f\"")
+			require.Contains(t, result, "*.tar.gz) continue")
 		})
 	}
 }
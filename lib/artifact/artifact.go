@@ -0,0 +1,188 @@
+// Package artifact records what a run produced in an artifact manifest.
+//
+// Recipe steps and step handlers declare artifacts as Specs: a file glob or an
+// image reference. After a step succeeds the runner resolves its specs into
+// Artifacts, with a digest and size for files, and at the end of the run
+// writes them to dist/artifacts.json. Later runs and commands read the
+// manifest instead of recomputing artifact names.
+package artifact
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// DefaultPath is the manifest location, relative to the project root.
+const DefaultPath = "dist/artifacts.json"
+
+// Artifact kinds.
+const (
+	KindBinary   = "binary"
+	KindArchive  = "archive"
+	KindImage    = "image"
+	KindChecksum = "checksum"
+	KindFormula  = "formula"
+	KindFile     = "file"
+)
+
+// Kinds lists the valid artifact kinds.
+var Kinds = []string{KindBinary, KindArchive, KindImage, KindChecksum, KindFormula, KindFile}
+
+// Spec declares an artifact a step produces. Exactly one of Path and Image is set.
+type Spec struct {
+	Kind     string `yaml:"kind" json:"kind"`
+	Path     string `yaml:"path,omitempty" json:"path,omitempty"`         // Glob relative to the step's working directory
+	Image    string `yaml:"image,omitempty" json:"image,omitempty"`       // Image reference
+	Platform string `yaml:"platform,omitempty" json:"platform,omitempty"` // e.g. "linux/amd64"; inferred from file names if empty
+}
+
+// Specs collects the specs a step handler declares. A nil *Specs discards them.
+type Specs struct {
+	list []Spec
+}
+
+// Add declares artifacts.
+func (s *Specs) Add(specs ...Spec) {
+	if s == nil {
+		return
+	}
+	s.list = append(s.list, specs...)
+}
+
+// List returns the declared specs.
+func (s *Specs) List() []Spec {
+	if s == nil {
+		return nil
+	}
+	return s.list
+}
+
+// Artifact is a file or image a run produced.
+type Artifact struct {
+	Kind     string `json:"kind"`
+	Service  string `json:"service"`
+	Step     string `json:"step"`
+	Path     string `json:"path,omitempty"`  // Relative to the project root
+	Image    string `json:"image,omitempty"` // Image reference
+	Digest   string `json:"digest,omitempty"`
+	Size     int64  `json:"size,omitempty"`
+	Platform string `json:"platform,omitempty"`
+}
+
+// key identifies an artifact across runs.
+func (a Artifact) key() string {
+	return a.Service + "\x00" + a.Kind + "\x00" + a.Path + "\x00" + a.Image
+}
+
+// Manifest lists the artifacts produced for a tag.
+type Manifest struct {
+	Tag       string     `json:"tag"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// Read loads a manifest. A missing file yields an empty manifest.
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+path)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse "+path)
+	}
+	return &m, nil
+}
+
+// Write saves a manifest, creating its directory if needed.
+func Write(path string, m *Manifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create "+filepath.Dir(path))
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode artifact manifest")
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil { //nolint:gosec // the manifest is meant to be shared
+		return errors.Wrap(err, "failed to write "+path)
+	}
+	return nil
+}
+
+// Merge adds artifacts, replacing earlier entries for the same service, kind
+// and path or image, and sorts the manifest.
+func (m *Manifest) Merge(artifacts []Artifact) {
+	index := make(map[string]int, len(m.Artifacts))
+	for i, a := range m.Artifacts {
+		index[a.key()] = i
+	}
+	for _, a := range artifacts {
+		if i, ok := index[a.key()]; ok {
+			m.Artifacts[i] = a
+			continue
+		}
+		index[a.key()] = len(m.Artifacts)
+		m.Artifacts = append(m.Artifacts, a)
+	}
+
+	sort.SliceStable(m.Artifacts, func(i, j int) bool {
+		a, b := m.Artifacts[i], m.Artifacts[j]
+		if a.Service != b.Service {
+			return a.Service < b.Service
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Path+a.Image < b.Path+b.Image
+	})
+}
+
+// Prune removes file artifacts that no longer exist, e.g. binaries a later
+// step packed into an archive. Paths are resolved relative to root.
+func (m *Manifest) Prune(root string) {
+	kept := m.Artifacts[:0]
+	for _, a := range m.Artifacts {
+		if a.Path != "" {
+			path := a.Path
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root, filepath.FromSlash(path))
+			}
+			if _, err := os.Stat(path); err != nil {
+				continue
+			}
+		}
+		kept = append(kept, a)
+	}
+	m.Artifacts = kept
+}
+
+// Find returns a service's artifacts of a kind.
+func (m *Manifest) Find(service, kind string) []Artifact {
+	var found []Artifact
+	for _, a := range m.Artifacts {
+		if a.Service == service && a.Kind == kind {
+			found = append(found, a)
+		}
+	}
+	return found
+}
+
+// platformPattern matches an os/arch pair in a file name, e.g. "app_v1_linux_amd64.tar.gz".
+var platformPattern = regexp.MustCompile(`(darwin|linux|windows|freebsd)[_-](amd64|arm64|386|arm)\b`)
+
+// PlatformFromName infers "os/arch" from a file name, or returns "".
+func PlatformFromName(name string) string {
+	match := platformPattern.FindStringSubmatch(filepath.Base(name))
+	if match == nil {
+		return ""
+	}
+	return match[1] + "/" + match[2]
+}
@@ -0,0 +1,92 @@
+package artifact_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWrite(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "dist", "artifacts.json")
+
+	manifest, err := artifact.Read(path)
+	require.NoError(t, err)
+	require.Empty(t, manifest.Artifacts)
+
+	manifest = &artifact.Manifest{
+		Tag: "v1.0.0",
+		Artifacts: []artifact.Artifact{
+			{Kind: artifact.KindImage, Service: "api", Step: "build docker image", Image: "gcr.io/p/api:v1.0.0"},
+		},
+	}
+	require.NoError(t, artifact.Write(path, manifest))
+
+	got, err := artifact.Read(path)
+	require.NoError(t, err)
+	require.Equal(t, manifest, got)
+
+	require.NoError(t, os.WriteFile(path, []byte("{"), 0o600))
+	_, err = artifact.Read(path)
+	require.Error(t, err)
+}
+
+func TestManifestMerge(t *testing.T) {
+	t.Parallel()
+
+	manifest := &artifact.Manifest{}
+	manifest.Merge([]artifact.Artifact{
+		{Kind: artifact.KindImage, Service: "worker", Image: "worker:v1"},
+		{Kind: artifact.KindBinary, Service: "api", Path: "dist/api", Digest: "sha256:old"},
+		{Kind: artifact.KindArchive, Service: "api", Path: "dist/api.tar.gz"},
+	})
+	manifest.Merge([]artifact.Artifact{
+		{Kind: artifact.KindBinary, Service: "api", Path: "dist/api", Digest: "sha256:new"},
+	})
+
+	require.Len(t, manifest.Artifacts, 3)
+	require.Equal(t, "dist/api.tar.gz", manifest.Artifacts[0].Path)
+	require.Equal(t, "sha256:new", manifest.Artifacts[1].Digest)
+	require.Equal(t, "worker", manifest.Artifacts[2].Service)
+
+	found := manifest.Find("api", artifact.KindBinary)
+	require.Len(t, found, 1)
+	require.Empty(t, manifest.Find("worker", artifact.KindBinary))
+}
+
+func TestManifestPrune(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "kept.tar.gz"), []byte("x"), 0o600))
+
+	manifest := &artifact.Manifest{Artifacts: []artifact.Artifact{
+		{Kind: artifact.KindArchive, Service: "api", Path: "kept.tar.gz"},
+		{Kind: artifact.KindBinary, Service: "api", Path: "gone"},
+		{Kind: artifact.KindImage, Service: "api", Image: "api:v1"},
+	}}
+	manifest.Prune(root)
+
+	require.Len(t, manifest.Artifacts, 2)
+	require.Equal(t, "kept.tar.gz", manifest.Artifacts[0].Path)
+	require.Equal(t, "api:v1", manifest.Artifacts[1].Image)
+}
+
+func TestPlatformFromName(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"dist/pilum_v1.0.0_darwin_arm64.tar.gz": "darwin/arm64",
+		"pilum_v1.0.0_linux_amd64":              "linux/amd64",
+		"app-windows-386.exe":                   "windows/386",
+		"checksums.txt":                         "",
+	}
+	for name, want := range tests {
+		require.Equal(t, want, artifact.PlatformFromName(name), name)
+	}
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// WriteBundle writes files, given as slash-separated paths relative to base,
+// to w as a zstd-compressed tar archive.
+func WriteBundle(w io.Writer, base string, files []string) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return errors.Wrap(err, "failed to write bundle")
+	}
+	tw := tar.NewWriter(zw)
+
+	for _, file := range files {
+		if err := addToBundle(tw, base, file); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return errors.Wrap(err, "failed to write bundle")
+	}
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(err, "failed to write bundle")
+	}
+	return nil
+}
+
+func addToBundle(tw *tar.Writer, base, file string) error {
+	f, err := os.Open(filepath.Join(base, filepath.FromSlash(file)))
+	if err != nil {
+		return errors.Wrap(err, "failed to read "+file)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return errors.Wrap(err, "failed to read "+file)
+	}
+
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     file,
+		Mode:     int64(info.Mode().Perm()),
+		Size:     info.Size(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return errors.Wrap(err, "failed to write bundle")
+	}
+	if _, err := io.Copy(tw, f); err != nil {
+		return errors.Wrap(err, "failed to write "+file+" to bundle")
+	}
+	return nil
+}
+
+// ExtractBundle extracts a bundle written by WriteBundle into base and returns
+// the extracted files. Entries that aren't regular files, would land outside
+// base or don't match the outputs globs (see ExpandGlobs) are rejected, so a
+// bundle can only write files the step could have produced.
+func ExtractBundle(r io.Reader, base string, outputs []string) ([]string, error) {
+	for _, pattern := range outputs {
+		if err := ValidateOutputGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+	allowed, err := compileGlobSet(outputs)
+	if err != nil {
+		return nil, errors.Wrap(err, "outputs")
+	}
+
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid bundle")
+	}
+	defer zr.Close()
+
+	var files []string
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid bundle")
+		}
+
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(name)) {
+			return nil, errors.New("bundle entry '%s' is not a file inside the output directory", hdr.Name)
+		}
+		if !allowed.match(name) {
+			return nil, errors.New("bundle entry '%s' does not match the step's outputs", hdr.Name)
+		}
+		if err := extractFile(tr, filepath.Join(base, filepath.FromSlash(name)), os.FileMode(hdr.Mode).Perm()); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+	return files, nil
+}
+
+func extractFile(r io.Reader, dest string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create "+filepath.Dir(dest))
+	}
+
+	// Replace rather than overwrite, so a running binary can be swapped out
+	tmp := dest + ".pilum-tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return errors.Wrap(err, "failed to write "+dest)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to write "+dest)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to write "+dest)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return errors.Wrap(err, "failed to write "+dest)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package cache records step results so unchanged steps can be skipped.
+//
+// A step that declares `inputs:` is hashed together with its resolved command,
+// working directory and environment. After the step succeeds, the hash and a
+// digest of each file matched by its `outputs:` are stored in an Entry. The
+// next run skips the step if the hash is unchanged and its outputs are still
+// on disk with the same contents.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// DefaultDir is the local cache directory, relative to the project root.
+const DefaultDir = ".pilum/cache"
+
+// ConfigInputPrefix marks an input that is a config key rather than a glob,
+// e.g. "config:cloud_run.memory".
+const ConfigInputPrefix = "config:"
+
+// Entry is the cached result of a step for one service.
+type Entry struct {
+	Service string            `json:"service"`
+	Step    string            `json:"step"`
+	Hash    string            `json:"hash"`
+	Outputs map[string]string `json:"outputs,omitempty"` // Output file -> content digest
+}
+
+// Store keeps entries as JSON files in a directory.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store in dir. The directory is created on first write.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the store's directory.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Get returns the entry for a service's step, if one was stored.
+func (s *Store) Get(service, step string) (Entry, bool) {
+	data, err := os.ReadFile(s.path(service, step))
+	if err != nil {
+		return Entry{}, false
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Entry{}, false // Corrupt entries are treated as misses and overwritten
+	}
+	return entry, true
+}
+
+// Put stores an entry, replacing any previous entry for the same service and step.
+func (s *Store) Put(entry Entry) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return errors.Wrap(err, "failed to create cache directory "+s.dir)
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode cache entry")
+	}
+
+	// Write atomically so parallel steps never read a partial entry
+	path := s.path(entry.Service, entry.Step)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil { //nolint:gosec // cache entries are not secret
+		return errors.Wrap(err, "failed to write cache entry")
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(err, "failed to write cache entry")
+	}
+	return nil
+}
+
+// Clean removes every entry and returns how many were removed.
+func (s *Store) Clean() (int, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read cache directory "+s.dir)
+	}
+
+	count := 0
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".json") {
+			count++
+		}
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		return 0, errors.Wrap(err, "failed to remove cache directory "+s.dir)
+	}
+	return count, nil
+}
+
+// path returns the file an entry is stored in.
+func (s *Store) path(service, step string) string {
+	sum := sha256.Sum256([]byte(service + "\x00" + step))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".json")
+}
@@ -0,0 +1,102 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	store := cache.NewStore(filepath.Join(t.TempDir(), "cache"))
+
+	_, ok := store.Get("api", "build")
+	require.False(t, ok)
+
+	entry := cache.Entry{Service: "api", Step: "build", Hash: "abc", Outputs: map[string]string{"dist/api": "123"}}
+	require.NoError(t, store.Put(entry))
+	require.NoError(t, store.Put(cache.Entry{Service: "api (us-east1)", Step: "build", Hash: "def"}))
+
+	got, ok := store.Get("api", "build")
+	require.True(t, ok)
+	require.Equal(t, entry, got)
+
+	// Entries are per service and step
+	_, ok = store.Get("api", "deploy")
+	require.False(t, ok)
+
+	entry.Hash = "xyz"
+	require.NoError(t, store.Put(entry))
+	got, _ = store.Get("api", "build")
+	require.Equal(t, "xyz", got.Hash)
+
+	count, err := store.Clean()
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	_, ok = store.Get("api", "build")
+	require.False(t, ok)
+
+	count, err = store.Clean()
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	spec := cache.Spec{
+		Command: []string{"go", "build"},
+		Cwd:     "services/api",
+		Env:     map[string]string{"CGO_ENABLED": "0", "GOOS": "linux"},
+		Config:  map[string]any{"build.language": "go"},
+		Files:   map[string]string{"main.go": "aaa"},
+	}
+	hash, err := cache.Hash(spec)
+	require.NoError(t, err)
+
+	same := spec
+	same.Env = map[string]string{"GOOS": "linux", "CGO_ENABLED": "0"}
+	again, err := cache.Hash(same)
+	require.NoError(t, err)
+	require.Equal(t, hash, again)
+
+	for _, change := range []func(s *cache.Spec){
+		func(s *cache.Spec) { s.Command = []string{"go", "build", "-race"} },
+		func(s *cache.Spec) { s.Cwd = "." },
+		func(s *cache.Spec) { s.Env = map[string]string{"CGO_ENABLED": "1", "GOOS": "linux"} },
+		func(s *cache.Spec) { s.Config = map[string]any{"build.language": "rust"} },
+		func(s *cache.Spec) { s.Files = map[string]string{"main.go": "bbb"} },
+	} {
+		changed := spec
+		change(&changed)
+		other, err := cache.Hash(changed)
+		require.NoError(t, err)
+		require.NotEqual(t, hash, other)
+	}
+}
+
+func TestDigestGlobs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "dist/api", "dist/api.sha256")
+
+	digests, err := cache.DigestGlobs(dir, []string{"dist/*"})
+	require.NoError(t, err)
+	require.Len(t, digests, 2)
+
+	again, err := cache.DigestGlobs(dir, []string{"dist"})
+	require.NoError(t, err)
+	require.True(t, cache.SameDigests(digests, again))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "dist", "api"), []byte("rebuilt"), 0o600))
+	changed, err := cache.DigestGlobs(dir, []string{"dist/*"})
+	require.NoError(t, err)
+	require.False(t, cache.SameDigests(digests, changed))
+	require.False(t, cache.SameDigests(digests, map[string]string{}))
+}
@@ -0,0 +1,197 @@
+package cache
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/gitignore"
+)
+
+// glob is a compiled input or output pattern.
+type glob struct {
+	pattern string
+	re      *regexp.Regexp
+	prefix  string // Leading directories without wildcards, walked instead of the whole base
+	exclude bool
+}
+
+// ValidateGlob returns an error if pattern is not a valid glob.
+func ValidateGlob(pattern string) error {
+	_, err := compileGlob(pattern)
+	return err
+}
+
+// ValidateOutputGlob returns an error if pattern is not a valid output glob:
+// a valid glob that stays inside the step's working directory, since a cache
+// bundle can only restore files below it.
+func ValidateOutputGlob(pattern string) error {
+	g, err := compileGlob(pattern)
+	if err != nil {
+		return err
+	}
+	if g.pattern == ".." || strings.HasPrefix(g.pattern, "../") {
+		return errors.New("output '%s' must be inside the working directory", pattern)
+	}
+	return nil
+}
+
+// ExpandGlobs returns the files under base matched by patterns, as sorted
+// slash-separated paths relative to base. Patterns are relative to base and use
+// "/" separators: "*" and "?" don't match "/", "**" matches any number of
+// directories, and "[...]" matches a character class. A pattern that matches
+// a directory matches every file below it, and a pattern prefixed with "!"
+// excludes what it matches. Patterns may start with "../" to match files
+// outside base (e.g. shared inputs); such files are returned with the "../".
+func ExpandGlobs(base string, patterns []string) ([]string, error) {
+	set, err := compileGlobSet(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make(map[string]bool)
+	for _, g := range set.includes {
+		root := filepath.Join(base, filepath.FromSlash(g.prefix))
+		if _, err := os.Stat(root); err != nil {
+			continue // Nothing to match
+		}
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if d.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			rel, err := filepath.Rel(base, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if g.matches(rel) {
+				matched[rel] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to expand '"+g.pattern+"'")
+		}
+	}
+
+	files := make([]string, 0, len(matched))
+	for file := range matched {
+		if !set.excluded(file) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// ExpandOutputs is ExpandGlobs for a step's outputs, which must pass
+// ValidateOutputGlob.
+func ExpandOutputs(base string, patterns []string) ([]string, error) {
+	for _, pattern := range patterns {
+		if err := ValidateOutputGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return ExpandGlobs(base, patterns)
+}
+
+// globSet is a compiled list of patterns, as taken by ExpandGlobs.
+type globSet struct {
+	includes, excludes []glob
+}
+
+func compileGlobSet(patterns []string) (globSet, error) {
+	var set globSet
+	for _, pattern := range patterns {
+		g, err := compileGlob(pattern)
+		if err != nil {
+			return globSet{}, err
+		}
+		if g.exclude {
+			set.excludes = append(set.excludes, g)
+		} else {
+			set.includes = append(set.includes, g)
+		}
+	}
+	return set, nil
+}
+
+// match reports whether ExpandGlobs would return file, a slash-separated path
+// relative to base, if it existed.
+func (s globSet) match(file string) bool {
+	for _, g := range s.includes {
+		if g.matches(file) {
+			return !s.excluded(file)
+		}
+	}
+	return false
+}
+
+func (s globSet) excluded(file string) bool {
+	for _, g := range s.excludes {
+		if g.matches(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the pattern matches file or one of its parent directories.
+func (g glob) matches(file string) bool {
+	for p := file; p != "." && p != "/" && p != ""; p = path.Dir(p) {
+		if g.re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// compileGlob compiles a pattern with gitignore.CompileGlob, the glob syntax
+// shared with watch paths and ignore files.
+func compileGlob(pattern string) (glob, error) {
+	g := glob{}
+	pattern = strings.TrimSpace(pattern)
+	if rest, ok := strings.CutPrefix(pattern, "!"); ok {
+		g.exclude = true
+		pattern = rest
+	}
+	if pattern == "" {
+		return g, errors.New("empty glob pattern")
+	}
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if pattern == "." {
+		pattern = "**" // The whole base directory
+	}
+	g.pattern = pattern
+	if strings.HasPrefix(pattern, "/") {
+		return g, errors.New("glob '%s' must be relative", pattern)
+	}
+
+	// Walk from the longest leading path without wildcards
+	var prefix []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(segment, "*?[") {
+			break
+		}
+		prefix = append(prefix, segment)
+	}
+	g.prefix = strings.Join(prefix, "/")
+
+	re, err := gitignore.CompileGlob(pattern)
+	if err != nil {
+		return g, err
+	}
+	g.re = re
+	return g, nil
+}
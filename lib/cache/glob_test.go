@@ -0,0 +1,87 @@
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/cache"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(file), 0o600))
+	}
+}
+
+func TestExpandGlobs(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	writeFiles(t, root,
+		"shared/util.go",
+		"svc/main.go",
+		"svc/main_test.go",
+		"svc/go.mod",
+		"svc/internal/db/db.go",
+		"svc/static/app.js",
+		"svc/static/img/logo.png",
+		"svc/.git/HEAD",
+	)
+	base := filepath.Join(root, "svc")
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+	}{
+		{"star", []string{"*.go"}, []string{"main.go", "main_test.go"}},
+		{"double star", []string{"**/*.go"}, []string{"internal/db/db.go", "main.go", "main_test.go"}},
+		{"exclude", []string{"**/*.go", "!**/*_test.go"}, []string{"internal/db/db.go", "main.go"}},
+		{"literal", []string{"go.mod"}, []string{"go.mod"}},
+		{"directory", []string{"static"}, []string{"static/app.js", "static/img/logo.png"}},
+		{"question and class", []string{"static/[a-z]pp.j?"}, []string{"static/app.js"}},
+		{"parent", []string{"../shared/*.go"}, []string{"../shared/util.go"}},
+		{"base", []string{"."}, []string{"go.mod", "internal/db/db.go", "main.go", "main_test.go", "static/app.js", "static/img/logo.png"}},
+		{"missing", []string{"dist/**"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files, err := cache.ExpandGlobs(base, tt.patterns)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, files)
+		})
+	}
+}
+
+func TestValidateGlob(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, cache.ValidateGlob("src/**/*.go"))
+	require.NoError(t, cache.ValidateGlob("!vendor"))
+	require.NoError(t, cache.ValidateGlob("dist/${name}"))
+	require.ErrorContains(t, cache.ValidateGlob("src/[a-z"), "unterminated")
+	require.ErrorContains(t, cache.ValidateGlob("/etc/passwd"), "must be relative")
+	require.Error(t, cache.ValidateGlob(""))
+}
+
+func TestValidateOutputGlob(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, cache.ValidateOutputGlob("dist/**"))
+	require.NoError(t, cache.ValidateOutputGlob("build/../dist"))
+	require.ErrorContains(t, cache.ValidateOutputGlob("../dist"), "must be inside the working directory")
+	require.ErrorContains(t, cache.ValidateOutputGlob("dist/../../shared"), "must be inside the working directory")
+	require.ErrorContains(t, cache.ValidateOutputGlob(".."), "must be inside the working directory")
+
+	_, err := cache.ExpandOutputs(t.TempDir(), []string{"dist", "../shared"})
+	require.ErrorContains(t, err, "must be inside the working directory")
+}
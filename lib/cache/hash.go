@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// hashVersion changes whenever the hash inputs change, invalidating old entries.
+const hashVersion = "pilum-cache-v1"
+
+// Spec is everything a step's hash covers.
+type Spec struct {
+	Command any               // Resolved command
+	Cwd     string            // Working directory
+	Env     map[string]string // Step environment variables
+	Config  map[string]any    // Config inputs, by key
+	Files   map[string]string // Input files -> content digest
+}
+
+// Hash returns a digest of the spec.
+func Hash(spec Spec) (string, error) {
+	h := sha256.New()
+	write := func(label string, value any) error {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return errors.Wrap(err, "failed to hash "+label)
+		}
+		h.Write([]byte(label + " "))
+		h.Write(data)
+		h.Write([]byte("\n"))
+		return nil
+	}
+
+	h.Write([]byte(hashVersion + "\n"))
+	if err := write("command", spec.Command); err != nil {
+		return "", err
+	}
+	if err := write("cwd", spec.Cwd); err != nil {
+		return "", err
+	}
+	// encoding/json sorts map keys, so maps hash deterministically
+	if err := write("env", spec.Env); err != nil {
+		return "", err
+	}
+	if err := write("config", spec.Config); err != nil {
+		return "", err
+	}
+	if err := write("files", spec.Files); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// DigestFiles returns the content digest of each file, keyed by its path
+// relative to base.
+func DigestFiles(base string, files []string) (map[string]string, error) {
+	digests := make(map[string]string, len(files))
+	for _, file := range files {
+		digest, err := digestFile(filepath.Join(base, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		digests[file] = digest
+	}
+	return digests, nil
+}
+
+// DigestGlobs expands patterns under base and digests the matched files.
+func DigestGlobs(base string, patterns []string) (map[string]string, error) {
+	files, err := ExpandGlobs(base, patterns)
+	if err != nil {
+		return nil, err
+	}
+	return DigestFiles(base, files)
+}
+
+// SameDigests reports whether two digest maps are identical.
+func SameDigests(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, digest := range a {
+		if other, ok := b[file]; !ok || other != digest {
+			return false
+		}
+	}
+	return true
+}
+
+func digestFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read "+path)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", errors.Wrap(err, "failed to read "+path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// The remote cache protocol is plain HTTP, keyed by step hash:
+//
+//	GET /cache/<hash>  200 with the bundle, or 404 if it isn't cached
+//	PUT /cache/<hash>  store the request body as the bundle
+//
+// Bundles are zstd-compressed tar archives of the step's outputs, relative to
+// its working directory. Requests carry "Authorization: Bearer <token>" when a
+// token is configured.
+
+// BundleContentType is the media type of a cache bundle.
+const BundleContentType = "application/zstd"
+
+// DefaultRemoteTimeout bounds each request to a remote cache.
+const DefaultRemoteTimeout = 60 * time.Second
+
+// hashPattern matches a step hash as produced by Hash.
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// RemoteOptions configures a Remote.
+type RemoteOptions struct {
+	URL      string
+	Token    string
+	ReadOnly bool         // Only download; Upload is a no-op
+	Client   *http.Client // Default: DefaultRemoteTimeout
+	// MaxBundleSize is the largest bundle Download accepts, in bytes
+	// (default: DefaultMaxBundleSize).
+	MaxBundleSize int64
+}
+
+// Remote is a client for a shared HTTP cache.
+type Remote struct {
+	url      string
+	token    string
+	readOnly bool
+	client   *http.Client
+	maxSize  int64
+}
+
+// NewRemote returns a client for the cache at opts.URL.
+func NewRemote(opts RemoteOptions) *Remote {
+	client := opts.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultRemoteTimeout}
+	}
+	maxSize := opts.MaxBundleSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxBundleSize
+	}
+	return &Remote{
+		url:      strings.TrimSuffix(opts.URL, "/"),
+		token:    opts.Token,
+		readOnly: opts.ReadOnly,
+		client:   client,
+		maxSize:  maxSize,
+	}
+}
+
+// ReadOnly reports whether uploads are disabled.
+func (r *Remote) ReadOnly() bool {
+	return r.readOnly
+}
+
+// Download fetches the bundle for hash and extracts the files matching the
+// outputs globs into base; any other file, or a bundle larger than the
+// maximum bundle size, fails the download. It returns the extracted files,
+// and false if the remote doesn't have the hash.
+func (r *Remote) Download(hash, base string, outputs []string) ([]string, bool, error) {
+	resp, err := r.do(http.MethodGet, hash, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, errors.New("remote cache returned %s for %s", resp.Status, hash)
+	}
+
+	if resp.ContentLength > r.maxSize {
+		return nil, false, errors.New("remote cache bundle for %s is larger than %d bytes", hash, r.maxSize)
+	}
+	body := &io.LimitedReader{R: resp.Body, N: r.maxSize + 1}
+	files, err := ExtractBundle(body, base, outputs)
+	if body.N == 0 {
+		return nil, false, errors.New("remote cache bundle for %s is larger than %d bytes", hash, r.maxSize)
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "failed to extract "+hash)
+	}
+	return files, true, nil
+}
+
+// Upload bundles files, relative to base, and stores them under hash.
+func (r *Remote) Upload(hash, base string, files []string) error {
+	if r.readOnly {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, base, files); err != nil {
+		return err
+	}
+
+	resp, err := r.do(http.MethodPut, hash, &buf)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		return errors.New("remote cache returned %s for %s", resp.Status, hash)
+	}
+	return nil
+}
+
+func (r *Remote) do(method, hash string, body io.Reader) (*http.Response, error) {
+	if !hashPattern.MatchString(hash) {
+		return nil, errors.New("invalid cache hash '%s'", hash)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, r.url+"/cache/"+hash, body)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid remote cache url")
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", BundleContentType)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "remote cache request failed")
+	}
+	return resp, nil
+}
@@ -0,0 +1,199 @@
+package cache_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/cache"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+var testHash = strings.Repeat("ab", 32)
+
+func TestRemoteRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(cache.NewServer(t.TempDir(), "secret", false))
+	t.Cleanup(server.Close)
+
+	src := t.TempDir()
+	writeFiles(t, src, "dist/app", "dist/lib/util.so")
+	require.NoError(t, os.Chmod(filepath.Join(src, "dist", "app"), 0o755))
+
+	remote := cache.NewRemote(cache.RemoteOptions{URL: server.URL + "/", Token: "secret"})
+
+	_, ok, err := remote.Download(testHash, t.TempDir(), []string{"dist"})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	require.NoError(t, remote.Upload(testHash, src, []string{"dist/app", "dist/lib/util.so"}))
+
+	dest := t.TempDir()
+	files, ok, err := remote.Download(testHash, dest, []string{"dist"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []string{"dist/app", "dist/lib/util.so"}, files)
+
+	data, err := os.ReadFile(filepath.Join(dest, "dist", "lib", "util.so"))
+	require.NoError(t, err)
+	require.Equal(t, "dist/lib/util.so", string(data))
+	info, err := os.Stat(filepath.Join(dest, "dist", "app"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o755), info.Mode().Perm())
+}
+
+func TestRemoteAuth(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(cache.NewServer(t.TempDir(), "secret", false))
+	t.Cleanup(server.Close)
+
+	for _, token := range []string{"", "wrong"} {
+		remote := cache.NewRemote(cache.RemoteOptions{URL: server.URL, Token: token})
+		_, _, err := remote.Download(testHash, t.TempDir(), []string{"app"})
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "401")
+	}
+}
+
+func TestRemoteReadOnly(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	server := httptest.NewServer(cache.NewServer(dir, "", false))
+	t.Cleanup(server.Close)
+
+	src := t.TempDir()
+	writeFiles(t, src, "app")
+
+	// A read-only client never uploads
+	remote := cache.NewRemote(cache.RemoteOptions{URL: server.URL, ReadOnly: true})
+	require.True(t, remote.ReadOnly())
+	require.NoError(t, remote.Upload(testHash, src, []string{"app"}))
+	_, ok, err := remote.Download(testHash, t.TempDir(), []string{"app"})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// A read-only server rejects uploads
+	readOnlyServer := httptest.NewServer(cache.NewServer(dir, "", true))
+	t.Cleanup(readOnlyServer.Close)
+	err = cache.NewRemote(cache.RemoteOptions{URL: readOnlyServer.URL}).Upload(testHash, src, []string{"app"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "403")
+}
+
+func TestRemoteInvalidHash(t *testing.T) {
+	t.Parallel()
+
+	remote := cache.NewRemote(cache.RemoteOptions{URL: "http://127.0.0.1:0"})
+	_, _, err := remote.Download("../etc/passwd", t.TempDir(), nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid cache hash")
+}
+
+func TestExtractBundleRejectsEscapingPaths(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"../evil", "/etc/evil", "a/../../evil"} {
+		var buf bytes.Buffer
+		zw, err := zstd.NewWriter(&buf)
+		require.NoError(t, err)
+		tw := tar.NewWriter(zw)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644, Size: 4}))
+		_, err = tw.Write([]byte("evil"))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, zw.Close())
+
+		base := t.TempDir()
+		_, err = cache.ExtractBundle(&buf, filepath.Join(base, "out"), []string{"**"})
+		require.Error(t, err, name)
+		require.NoFileExists(t, filepath.Join(base, "evil"))
+	}
+}
+
+func TestRemoteRejectsUndeclaredOutputs(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(cache.NewServer(t.TempDir(), "", false))
+	t.Cleanup(server.Close)
+
+	// Whoever can write to the cache could try to overwrite sources or git hooks
+	src := t.TempDir()
+	writeFiles(t, src, "dist/app", ".git/hooks/pre-commit", "main.go")
+	remote := cache.NewRemote(cache.RemoteOptions{URL: server.URL})
+	require.NoError(t, remote.Upload(testHash, src, []string{"dist/app", ".git/hooks/pre-commit"}))
+
+	dest := t.TempDir()
+	_, _, err := remote.Download(testHash, dest, []string{"dist", "!dist/*.log"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match the step's outputs")
+	require.NoFileExists(t, filepath.Join(dest, ".git", "hooks", "pre-commit"))
+
+	// Exclusions apply too
+	require.NoError(t, remote.Upload(testHash, src, []string{"main.go"}))
+	_, _, err = remote.Download(testHash, dest, []string{"**", "!*.go"})
+	require.Error(t, err)
+	require.NoFileExists(t, filepath.Join(dest, "main.go"))
+}
+
+func TestServerRejectsLargeBundles(t *testing.T) {
+	t.Parallel()
+
+	handler := cache.NewServer(t.TempDir(), "", false)
+	handler.SetMaxBundleSize(1024)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/cache/"+testHash, strings.NewReader(strings.Repeat("x", 1025))) //nolint:noctx // test request
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
+
+func TestRemoteRejectsLargeBundles(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	data := make([]byte, 4096)
+	_, err := rand.Read(data)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(src, "app"), data, 0o644))
+	var bundle bytes.Buffer
+	require.NoError(t, cache.WriteBundle(&bundle, src, []string{"app"}))
+
+	// Streamed, so the size is only known once the limit is passed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.(http.Flusher).Flush()
+		_, _ = w.Write(bundle.Bytes())
+	}))
+	t.Cleanup(server.Close)
+
+	remote := cache.NewRemote(cache.RemoteOptions{URL: server.URL, MaxBundleSize: 1024})
+	_, _, err = remote.Download(testHash, t.TempDir(), []string{"**"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "larger than 1024 bytes")
+}
+
+func TestServerRejectsUnknownPaths(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(cache.NewServer(t.TempDir(), "", false))
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/cache/not-a-hash") //nolint:noctx // test request
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"crypto/subtle"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+)
+
+// DefaultMaxBundleSize is the largest bundle a Server accepts by default.
+const DefaultMaxBundleSize = 1 << 30
+
+// Server is a reference remote cache that stores bundles as files in a
+// directory. It implements the protocol described on Remote.
+type Server struct {
+	dir      string
+	token    string
+	readOnly bool
+	maxSize  int64
+}
+
+// NewServer returns a server storing bundles in dir. If token is set, every
+// request must carry it as a bearer token. A read-only server rejects uploads.
+func NewServer(dir, token string, readOnly bool) *Server {
+	return &Server{dir: dir, token: token, readOnly: readOnly, maxSize: DefaultMaxBundleSize}
+}
+
+// SetMaxBundleSize sets the largest bundle the server accepts, in bytes.
+func (s *Server) SetMaxBundleSize(n int64) {
+	s.maxSize = n
+}
+
+// ServeHTTP handles GET and PUT /cache/<hash>.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	hash, ok := strings.CutPrefix(req.URL.Path, "/cache/")
+	if !ok || !hashPattern.MatchString(hash) {
+		http.NotFound(w, req)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		s.get(w, req, hash)
+	case http.MethodPut:
+		if s.readOnly {
+			http.Error(w, "cache is read-only", http.StatusForbidden)
+			return
+		}
+		s.put(w, req, hash)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) authorized(req *http.Request) bool {
+	if s.token == "" {
+		return true
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+func (s *Server) get(w http.ResponseWriter, req *http.Request, hash string) {
+	f, err := os.Open(s.path(hash))
+	if os.IsNotExist(err) {
+		http.NotFound(w, req)
+		return
+	}
+	if err != nil {
+		output.Warning("cache: failed to read %s: %v", hash, err)
+		http.Error(w, "failed to read bundle", http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "failed to read bundle", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", BundleContentType)
+	http.ServeContent(w, req, "", info.ModTime(), f)
+}
+
+func (s *Server) put(w http.ResponseWriter, req *http.Request, hash string) {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		output.Warning("cache: failed to create %s: %v", s.dir, err)
+		http.Error(w, "failed to store bundle", http.StatusInternalServerError)
+		return
+	}
+
+	// Write to a temp file first so readers never see a partial bundle
+	tmp, err := os.CreateTemp(s.dir, "."+hash+"-")
+	if err != nil {
+		http.Error(w, "failed to store bundle", http.StatusInternalServerError)
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, http.MaxBytesReader(w, req.Body, s.maxSize)); err != nil {
+		tmp.Close()
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "bundle too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+	if err := tmp.Close(); err != nil {
+		http.Error(w, "failed to store bundle", http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(tmp.Name(), s.path(hash)); err != nil {
+		output.Warning("cache: failed to store %s: %v", hash, err)
+		http.Error(w, "failed to store bundle", http.StatusInternalServerError)
+		return
+	}
+
+	output.Debugf("cache: stored %s", hash)
+	w.WriteHeader(http.StatusCreated)
+}
+
+// path returns the file a bundle is stored in.
+func (s *Server) path(hash string) string {
+	return filepath.Join(s.dir, hash+".tar.zst")
+}
@@ -136,3 +136,144 @@ func GetNestedString(config map[string]any, keys ...string) string {
 
 	return ""
 }
+
+// GetNested extracts a value of any type from a nested map structure.
+// Intermediate maps may be map[string]any or map[any]any.
+// Returns false if any key along the path is missing.
+func GetNested(config map[string]any, keys ...string) (any, bool) {
+	if len(keys) == 0 || config == nil {
+		return nil, false
+	}
+
+	current := config
+	for i, key := range keys {
+		val, exists := current[key]
+		if !exists {
+			return nil, false
+		}
+
+		if i == len(keys)-1 {
+			return val, true
+		}
+
+		switch val.(type) {
+		case map[string]any, map[any]any:
+			current = MapFromAny(val)
+		default:
+			return nil, false
+		}
+	}
+
+	return nil, false
+}
+
+// SetNested sets a value in a nested map structure, creating intermediate maps as needed.
+// Intermediate map[any]any values (from yaml.v2) are replaced with map[string]any.
+// A non-map value along the path is overwritten.
+func SetNested(config map[string]any, value any, keys ...string) {
+	if len(keys) == 0 || config == nil {
+		return
+	}
+
+	current := config
+	for _, key := range keys[:len(keys)-1] {
+		var next map[string]any
+		switch val := current[key].(type) {
+		case map[string]any:
+			next = val
+		case map[any]any:
+			next = MapFromAny(val)
+		default:
+			next = make(map[string]any)
+		}
+		current[key] = next
+		current = next
+	}
+
+	current[keys[len(keys)-1]] = value
+}
+
+// CloneMap returns a deep copy of a config map. Nested maps are copied as
+// map[string]any and lists are copied; scalar values are shared.
+func CloneMap(config map[string]any) map[string]any {
+	if config == nil {
+		return nil
+	}
+
+	result := make(map[string]any, len(config))
+	for k, v := range config {
+		result[k] = cloneValue(v)
+	}
+	return result
+}
+
+func cloneValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return CloneMap(val)
+	case map[any]any:
+		return CloneMap(MapFromAny(val))
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			result[i] = cloneValue(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// DeepMerge returns a copy of base with override merged on top. Nested maps
+// are merged key by key; any other value in override, including lists,
+// replaces the value in base. Neither argument is modified.
+func DeepMerge(base, override map[string]any) map[string]any {
+	result := CloneMap(base)
+	if result == nil {
+		result = make(map[string]any, len(override))
+	}
+
+	for k, v := range override {
+		overrideMap, overrideIsMap := asMap(v)
+		baseMap, baseIsMap := asMap(result[k])
+		if overrideIsMap && baseIsMap {
+			result[k] = DeepMerge(baseMap, overrideMap)
+			continue
+		}
+		result[k] = cloneValue(v)
+	}
+	return result
+}
+
+// asMap returns v as map[string]any if it is a map.
+func asMap(v any) (map[string]any, bool) {
+	switch v.(type) {
+	case map[string]any, map[any]any:
+		return MapFromAny(v), true
+	default:
+		return nil, false
+	}
+}
+
+// Flatten returns the leaf values of a config map keyed by dotted path,
+// e.g. {"build": {"language": "go"}} becomes {"build.language": "go"}.
+// Lists and empty maps are leaves.
+func Flatten(config map[string]any) map[string]any {
+	result := make(map[string]any)
+	flattenInto(result, "", config)
+	return result
+}
+
+func flattenInto(result map[string]any, prefix string, config map[string]any) {
+	for k, v := range config {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if m, ok := asMap(v); ok && len(m) > 0 {
+			flattenInto(result, key, m)
+			continue
+		}
+		result[key] = v
+	}
+}
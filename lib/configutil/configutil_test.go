@@ -401,3 +401,135 @@ func TestGetNestedString(t *testing.T) {
 		})
 	}
 }
+
+func TestGetNested(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name": "svc",
+		"cloud_run": map[string]any{
+			"max_instances": 10,
+			"labels":        []any{"a", "b"},
+		},
+		"legacy": map[any]any{
+			"enabled": true,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected any
+		found    bool
+	}{
+		{name: "top level", keys: []string{"name"}, expected: "svc", found: true},
+		{name: "nested int", keys: []string{"cloud_run", "max_instances"}, expected: 10, found: true},
+		{name: "nested list", keys: []string{"cloud_run", "labels"}, expected: []any{"a", "b"}, found: true},
+		{name: "yaml.v2 map", keys: []string{"legacy", "enabled"}, expected: true, found: true},
+		{name: "missing leaf", keys: []string{"cloud_run", "memory"}, found: false},
+		{name: "missing section", keys: []string{"lambda", "memory"}, found: false},
+		{name: "through scalar", keys: []string{"name", "x"}, found: false},
+		{name: "no keys", keys: nil, found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			val, found := configutil.GetNested(config, tt.keys...)
+			require.Equal(t, tt.found, found)
+			require.Equal(t, tt.expected, val)
+		})
+	}
+}
+
+func TestSetNested(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":   "svc",
+		"legacy": map[any]any{"enabled": true},
+	}
+
+	configutil.SetNested(config, "512Mi", "cloud_run", "memory")
+	configutil.SetNested(config, 10, "cloud_run", "max_instances")
+	configutil.SetNested(config, false, "legacy", "debug")
+	configutil.SetNested(config, "x", "name", "nested")
+	configutil.SetNested(config, "ignored")
+
+	require.Equal(t, map[string]any{"memory": "512Mi", "max_instances": 10}, config["cloud_run"])
+	require.Equal(t, map[string]any{"enabled": true, "debug": false}, config["legacy"])
+	require.Equal(t, map[string]any{"nested": "x"}, config["name"])
+}
+
+func TestCloneMap(t *testing.T) {
+	t.Parallel()
+
+	original := map[string]any{
+		"cloud_run": map[string]any{"memory": "512Mi"},
+		"legacy":    map[any]any{"enabled": true},
+		"tags":      []any{"a", map[string]any{"b": 1}},
+	}
+
+	clone := configutil.CloneMap(original)
+	configutil.SetNested(clone, "1Gi", "cloud_run", "memory")
+	clone["tags"].([]any)[1].(map[string]any)["b"] = 2
+
+	require.Equal(t, "512Mi", original["cloud_run"].(map[string]any)["memory"])
+	require.Equal(t, 1, original["tags"].([]any)[1].(map[string]any)["b"])
+	require.Equal(t, map[string]any{"enabled": true}, clone["legacy"])
+	require.Nil(t, configutil.CloneMap(nil))
+}
+
+func TestDeepMerge(t *testing.T) {
+	t.Parallel()
+
+	base := map[string]any{
+		"project":   "acme-dev",
+		"cloud_run": map[any]any{"memory": "512Mi", "min_instances": 0},
+		"env_vars":  map[string]any{"LOG_LEVEL": "debug", "FEATURE": "on"},
+		"regions":   []any{"us-central1", "europe-west1"},
+	}
+	override := map[string]any{
+		"project":   "acme-prod",
+		"cloud_run": map[string]any{"min_instances": 2},
+		"env_vars":  map[any]any{"LOG_LEVEL": "info"},
+		"regions":   []any{"us-east1"},
+		"new":       map[string]any{"key": "value"},
+	}
+
+	merged := configutil.DeepMerge(base, override)
+
+	require.Equal(t, map[string]any{
+		"project":   "acme-prod",
+		"cloud_run": map[string]any{"memory": "512Mi", "min_instances": 2},
+		"env_vars":  map[string]any{"LOG_LEVEL": "info", "FEATURE": "on"},
+		"regions":   []any{"us-east1"},
+		"new":       map[string]any{"key": "value"},
+	}, merged)
+
+	// Inputs are untouched
+	require.Equal(t, "acme-dev", base["project"])
+	require.Equal(t, map[string]any{"LOG_LEVEL": "debug", "FEATURE": "on"}, base["env_vars"])
+
+	require.Equal(t, map[string]any{"a": 1}, configutil.DeepMerge(nil, map[string]any{"a": 1}))
+}
+
+func TestFlatten(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":    "api",
+		"build":   map[any]any{"language": "go", "flags": map[string]any{"ldflags": []any{"-s"}}},
+		"regions": []any{"us-east1"},
+		"labels":  map[string]any{},
+	}
+
+	require.Equal(t, map[string]any{
+		"name":                "api",
+		"build.language":      "go",
+		"build.flags.ldflags": []any{"-s"},
+		"regions":             []any{"us-east1"},
+		"labels":              map[string]any{},
+	}, configutil.Flatten(config))
+}
@@ -131,3 +131,17 @@ func TestIsGitRepository(t *testing.T) {
 
 	// If we get here, the function correctly detected we're in a git repo
 }
+
+func TestCurrentInfo(t *testing.T) {
+	if !IsGitRepository() {
+		t.Skip("Not running in a git repository")
+	}
+
+	info := CurrentInfo()
+	if len(info.SHA) != 40 {
+		t.Errorf("CurrentInfo().SHA = %q, want a 40 character commit hash", info.SHA)
+	}
+	if info.ShortSHA != info.SHA[:7] {
+		t.Errorf("CurrentInfo().ShortSHA = %q, want %q", info.ShortSHA, info.SHA[:7])
+	}
+}
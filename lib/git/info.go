@@ -0,0 +1,34 @@
+package git
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Info describes the current commit.
+type Info struct {
+	SHA      string
+	ShortSHA string
+	Branch   string
+}
+
+// CurrentInfo returns the commit and branch of HEAD in the current directory.
+// Fields are empty when not in a git repository; Branch is empty on a detached HEAD.
+func CurrentInfo() Info {
+	var info Info
+
+	if out, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+		info.SHA = strings.TrimSpace(string(out))
+		if len(info.SHA) >= 7 {
+			info.ShortSHA = info.SHA[:7]
+		}
+	}
+
+	if out, err := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD").Output(); err == nil {
+		if branch := strings.TrimSpace(string(out)); branch != "HEAD" {
+			info.Branch = branch
+		}
+	}
+
+	return info
+}
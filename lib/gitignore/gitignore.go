@@ -0,0 +1,203 @@
+// Package gitignore matches paths against .gitignore patterns the way git
+// does: negation, directory-only patterns, anchoring, ** and patterns read
+// from nested .gitignore files.
+package gitignore
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Pattern is a single line of an ignore file.
+type Pattern struct {
+	base    string // Directory of the ignore file, relative to the root ("" for the root)
+	negate  bool   // "!pattern" re-includes what earlier patterns ignored
+	dirOnly bool   // "pattern/" only matches directories
+	re      *regexp.Regexp
+}
+
+// ParsePattern parses a line of an ignore file read from the directory base
+// (a slash path relative to the root, "" for the root). It reports false for
+// blank lines, comments and invalid patterns.
+func ParsePattern(line, base string) (Pattern, bool) {
+	line = trimTrailingSpace(strings.TrimSuffix(line, "\r"))
+	if line == "" || strings.HasPrefix(line, "#") {
+		return Pattern{}, false
+	}
+
+	p := Pattern{base: strings.Trim(base, "/")}
+	if rest, ok := strings.CutPrefix(line, "!"); ok {
+		p.negate = true
+		line = rest
+	}
+	if rest, ok := strings.CutSuffix(line, "/"); ok {
+		p.dirOnly = true
+		line = rest
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to base; otherwise it
+	// matches a name at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	expr, ok := globExpr(line)
+	if !ok {
+		return Pattern{}, false
+	}
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return Pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// CompileGlob compiles a glob anchored at the root, with the same syntax as
+// ignore patterns: * and ? stay within a path component, ** spans any number
+// of them, [...] is a character class and \ escapes the next character. It's
+// the glob syntax used across pilum, so watch paths, workspace globs and cache
+// inputs all match the same way.
+func CompileGlob(glob string) (*regexp.Regexp, error) {
+	expr, ok := globExpr(strings.TrimPrefix(glob, "/"))
+	if !ok {
+		return nil, errors.New("glob '%s' has an unterminated character class", glob)
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid glob '"+glob+"'")
+	}
+	return re, nil
+}
+
+// globExpr translates a glob to a regular expression. It reports false if a
+// character class isn't terminated.
+func globExpr(glob string) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			b.WriteString("(?:.*/)?") // Leading or middle **/: zero or more directories
+			i += 2
+		case glob[i:] == "**" && i == 0:
+			b.WriteString(".*") // A lone **: everything
+			i++
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob) && i > 0 && glob[i-1] == '/':
+			b.WriteString(".*") // Trailing /**: everything inside
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", false
+			}
+			class := glob[i+1 : i+1+end]
+			if rest, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + rest
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), true
+}
+
+// trimTrailingSpace removes trailing spaces unless they're escaped with a backslash.
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// ReadFile reads the patterns of an ignore file in the directory base. A
+// missing file has no patterns.
+func ReadFile(path, base string) ([]Pattern, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading "+path)
+	}
+	defer file.Close()
+
+	var patterns []Pattern
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if p, ok := ParsePattern(scanner.Text(), base); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "error reading "+path)
+	}
+	return patterns, nil
+}
+
+// Matcher holds the patterns of every ignore file read so far. Later
+// patterns take precedence, so files are added from the root down.
+type Matcher struct {
+	patterns []Pattern
+}
+
+// Add appends patterns, which take precedence over those added before.
+func (m *Matcher) Add(patterns ...Pattern) {
+	m.patterns = append(m.patterns, patterns...)
+}
+
+// Len returns the number of patterns.
+func (m *Matcher) Len() int {
+	return len(m.patterns)
+}
+
+// Ignored reports whether path (a slash path relative to the root) is
+// ignored. As in git, nothing inside an ignored directory can be re-included.
+func (m *Matcher) Ignored(path string, isDir bool) bool {
+	path = strings.Trim(path, "/")
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && m.match(path[:i], true) {
+			return true
+		}
+	}
+	return m.match(path, isDir)
+}
+
+// match applies the patterns to path alone; the last one that matches decides.
+func (m *Matcher) match(path string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		rel := path
+		if p.base != "" {
+			var ok bool
+			if rel, ok = strings.CutPrefix(path, p.base+"/"); !ok {
+				continue
+			}
+		}
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,117 @@
+package gitignore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/gitignore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		lines    []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{name: "name at any depth", lines: []string{"build"}, path: "services/api/build", isDir: true, expected: true},
+		{name: "name glob", lines: []string{"*.log"}, path: "a/b/debug.log", expected: true},
+		{name: "star stays in component", lines: []string{"a/*.log"}, path: "a/b/debug.log", expected: false},
+		{name: "anchored", lines: []string{"/build"}, path: "services/build", isDir: true, expected: false},
+		{name: "anchored at root", lines: []string{"/build"}, path: "build", isDir: true, expected: true},
+		{name: "middle slash anchors", lines: []string{"services/legacy"}, path: "other/services/legacy", isDir: true, expected: false},
+		{name: "dir only skips files", lines: []string{"build/"}, path: "build", expected: false},
+		{name: "dir only matches dirs", lines: []string{"build/"}, path: "x/build", isDir: true, expected: true},
+		{name: "leading double star", lines: []string{"**/fixtures"}, path: "a/b/fixtures", isDir: true, expected: true},
+		{name: "middle double star", lines: []string{"a/**/z"}, path: "a/z", isDir: true, expected: true},
+		{name: "middle double star deep", lines: []string{"a/**/z"}, path: "a/b/c/z", isDir: true, expected: true},
+		{name: "trailing double star", lines: []string{"vendor/**"}, path: "vendor/x/y.go", expected: true},
+		{name: "trailing double star not dir itself", lines: []string{"vendor/**"}, path: "vendor", isDir: true, expected: false},
+		{name: "negation", lines: []string{"*.yaml", "!pilum.yaml"}, path: "api/pilum.yaml", expected: false},
+		{name: "last match wins", lines: []string{"!pilum.yaml", "*.yaml"}, path: "api/pilum.yaml", expected: true},
+		{name: "no re-include inside ignored dir", lines: []string{"examples/", "!examples/api/"}, path: "examples/api", isDir: true, expected: true},
+		{name: "parent ignored", lines: []string{"examples"}, path: "examples/api/pilum.yaml", expected: true},
+		{name: "character class", lines: []string{"test-[0-9]"}, path: "test-3", isDir: true, expected: true},
+		{name: "negated class", lines: []string{"test-[!0-9]"}, path: "test-3", isDir: true, expected: false},
+		{name: "escaped hash", lines: []string{`\#notes`}, path: "#notes", expected: true},
+		{name: "comment", lines: []string{"# build"}, path: "# build", expected: false},
+		{name: "trailing spaces", lines: []string{"build   "}, path: "build", isDir: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var m gitignore.Matcher
+			for _, line := range tt.lines {
+				if p, ok := gitignore.ParsePattern(line, ""); ok {
+					m.Add(p)
+				}
+			}
+			require.Equal(t, tt.expected, m.Ignored(tt.path, tt.isDir))
+		})
+	}
+}
+
+func TestMatcherNestedBase(t *testing.T) {
+	t.Parallel()
+
+	var m gitignore.Matcher
+	root, _ := gitignore.ParsePattern("*.tmp", "")
+	nested, _ := gitignore.ParsePattern("/generated", "services/api")
+	keep, _ := gitignore.ParsePattern("!keep.tmp", "services/api")
+	m.Add(root, nested, keep)
+
+	require.True(t, m.Ignored("services/api/generated", true))
+	require.False(t, m.Ignored("services/worker/generated", true), "nested patterns only apply beneath their directory")
+	require.False(t, m.Ignored("generated", true))
+	require.False(t, m.Ignored("services/api/keep.tmp", false))
+	require.True(t, m.Ignored("services/worker/keep.tmp", false))
+}
+
+func TestCompileGlob(t *testing.T) {
+	t.Parallel()
+
+	re, err := gitignore.CompileGlob("services/**")
+	require.NoError(t, err)
+	require.True(t, re.MatchString("services/api"))
+	require.True(t, re.MatchString("services/a/b"))
+	require.False(t, re.MatchString("tools/cli"))
+
+	re, err = gitignore.CompileGlob("*/legacy-*")
+	require.NoError(t, err)
+	require.True(t, re.MatchString("services/legacy-api"))
+	require.False(t, re.MatchString("legacy-api"))
+
+	re, err = gitignore.CompileGlob("**")
+	require.NoError(t, err)
+	require.True(t, re.MatchString("services/api"))
+
+	_, err = gitignore.CompileGlob("services/[a-z")
+	require.ErrorContains(t, err, "unterminated character class")
+
+	_, ok := gitignore.ParsePattern("build-[0-9", "")
+	require.False(t, ok, "invalid patterns are skipped")
+}
+
+func TestReadFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".gitignore")
+	require.NoError(t, os.WriteFile(path, []byte("# comment\n\nnode_modules\r\n!keep\n"), 0o644))
+
+	patterns, err := gitignore.ReadFile(path, "")
+	require.NoError(t, err)
+	require.Len(t, patterns, 2)
+
+	patterns, err = gitignore.ReadFile(filepath.Join(dir, "missing"), "")
+	require.NoError(t, err)
+	require.Empty(t, patterns)
+}
@@ -0,0 +1,228 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Export formats.
+const (
+	FormatDOT     = "dot"
+	FormatMermaid = "mermaid"
+	FormatJSON    = "json"
+)
+
+// Formats lists the supported export formats.
+var Formats = []string{FormatDOT, FormatMermaid, FormatJSON}
+
+// Node kinds.
+const (
+	KindService = "service"
+	KindStep    = "step"
+)
+
+// Node statuses, used to highlight nodes.
+const (
+	StatusChanged  = "changed"  // Changed directly
+	StatusAffected = "affected" // Depends on something changed
+)
+
+// Edge kinds.
+const (
+	EdgeDependsOn = "depends_on" // From depends on To
+	EdgeStep      = "step"       // To runs after From, within one service
+)
+
+// ExportNode is a node of an exported graph.
+type ExportNode struct {
+	ID      string `json:"id"`
+	Label   string `json:"label"`
+	Kind    string `json:"kind"`
+	Service string `json:"service,omitempty"` // The service a step belongs to
+	Status  string `json:"status,omitempty"`
+}
+
+// ExportEdge is an edge of an exported graph.
+type ExportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// Export is a graph prepared for rendering, with nodes and edges in the
+// order they should be written.
+type Export struct {
+	Nodes []ExportNode `json:"nodes"`
+	Edges []ExportEdge `json:"edges"`
+	Cycle []string     `json:"cycle,omitempty"` // A dependency cycle, see FindCycle
+}
+
+// Export returns the graph's nodes, sorted by name, and their depends_on
+// edges. Dependencies that aren't nodes are left out. Statuses are taken
+// from status, keyed by node name.
+func (g *Graph) Export(status map[string]string) Export {
+	export := Export{Nodes: []ExportNode{}, Edges: []ExportEdge{}, Cycle: g.FindCycle()}
+	for _, name := range g.Nodes() {
+		export.Nodes = append(export.Nodes, ExportNode{ID: name, Label: name, Kind: KindService, Status: status[name]})
+		for _, dep := range g.nodes[name].DependsOn {
+			if g.HasNode(dep) {
+				export.Edges = append(export.Edges, ExportEdge{From: name, To: dep, Kind: EdgeDependsOn})
+			}
+		}
+	}
+	return export
+}
+
+// AddSteps adds a service's steps as nodes, chained in the order they run.
+func (e *Export) AddSteps(service string, steps []string) {
+	prev := service
+	for _, step := range steps {
+		id := service + "/" + step
+		e.Nodes = append(e.Nodes, ExportNode{ID: id, Label: step, Kind: KindStep, Service: service})
+		e.Edges = append(e.Edges, ExportEdge{From: prev, To: id, Kind: EdgeStep})
+		prev = id
+	}
+}
+
+// Render renders the export in one of Formats.
+func (e Export) Render(format string) (string, error) {
+	switch format {
+	case FormatDOT:
+		return e.DOT(), nil
+	case FormatMermaid:
+		return e.Mermaid(), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return "", errors.Wrap(err, "error encoding graph")
+		}
+		return string(data) + "\n", nil
+	default:
+		return "", errors.New("unknown graph format '%s' (use %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// statusColors are the fill colors of highlighted nodes.
+var statusColors = map[string]string{
+	StatusChanged:  "#f4a582",
+	StatusAffected: "#fddbc7",
+}
+
+// cycleColor is the color of edges on a dependency cycle.
+const cycleColor = "#d6604d"
+
+// DOT renders the export as a Graphviz digraph.
+func (e Export) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph pilum {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=rounded];\n")
+
+	for _, n := range e.Nodes {
+		attrs := []string{"label=" + dotQuote(n.Label)}
+		if n.Kind == KindStep {
+			attrs = append(attrs, "shape=ellipse")
+		}
+		if color, ok := statusColors[n.Status]; ok {
+			style := "rounded,filled"
+			if n.Kind == KindStep {
+				style = "filled"
+			}
+			attrs = append(attrs, "style="+dotQuote(style), "fillcolor="+dotQuote(color))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range e.Edges {
+		var attrs []string
+		if edge.Kind == EdgeStep {
+			attrs = append(attrs, "style=dashed")
+		}
+		if e.onCycle(edge) {
+			attrs = append(attrs, "color="+dotQuote(cycleColor), "penwidth=2")
+		}
+		line := "  " + dotQuote(edge.From) + " -> " + dotQuote(edge.To)
+		if len(attrs) > 0 {
+			line += " [" + strings.Join(attrs, ", ") + "]"
+		}
+		b.WriteString(line + ";\n")
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the export as a Mermaid flowchart.
+func (e Export) Mermaid() string {
+	// Mermaid IDs can't contain most punctuation, so nodes are numbered
+	ids := make(map[string]string, len(e.Nodes))
+	for i, n := range e.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, n := range e.Nodes {
+		if n.Kind == KindStep {
+			fmt.Fprintf(&b, "  %s([%s])\n", ids[n.ID], mermaidQuote(n.Label))
+		} else {
+			fmt.Fprintf(&b, "  %s[%s]\n", ids[n.ID], mermaidQuote(n.Label))
+		}
+	}
+
+	var cycleEdges []string
+	for i, edge := range e.Edges {
+		arrow := "-->"
+		if edge.Kind == EdgeStep {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s %s\n", ids[edge.From], arrow, ids[edge.To])
+		if e.onCycle(edge) {
+			cycleEdges = append(cycleEdges, fmt.Sprint(i))
+		}
+	}
+
+	for _, status := range []string{StatusChanged, StatusAffected} {
+		var members []string
+		for _, n := range e.Nodes {
+			if n.Status == status {
+				members = append(members, ids[n.ID])
+			}
+		}
+		if len(members) > 0 {
+			fmt.Fprintf(&b, "  classDef %s fill:%s\n", status, statusColors[status])
+			fmt.Fprintf(&b, "  class %s %s\n", strings.Join(members, ","), status)
+		}
+	}
+	if len(cycleEdges) > 0 {
+		fmt.Fprintf(&b, "  linkStyle %s stroke:%s,stroke-width:2px\n", strings.Join(cycleEdges, ","), cycleColor)
+	}
+
+	return b.String()
+}
+
+// onCycle reports whether edge is one of the steps of the export's cycle.
+func (e Export) onCycle(edge ExportEdge) bool {
+	if edge.Kind != EdgeDependsOn {
+		return false
+	}
+	for i := 0; i+1 < len(e.Cycle); i++ {
+		if e.Cycle[i] == edge.From && e.Cycle[i+1] == edge.To {
+			return true
+		}
+	}
+	return false
+}
+
+// dotQuote quotes a DOT ID or attribute value.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// mermaidQuote quotes a Mermaid label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func exampleExport() Export {
+	g := New()
+	g.AddNode("api", []string{"db"})
+	g.AddNode("db", nil)
+	g.AddNode("web", []string{"api", "missing"})
+
+	export := g.Export(map[string]string{"db": StatusChanged, "api": StatusAffected})
+	export.AddSteps("db", []string{"build", "deploy"})
+	return export
+}
+
+func TestExport(t *testing.T) {
+	export := exampleExport()
+
+	ids := make([]string, 0, len(export.Nodes))
+	for _, n := range export.Nodes {
+		ids = append(ids, n.ID)
+	}
+	if got, want := strings.Join(ids, ","), "api,db,web,db/build,db/deploy"; got != want {
+		t.Errorf("Export() nodes = %s, want %s", got, want)
+	}
+
+	edges := make([]string, 0, len(export.Edges))
+	for _, e := range export.Edges {
+		edges = append(edges, e.From+">"+e.To)
+	}
+	if got, want := strings.Join(edges, ","), "api>db,web>api,db>db/build,db/build>db/deploy"; got != want {
+		t.Errorf("Export() edges = %s, want %s", got, want)
+	}
+	if export.Cycle != nil {
+		t.Errorf("Export() cycle = %v, want none", export.Cycle)
+	}
+}
+
+func TestExportDOT(t *testing.T) {
+	dot := exampleExport().DOT()
+
+	for _, want := range []string{
+		"digraph pilum {",
+		`"db" [label="db", style="rounded,filled", fillcolor="#f4a582"];`,
+		`"api" [label="api", style="rounded,filled", fillcolor="#fddbc7"];`,
+		`"web" [label="web"];`,
+		`"db/build" [label="build", shape=ellipse];`,
+		`"api" -> "db";`,
+		`"db/build" -> "db/deploy" [style=dashed];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT() missing %q in:\n%s", want, dot)
+		}
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	mermaid := exampleExport().Mermaid()
+
+	for _, want := range []string{
+		"flowchart LR",
+		`n0["api"]`,
+		`n3(["build"])`,
+		"n0 --> n1",
+		"n3 -.-> n4",
+		"class n1 changed",
+		"class n0 affected",
+	} {
+		if !strings.Contains(mermaid, want) {
+			t.Errorf("Mermaid() missing %q in:\n%s", want, mermaid)
+		}
+	}
+}
+
+func TestExportCycle(t *testing.T) {
+	g := New()
+	g.AddNode("a", []string{"b"})
+	g.AddNode("b", []string{"a"})
+	g.AddNode("c", []string{"a"})
+	export := g.Export(nil)
+
+	if got := FormatPath(export.Cycle); got != "a → b → a" {
+		t.Errorf("Export() cycle = %q", got)
+	}
+	dot := export.DOT()
+	if !strings.Contains(dot, `"a" -> "b" [color="#d6604d", penwidth=2];`) || strings.Contains(dot, `"c" -> "a" [`) {
+		t.Errorf("DOT() should only highlight the cycle's edges:\n%s", dot)
+	}
+	if !strings.Contains(export.Mermaid(), "linkStyle 0,1 stroke:#d6604d") {
+		t.Errorf("Mermaid() should highlight the cycle's edges:\n%s", export.Mermaid())
+	}
+}
+
+func TestExportRender(t *testing.T) {
+	out, err := exampleExport().Render(FormatJSON)
+	if err != nil {
+		t.Fatalf("Render(json) error = %v", err)
+	}
+	var decoded Export
+	if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+		t.Fatalf("Render(json) is not JSON: %v", err)
+	}
+	if decoded.Nodes[1].Status != StatusChanged {
+		t.Errorf("Render(json) db status = %q, want %q", decoded.Nodes[1].Status, StatusChanged)
+	}
+
+	if _, err := exampleExport().Render("svg"); err == nil {
+		t.Error("Render(svg) expected error, got nil")
+	}
+}
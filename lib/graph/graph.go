@@ -1,6 +1,10 @@
 package graph
 
 import (
+	"slices"
+	"sort"
+	"strings"
+
 	"github.com/sid-technologies/pilum/lib/errors"
 )
 
@@ -75,17 +79,82 @@ func (g *Graph) TopologicalSort() ([]string, error) {
 
 	// If we didn't process all nodes, there's a cycle
 	if len(sorted) != len(g.nodes) {
-		// Find nodes involved in the cycle
-		var cycleNodes []string
-		for name, degree := range inDegree {
-			if degree > 0 {
-				cycleNodes = append(cycleNodes, name)
+		return nil, errors.New("circular dependency detected: %s", FormatPath(g.FindCycle()))
+	}
+
+	return sorted, nil
+}
+
+// FindCycle returns a dependency cycle as a path that starts and ends at the
+// same node, e.g. [A B C A] where A depends on B, B on C and C on A. It
+// returns nil if the graph has no cycle. Nodes are visited in name order, so
+// the same graph always reports the same cycle.
+func (g *Graph) FindCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range g.nodes[name].DependsOn {
+			if _, exists := g.nodes[dep]; !exists {
+				continue
+			}
+			switch state[dep] {
+			case visiting:
+				start := slices.Index(path, dep)
+				return append(slices.Clone(path[start:]), dep)
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
 			}
 		}
-		return nil, errors.New("circular dependency detected involving: %v", cycleNodes)
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
 	}
 
-	return sorted, nil
+	for _, name := range g.Nodes() {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// FormatPath formats a path of nodes, such as a cycle returned by FindCycle,
+// as "A → B → C → A".
+func FormatPath(cycle []string) string {
+	return strings.Join(cycle, " → ")
+}
+
+// Nodes returns the names of all nodes, sorted.
+func (g *Graph) Nodes() []string {
+	names := make([]string, 0, len(g.nodes))
+	for name := range g.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DirectDependencies returns the nodes the given node depends on directly,
+// in the order they were declared.
+func (g *Graph) DirectDependencies(name string) []string {
+	node, exists := g.nodes[name]
+	if !exists {
+		return nil
+	}
+	return node.DependsOn
 }
 
 // GetDependents returns all nodes that depend on the given node (direct and transitive).
@@ -148,6 +217,34 @@ func (g *Graph) PropagateChanges(changed map[string]bool) map[string]bool {
 	return result
 }
 
+// DependencyPath returns the shortest path from name through its
+// dependencies to one of targets, e.g. [web api db] if web depends on api and
+// api on db. It returns nil if name doesn't depend on any of them.
+func (g *Graph) DependencyPath(name string, targets map[string]bool) []string {
+	prev := map[string]string{name: ""}
+	queue := []string{name}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, dep := range g.reverseEdges[n] {
+			if _, seen := prev[dep]; seen {
+				continue
+			}
+			prev[dep] = n
+			if targets[dep] {
+				path := []string{dep}
+				for p := n; p != ""; p = prev[p] {
+					path = append(path, p)
+				}
+				slices.Reverse(path)
+				return path
+			}
+			queue = append(queue, dep)
+		}
+	}
+	return nil
+}
+
 // ValidateDependencies checks that all dependencies reference existing nodes.
 // Returns an error if any dependency references a non-existent node.
 func (g *Graph) ValidateDependencies() error {
@@ -299,3 +299,79 @@ func TestHasNode(t *testing.T) {
 		t.Error("HasNode(B) = true, want false")
 	}
 }
+
+func TestFindCycle(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes map[string][]string
+		want  string
+	}{
+		{
+			name:  "no cycle",
+			nodes: map[string][]string{"A": {"B"}, "B": {}},
+			want:  "",
+		},
+		{
+			name:  "three nodes",
+			nodes: map[string][]string{"A": {"B"}, "B": {"C"}, "C": {"A"}},
+			want:  "A → B → C → A",
+		},
+		{
+			name:  "self-referencing",
+			nodes: map[string][]string{"A": {"A"}},
+			want:  "A → A",
+		},
+		{
+			name:  "cycle below an acyclic node",
+			nodes: map[string][]string{"A": {"B", "missing"}, "B": {"C"}, "C": {"D"}, "D": {"C"}},
+			want:  "C → D → C",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := New()
+			for name, deps := range tt.nodes {
+				g.AddNode(name, deps)
+			}
+
+			if got := FormatPath(g.FindCycle()); got != tt.want {
+				t.Errorf("FindCycle() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDependencyPath(t *testing.T) {
+	g := New()
+	g.AddNode("web", []string{"api", "cdn"})
+	g.AddNode("api", []string{"db"})
+	g.AddNode("cdn", nil)
+	g.AddNode("db", nil)
+
+	changed := map[string]bool{"db": true}
+	if got := FormatPath(g.DependencyPath("web", changed)); got != "web → api → db" {
+		t.Errorf("DependencyPath(web) = %q, want %q", got, "web → api → db")
+	}
+	if got := g.DependencyPath("cdn", changed); got != nil {
+		t.Errorf("DependencyPath(cdn) = %v, want nil", got)
+	}
+	if got := FormatPath(g.DependencyPath("web", map[string]bool{"db": true, "api": true})); got != "web → api" {
+		t.Errorf("DependencyPath(web) = %q, want the shortest path", got)
+	}
+}
+
+func TestTopologicalSortReportsCyclePath(t *testing.T) {
+	g := New()
+	g.AddNode("api", []string{"db"})
+	g.AddNode("db", []string{"cache"})
+	g.AddNode("cache", []string{"api"})
+
+	_, err := g.TopologicalSort()
+	if err == nil {
+		t.Fatal("TopologicalSort() expected error, got nil")
+	}
+	if want := "circular dependency detected: api → db → cache → api"; err.Error() != want {
+		t.Errorf("TopologicalSort() error = %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,129 @@
+package interpolate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// function transforms a pipeline value. A nil value means undefined.
+type function struct {
+	minArgs int
+	maxArgs int
+	apply   func(value any, args []string) any
+}
+
+// functions lists the functions available in pipelines.
+var functions = map[string]function{
+	// default "x" replaces an undefined or empty value
+	"default": {minArgs: 1, maxArgs: 1, apply: func(value any, args []string) any {
+		if value == nil || value == "" {
+			return args[0]
+		}
+		if list, ok := value.([]any); ok && len(list) == 0 {
+			return args[0]
+		}
+		return value
+	}},
+	"lower": {apply: mapStrings(strings.ToLower)},
+	"upper": {apply: mapStrings(strings.ToUpper)},
+	"trim":  {apply: mapStrings(strings.TrimSpace)},
+	// join "sep" joins a list into one string (default separator ",")
+	"join": {maxArgs: 1, apply: func(value any, args []string) any {
+		list, ok := value.([]any)
+		if !ok {
+			return value
+		}
+		sep := ","
+		if len(args) > 0 {
+			sep = args[0]
+		}
+		items := make([]string, 0, len(list))
+		for _, item := range list {
+			items = append(items, render(item))
+		}
+		return strings.Join(items, sep)
+	}},
+	// quote single-quotes a value for the shell
+	"quote": {apply: mapStrings(shellQuote)},
+}
+
+// FunctionNames returns the names of all pipeline functions, sorted.
+func FunctionNames() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkCall validates a function name and argument count at parse time.
+func checkCall(c call, source string) error {
+	fn, exists := functions[c.name]
+	if !exists {
+		if suggestion := suggest.FormatSuggestion(c.name, FunctionNames()); suggestion != "" {
+			return errors.New("unknown function '%s' in '${%s}' - %s", c.name, source, suggestion)
+		}
+		return errors.New("unknown function '%s' in '${%s}' (available: %s)", c.name, source, strings.Join(FunctionNames(), ", "))
+	}
+	if len(c.args) < fn.minArgs || len(c.args) > fn.maxArgs {
+		return errors.New("function '%s' in '${%s}' takes %s", c.name, source, describeArgs(fn))
+	}
+	return nil
+}
+
+func describeArgs(fn function) string {
+	switch {
+	case fn.maxArgs == 0:
+		return "no arguments"
+	case fn.minArgs == fn.maxArgs:
+		return "exactly 1 argument"
+	default:
+		return "at most 1 argument"
+	}
+}
+
+// eval evaluates the expression against ctx.
+func (e *expression) eval(ctx Context) (any, error) {
+	var value any
+	if e.path == "" {
+		value = e.literal
+	} else if v, found := ctx.Lookup(e.path); found {
+		value = normalize(v)
+	}
+
+	for _, c := range e.pipeline {
+		if value == nil && c.name != "default" {
+			// Undefined values only pass through to a default
+			continue
+		}
+		value = functions[c.name].apply(value, c.args)
+	}
+
+	if value == nil {
+		return nil, undefinedError(e.path, ctx)
+	}
+	return value, nil
+}
+
+// mapStrings applies fn to a scalar value, or to every item of a list.
+func mapStrings(fn func(string) string) func(any, []string) any {
+	return func(value any, _ []string) any {
+		if list, ok := value.([]any); ok {
+			result := make([]any, len(list))
+			for i, item := range list {
+				result[i] = fn(render(item))
+			}
+			return result
+		}
+		return fn(render(value))
+	}
+}
+
+// shellQuote wraps s in single quotes, escaping embedded single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,225 @@
+// Package interpolate expands ${...} expressions in recipe commands.
+//
+// An expression is a dotted path into a Context, optionally followed by a
+// pipeline of functions:
+//
+//	${cloud_run.memory}
+//	${region | default "us-central1" | upper}
+//	${tags | join ","}
+//
+// A bare "${path}" argument whose value is a list expands into one argv entry
+// per item. "$${" produces a literal "${". Undefined variables are errors
+// unless a default is supplied.
+package interpolate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// Context holds the values expressions can reference. Nested maps are
+// addressed with dotted paths (e.g., "cloud_run.memory").
+type Context map[string]any
+
+// Lookup returns the value at a dotted path.
+func (c Context) Lookup(path string) (any, bool) {
+	value, found := configutil.GetNested(c, strings.Split(path, ".")...)
+	if !found || value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Paths returns every leaf path in the context, sorted. Used for suggestions.
+func (c Context) Paths() []string {
+	var paths []string
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for key, value := range m {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			switch value.(type) {
+			case map[string]any, map[any]any:
+				walk(path, configutil.MapFromAny(value))
+			default:
+				paths = append(paths, path)
+			}
+		}
+	}
+	walk("", c)
+	sort.Strings(paths)
+	return paths
+}
+
+// Expand evaluates every expression in s. List values are joined with spaces.
+func Expand(s string, ctx Context) (string, error) {
+	parts, err := parse(s)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		if part.expr == nil {
+			sb.WriteString(part.text)
+			continue
+		}
+		value, err := part.expr.eval(ctx)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(render(value))
+	}
+	return sb.String(), nil
+}
+
+// ExpandArgs evaluates every argument. An argument consisting of a single
+// expression whose value is a list expands into one argument per item.
+func ExpandArgs(args []string, ctx Context) ([]string, error) {
+	result := make([]string, 0, len(args))
+	for _, arg := range args {
+		expanded, err := expandArg(arg, ctx)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, expanded...)
+	}
+	return result, nil
+}
+
+// ExpandCommand evaluates a recipe command: a string, []string or []any.
+// List commands use ExpandArgs semantics; non-string items in []any are kept as-is.
+func ExpandCommand(cmd any, ctx Context) (any, error) {
+	switch v := cmd.(type) {
+	case string:
+		return Expand(v, ctx)
+	case []string:
+		return ExpandArgs(v, ctx)
+	case []any:
+		result := make([]any, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				result = append(result, item)
+				continue
+			}
+			expanded, err := expandArg(s, ctx)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range expanded {
+				result = append(result, e)
+			}
+		}
+		return result, nil
+	default:
+		return cmd, nil
+	}
+}
+
+// ExpandValue evaluates every string in a decoded YAML value, recursing into
+// maps and lists. Other values are kept as-is.
+func ExpandValue(value any, ctx Context) (any, error) {
+	switch v := value.(type) {
+	case string:
+		return Expand(v, ctx)
+	case []any:
+		result := make([]any, len(v))
+		for i, item := range v {
+			expanded, err := ExpandValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	case map[string]any:
+		result := make(map[string]any, len(v))
+		for key, item := range v {
+			expanded, err := ExpandValue(item, ctx)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+func expandArg(arg string, ctx Context) ([]string, error) {
+	parts, err := parse(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(parts) == 1 && parts[0].expr != nil {
+		value, err := parts[0].expr.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if list, ok := value.([]any); ok {
+			items := make([]string, 0, len(list))
+			for _, item := range list {
+				items = append(items, render(item))
+			}
+			return items, nil
+		}
+		return []string{render(value)}, nil
+	}
+
+	expanded, err := Expand(arg, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []string{expanded}, nil
+}
+
+// render formats a value for output.
+func render(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, render(item))
+		}
+		return strings.Join(items, " ")
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// normalize converts lookup results to the value types functions operate on:
+// []string becomes []any.
+func normalize(value any) any {
+	if list, ok := value.([]string); ok {
+		result := make([]any, len(list))
+		for i, s := range list {
+			result[i] = s
+		}
+		return result
+	}
+	return value
+}
+
+func undefinedError(path string, ctx Context) error {
+	if suggestion := suggest.FormatSuggestion(path, ctx.Paths()); suggestion != "" {
+		return errors.New("undefined variable '%s' - %s", path, suggestion)
+	}
+	return errors.New("undefined variable '%s'", path)
+}
@@ -0,0 +1,174 @@
+package interpolate_test
+
+import (
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/interpolate"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testContext() interpolate.Context {
+	return interpolate.Context{
+		"name":   "api",
+		"region": "US-Central1",
+		"cloud_run": map[string]any{
+			"memory":        "512Mi",
+			"max_instances": 10,
+			"cpu":           1.5,
+		},
+		"legacy":  map[any]any{"enabled": true},
+		"tags":    []any{"a", "b c"},
+		"flags":   []string{"--x", "--y"},
+		"empty":   "",
+		"message": "it's here",
+	}
+}
+
+func TestExpand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "no expressions", input: "echo hello", expected: "echo hello"},
+		{name: "top level", input: "deploy ${name}", expected: "deploy api"},
+		{name: "nested", input: "--memory=${cloud_run.memory}", expected: "--memory=512Mi"},
+		{name: "int", input: "${cloud_run.max_instances}", expected: "10"},
+		{name: "float", input: "${cloud_run.cpu}", expected: "1.5"},
+		{name: "bool in yaml.v2 map", input: "${legacy.enabled}", expected: "true"},
+		{name: "whitespace", input: "${ name }", expected: "api"},
+		{name: "lower", input: "${region | lower}", expected: "us-central1"},
+		{name: "upper", input: "${name|upper}", expected: "API"},
+		{name: "default for undefined", input: `${missing | default "fallback"}`, expected: "fallback"},
+		{name: "default for empty", input: `${empty | default "fallback"}`, expected: "fallback"},
+		{name: "default unused", input: `${name | default "fallback"}`, expected: "api"},
+		{name: "default then lower", input: `${missing | default "ABC" | lower}`, expected: "abc"},
+		{name: "function skipped until default", input: `${missing | lower | default "X"}`, expected: "X"},
+		{name: "join", input: `${tags | join ","}`, expected: "a,b c"},
+		{name: "join default separator", input: "${flags | join}", expected: "--x,--y"},
+		{name: "list in string context", input: "tags: ${tags}", expected: "tags: a b c"},
+		{name: "quote", input: "echo ${message | quote}", expected: `echo 'it'\''s here'`},
+		{name: "quote list", input: "${tags | quote | join \" \"}", expected: "'a' 'b c'"},
+		{name: "string literal", input: `${"x}y" | upper}`, expected: "X}Y"},
+		{name: "escaped quote in literal", input: `${"say \"hi\""}`, expected: `say "hi"`},
+		{name: "escape", input: "echo $${HOME} ${name}", expected: "echo ${HOME} api"},
+		{name: "dollar without brace", input: "echo $HOME", expected: "echo $HOME"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			result, err := interpolate.Expand(tt.input, testContext())
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExpandErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		msg   string
+	}{
+		{name: "undefined", input: "${bucket}", msg: "undefined variable 'bucket'"},
+		{name: "undefined nested with suggestion", input: "${cloud_run.memroy}", msg: "did you mean 'cloud_run.memory'?"},
+		{name: "undefined through function", input: "${bucket | lower}", msg: "undefined variable 'bucket'"},
+		{name: "unterminated", input: "echo ${name", msg: "unterminated expression"},
+		{name: "unterminated string", input: `${missing | default "x}`, msg: "unterminated"},
+		{name: "unknown function", input: "${name | lowr}", msg: "unknown function 'lowr'"},
+		{name: "unknown function suggestion", input: "${name | lowr}", msg: "did you mean 'lower'"},
+		{name: "missing argument", input: "${name | default}", msg: "function 'default' in '${name | default}' takes exactly 1 argument"},
+		{name: "too many arguments", input: `${name | lower "x"}`, msg: "takes no arguments"},
+		{name: "empty expression", input: "${}", msg: "invalid expression"},
+		{name: "invalid path", input: "${na$me}", msg: "invalid variable name"},
+		{name: "missing function", input: "${name | }", msg: "expected a function name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := interpolate.Expand(tt.input, testContext())
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	t.Parallel()
+
+	args, err := interpolate.ExpandArgs([]string{"deploy", "${tags}", "--name=${name}", "${flags}", "${tags | join}"}, testContext())
+	require.NoError(t, err)
+	require.Equal(t, []string{"deploy", "a", "b c", "--name=api", "--x", "--y", "a,b c"}, args)
+
+	_, err = interpolate.ExpandArgs([]string{"${missing}"}, testContext())
+	require.Error(t, err)
+}
+
+func TestExpandCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext()
+
+	str, err := interpolate.ExpandCommand("echo ${name}", ctx)
+	require.NoError(t, err)
+	require.Equal(t, "echo api", str)
+
+	strs, err := interpolate.ExpandCommand([]string{"echo", "${tags}"}, ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"echo", "a", "b c"}, strs)
+
+	anys, err := interpolate.ExpandCommand([]any{"sleep", 30, "${flags}"}, ctx)
+	require.NoError(t, err)
+	require.Equal(t, []any{"sleep", 30, "--x", "--y"}, anys)
+
+	other, err := interpolate.ExpandCommand(42, ctx)
+	require.NoError(t, err)
+	require.Equal(t, 42, other)
+}
+
+func TestExpandValue(t *testing.T) {
+	t.Parallel()
+
+	value, err := interpolate.ExpandValue(map[string]any{
+		"name":     "${name}",
+		"replicas": 3,
+		"args":     []any{"--memory=${cloud_run.memory}", true},
+		"nested":   map[string]any{"zone": "${zone | default \"a\"}"},
+	}, testContext())
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"name":     "api",
+		"replicas": 3,
+		"args":     []any{"--memory=512Mi", true},
+		"nested":   map[string]any{"zone": "a"},
+	}, value)
+
+	_, err = interpolate.ExpandValue([]any{"${missing}"}, testContext())
+	require.Error(t, err)
+}
+
+func TestContextPaths(t *testing.T) {
+	t.Parallel()
+
+	ctx := interpolate.Context{
+		"name":   "api",
+		"nested": map[string]any{"a": 1, "b": map[any]any{"c": 2}},
+	}
+	require.Equal(t, []string{"name", "nested.a", "nested.b.c"}, ctx.Paths())
+
+	value, found := ctx.Lookup("nested.b.c")
+	require.True(t, found)
+	require.Equal(t, 2, value)
+
+	_, found = ctx.Lookup("nested.x")
+	require.False(t, found)
+}
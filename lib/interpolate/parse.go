@@ -0,0 +1,206 @@
+package interpolate
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// part is either literal text or an expression.
+type part struct {
+	text string
+	expr *expression
+}
+
+// expression is a value source followed by a pipeline of function calls.
+type expression struct {
+	source   string
+	path     string // variable path; empty if literal is used
+	literal  string
+	pipeline []call
+}
+
+// call is a single pipeline stage, e.g. `default "x"`.
+type call struct {
+	name string
+	args []string
+}
+
+// parse splits s into literal text and ${...} expressions.
+func parse(s string) ([]part, error) {
+	var parts []part
+	var text strings.Builder
+
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			text.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			text.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			return nil, errors.New("unterminated expression in '%s'", s)
+		}
+
+		expr, err := parseExpression(s[i+2 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		if text.Len() > 0 {
+			parts = append(parts, part{text: text.String()})
+			text.Reset()
+		}
+		parts = append(parts, part{expr: expr})
+		i = end + 1
+	}
+
+	if text.Len() > 0 {
+		parts = append(parts, part{text: text.String()})
+	}
+	return parts, nil
+}
+
+// closingBrace returns the index of the "}" ending an expression that starts
+// at start, skipping braces inside quoted strings. Returns -1 if there is none.
+func closingBrace(s string, start int) int {
+	inQuote := false
+	for i := start; i < len(s); i++ {
+		switch {
+		case inQuote && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && s[i] == '}':
+			return i
+		}
+	}
+	return -1
+}
+
+// parseExpression parses the inside of ${...}.
+func parseExpression(src string) (*expression, error) {
+	stages, err := splitPipeline(src)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &expression{source: strings.TrimSpace(src)}
+
+	head, err := tokenize(stages[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(head) != 1 {
+		return nil, errors.New("invalid expression '${%s}': expected a variable or quoted string", expr.source)
+	}
+	if head[0].quoted {
+		expr.literal = head[0].value
+	} else {
+		if !isPath(head[0].value) {
+			return nil, errors.New("invalid variable name '%s' in '${%s}'", head[0].value, expr.source)
+		}
+		expr.path = head[0].value
+	}
+
+	for _, stage := range stages[1:] {
+		tokens, err := tokenize(stage)
+		if err != nil {
+			return nil, err
+		}
+		if len(tokens) == 0 || tokens[0].quoted {
+			return nil, errors.New("invalid expression '${%s}': expected a function name after '|'", expr.source)
+		}
+
+		c := call{name: tokens[0].value}
+		for _, tok := range tokens[1:] {
+			c.args = append(c.args, tok.value)
+		}
+		if err := checkCall(c, expr.source); err != nil {
+			return nil, err
+		}
+		expr.pipeline = append(expr.pipeline, c)
+	}
+
+	return expr, nil
+}
+
+// splitPipeline splits an expression on "|" outside quoted strings.
+func splitPipeline(src string) ([]string, error) {
+	var stages []string
+	inQuote := false
+	start := 0
+	for i := 0; i < len(src); i++ {
+		switch {
+		case inQuote && src[i] == '\\':
+			i++
+		case src[i] == '"':
+			inQuote = !inQuote
+		case !inQuote && src[i] == '|':
+			stages = append(stages, src[start:i])
+			start = i + 1
+		}
+	}
+	if inQuote {
+		return nil, errors.New("unterminated string in '${%s}'", strings.TrimSpace(src))
+	}
+	return append(stages, src[start:]), nil
+}
+
+type token struct {
+	value  string
+	quoted bool
+}
+
+// tokenize splits a pipeline stage into whitespace-separated words and
+// double-quoted strings (with \" and \\ escapes).
+func tokenize(stage string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(stage); {
+		switch {
+		case unicode.IsSpace(rune(stage[i])):
+			i++
+		case stage[i] == '"':
+			var sb strings.Builder
+			i++
+			for ; i < len(stage) && stage[i] != '"'; i++ {
+				if stage[i] == '\\' && i+1 < len(stage) {
+					i++
+				}
+				sb.WriteByte(stage[i])
+			}
+			if i >= len(stage) {
+				return nil, errors.New("unterminated string in '%s'", strings.TrimSpace(stage))
+			}
+			i++
+			tokens = append(tokens, token{value: sb.String(), quoted: true})
+		default:
+			start := i
+			for i < len(stage) && !unicode.IsSpace(rune(stage[i])) && stage[i] != '"' {
+				i++
+			}
+			tokens = append(tokens, token{value: stage[start:i]})
+		}
+	}
+	return tokens, nil
+}
+
+// isPath reports whether s is a valid dotted variable path.
+func isPath(s string) bool {
+	if s == "" || strings.HasPrefix(s, ".") || strings.HasSuffix(s, ".") || strings.Contains(s, "..") {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' && r != '.' {
+			return false
+		}
+	}
+	return true
+}
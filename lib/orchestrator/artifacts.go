@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/cache"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/interpolate"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+)
+
+// recordArtifacts resolves the artifacts a successful step declared, in its
+// recipe and through its handler, and keeps them for the manifest.
+func (r *Runner) recordArtifacts(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cwd string, specs *artifact.Specs) {
+	declared := append(append([]artifact.Spec{}, step.Artifacts...), specs.List()...)
+	if len(declared) == 0 || r.options.DryRun {
+		return
+	}
+
+	artifacts, err := r.resolveArtifacts(svc, step, cwd, declared)
+	if err != nil {
+		output.Warning("Could not record artifacts of %s for %s: %v", step.Name, svc.DisplayName(), err)
+	}
+
+	r.artifactsMu.Lock()
+	r.artifacts = append(r.artifacts, artifacts...)
+	r.artifactsMu.Unlock()
+}
+
+// resolveArtifacts expands declared specs: file globs relative to the step's
+// working directory become one artifact per file with its digest and size,
+// and images get their registry digest once they've been pushed.
+func (r *Runner) resolveArtifacts(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cwd string, declared []artifact.Spec) ([]artifact.Artifact, error) {
+	ctx := r.interpolationContext(svc)
+
+	var artifacts []artifact.Artifact
+	for _, spec := range declared {
+		values, err := interpolate.ExpandArgs([]string{spec.Path, spec.Image, spec.Platform}, ctx)
+		if err != nil {
+			return artifacts, errors.Wrap(err, "artifacts")
+		}
+		path, image, platform := values[0], values[1], values[2]
+
+		base := artifact.Artifact{Kind: spec.Kind, Service: svc.DisplayName(), Step: step.Name, Platform: platform}
+		if image != "" {
+			base.Image = image
+			digest, err := r.imageDigest(image)
+			if err != nil {
+				output.Debugf("Could not get the digest of %s: %v", image, err)
+			}
+			base.Digest = digest
+			artifacts = append(artifacts, base)
+			continue
+		}
+
+		files, err := cache.ExpandGlobs(dirOrDot(cwd), []string{path})
+		if err != nil {
+			return artifacts, err
+		}
+		if len(files) == 0 {
+			output.Debugf("Artifact pattern '%s' of %s for %s matched no files", path, step.Name, svc.DisplayName())
+			continue
+		}
+		digests, err := cache.DigestFiles(dirOrDot(cwd), files)
+		if err != nil {
+			return artifacts, err
+		}
+		for _, file := range files {
+			a := base
+			a.Path = filepath.ToSlash(filepath.Join(cwd, filepath.FromSlash(file)))
+			a.Digest = "sha256:" + digests[file]
+			a.Size = fileSize(filepath.Join(dirOrDot(cwd), filepath.FromSlash(file)))
+			if a.Platform == "" {
+				a.Platform = artifact.PlatformFromName(file)
+			}
+			artifacts = append(artifacts, a)
+		}
+	}
+	return artifacts, nil
+}
+
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// manifestPath returns where the artifact manifest is written.
+func (r *Runner) manifestPath() string {
+	if r.options.ArtifactsPath == "" {
+		return artifact.DefaultPath
+	}
+	return r.options.ArtifactsPath
+}
+
+// writeManifest merges this run's artifacts into the manifest. A manifest
+// from a different tag is replaced rather than merged.
+func (r *Runner) writeManifest() {
+	r.artifactsMu.Lock()
+	artifacts := r.artifacts
+	r.artifactsMu.Unlock()
+	if len(artifacts) == 0 {
+		return
+	}
+
+	path := r.manifestPath()
+	manifest, err := artifact.Read(path)
+	if err != nil || manifest.Tag != r.options.Tag {
+		manifest = &artifact.Manifest{}
+	}
+	manifest.Tag = r.options.Tag
+	manifest.Merge(artifacts)
+	manifest.Prune(".")
+
+	if err := artifact.Write(path, manifest); err != nil {
+		output.Warning("Could not write artifact manifest: %v", err)
+		return
+	}
+	output.Debugf("Wrote %d artifact(s) to %s", len(manifest.Artifacts), path)
+}
+
+// manifestImages returns the image recorded for each service by an earlier
+// run with the same tag.
+func (r *Runner) manifestImages() map[string]string {
+	images := make(map[string]string)
+	manifest, err := artifact.Read(r.manifestPath())
+	if err != nil || manifest.Tag != r.options.Tag {
+		return images
+	}
+	for _, a := range manifest.Artifacts {
+		if a.Kind == artifact.KindImage && a.Image != "" {
+			images[a.Service] = a.Image
+		}
+	}
+	return images
+}
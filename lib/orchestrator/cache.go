@@ -0,0 +1,152 @@
+package orchestrator
+
+import (
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/cache"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/interpolate"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+)
+
+// stepHash returns the cache hash of a step's resolved command, environment
+// and inputs, or "" if the step isn't cached.
+func (r *Runner) stepHash(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cmd any, cwd string, env map[string]string) (string, error) {
+	if r.cache == nil || len(step.Inputs) == 0 {
+		return "", nil
+	}
+
+	ctx := r.interpolationContext(svc)
+	config := make(map[string]any)
+	var globs []string
+	for _, input := range step.Inputs {
+		if key, ok := strings.CutPrefix(input, cache.ConfigInputPrefix); ok {
+			key = strings.TrimSpace(key)
+			value, _ := ctx.Lookup(key)
+			config[key] = value
+			continue
+		}
+		expanded, err := interpolate.Expand(input, ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "inputs")
+		}
+		globs = append(globs, expanded)
+	}
+
+	files, err := cache.DigestGlobs(dirOrDot(svc.Path), globs)
+	if err != nil {
+		return "", errors.Wrap(err, "inputs")
+	}
+
+	return cache.Hash(cache.Spec{Command: cmd, Cwd: cwd, Env: env, Config: config, Files: files})
+}
+
+// isCached reports whether a step last succeeded with the same hash and its
+// outputs are unchanged since.
+func (r *Runner) isCached(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, hash, cwd string) bool {
+	entry, ok := r.cache.Get(svc.DisplayName(), step.Name)
+	if !ok || entry.Hash != hash {
+		return false
+	}
+	if len(step.Outputs) == 0 {
+		return true
+	}
+
+	digests, err := r.outputDigests(svc, step, cwd)
+	if err != nil || len(digests) == 0 {
+		return false
+	}
+	return cache.SameDigests(entry.Outputs, digests)
+}
+
+// saveCache records a successful step. Failures only cost a cache miss next time.
+func (r *Runner) saveCache(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, hash, cwd string) {
+	digests, err := r.outputDigests(svc, step, cwd)
+	if err != nil {
+		output.Debugf("Not caching %s for %s: %v", step.Name, svc.DisplayName(), err)
+		return
+	}
+
+	entry := cache.Entry{Service: svc.DisplayName(), Step: step.Name, Hash: hash, Outputs: digests}
+	if err := r.cache.Put(entry); err != nil {
+		output.Debugf("Not caching %s for %s: %v", step.Name, svc.DisplayName(), err)
+	}
+}
+
+// fetchRemote restores a step's outputs from the remote cache and records it
+// locally. It reports whether the step can be skipped.
+func (r *Runner) fetchRemote(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, hash, cwd string) bool {
+	// A step without outputs has nothing to restore, so only the local cache can skip it
+	if r.remote == nil || len(step.Outputs) == 0 {
+		return false
+	}
+
+	// Only the step's outputs may be restored, so a bundle can't overwrite sources
+	outputs, err := interpolate.ExpandArgs(step.Outputs, r.interpolationContext(svc))
+	if err != nil {
+		output.Debugf("Not fetching %s for %s: %v", step.Name, svc.DisplayName(), err)
+		return false
+	}
+
+	files, ok, err := r.remote.Download(hash, dirOrDot(cwd), outputs)
+	if err != nil {
+		output.Warning("Remote cache unavailable for %s (%s): %v", step.Name, svc.DisplayName(), err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	output.Debugf("Restored %d output(s) of %s for %s from the remote cache", len(files), step.Name, svc.DisplayName())
+	r.saveCache(svc, step, hash, cwd)
+	return true
+}
+
+// uploadRemote shares a successful step's outputs. Failures only cost other
+// runners a cache miss.
+func (r *Runner) uploadRemote(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, hash, cwd string) {
+	if r.remote == nil || r.remote.ReadOnly() {
+		return
+	}
+
+	files, err := r.outputFiles(svc, step, cwd)
+	if err == nil {
+		err = r.remote.Upload(hash, dirOrDot(cwd), files)
+	}
+	if err != nil {
+		output.Warning("Could not upload %s for %s to the remote cache: %v", step.Name, svc.DisplayName(), err)
+	}
+}
+
+// outputDigests digests the files matched by a step's outputs, relative to its working directory.
+func (r *Runner) outputDigests(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cwd string) (map[string]string, error) {
+	if len(step.Outputs) == 0 {
+		return nil, nil
+	}
+	files, err := r.outputFiles(svc, step, cwd)
+	if err != nil {
+		return nil, err
+	}
+	return cache.DigestFiles(dirOrDot(cwd), files)
+}
+
+// outputFiles returns the files matched by a step's outputs, relative to its working directory.
+func (r *Runner) outputFiles(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cwd string) ([]string, error) {
+	if len(step.Outputs) == 0 {
+		return nil, nil
+	}
+	globs, err := interpolate.ExpandArgs(step.Outputs, r.interpolationContext(svc))
+	if err != nil {
+		return nil, errors.Wrap(err, "outputs")
+	}
+	return cache.ExpandOutputs(dirOrDot(cwd), globs)
+}
+
+func dirOrDot(dir string) string {
+	if dir == "" {
+		return "."
+	}
+	return dir
+}
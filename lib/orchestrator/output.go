@@ -189,6 +189,8 @@ type JSONTaskInfo struct {
 	Service  string `json:"service"`
 	Step     string `json:"step"`
 	Success  bool   `json:"success"`
+	Cached   bool   `json:"cached,omitempty"`
+	Shared   bool   `json:"shared,omitempty"`
 	Duration string `json:"duration"`
 	Error    string `json:"error,omitempty"`
 }
@@ -200,10 +202,18 @@ func (o *OutputManager) PrintComplete(results []TaskResult) {
 
 	successCount := 0
 	failedCount := 0
+	cachedCount := 0
+	sharedCount := 0
 	var failedServices []string
 	var totalDuration time.Duration
 
 	for _, r := range results {
+		if r.Cached {
+			cachedCount++
+		}
+		if r.Shared && r.Success {
+			sharedCount++
+		}
 		if r.Success {
 			successCount++
 		} else {
@@ -225,6 +235,8 @@ func (o *OutputManager) PrintComplete(results []TaskResult) {
 				Service:  r.ServiceName,
 				Step:     r.StepName,
 				Success:  r.Success,
+				Cached:   r.Cached,
+				Shared:   r.Shared,
 				Duration: formatDuration(r.Duration),
 				Error:    errStr,
 			}
@@ -269,6 +281,12 @@ func (o *OutputManager) PrintComplete(results []TaskResult) {
 		fmt.Printf("     Failed: %s\n", strings.Join(failedServices, ", "))
 	}
 
+	if cachedCount > 0 {
+		fmt.Printf("     Cached: %d step(s) skipped\n", cachedCount)
+	}
+	if sharedCount > 0 {
+		fmt.Printf("     Shared: %d build step(s) reused across targets\n", sharedCount)
+	}
 	fmt.Printf("     Total time: %s\n", formatDuration(totalDuration))
 	fmt.Println()
 }
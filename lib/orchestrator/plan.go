@@ -0,0 +1,137 @@
+package orchestrator
+
+import (
+	"github.com/sid-technologies/pilum/ingredients/build"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/interpolate"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+)
+
+// hermeticGit is the commit reported to ${git.*} in hermetic mode.
+var hermeticGit = git.Info{
+	SHA:      "0000000000000000000000000000000000000000",
+	ShortSHA: "0000000",
+	Branch:   "main",
+}
+
+// PlannedStep is a step resolved for one service without running it.
+type PlannedStep struct {
+	Service string            `yaml:"service" json:"service"`
+	Step    string            `yaml:"step" json:"step"`
+	Command any               `yaml:"command,omitempty" json:"command,omitempty"` // nil if no handler produces a command
+	Cwd     string            `yaml:"cwd" json:"cwd"`
+	Env     map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Inputs and Outputs are the step's cache globs, with ${...} expanded
+	Inputs  []string `yaml:"inputs,omitempty" json:"inputs,omitempty"`
+	Outputs []string `yaml:"outputs,omitempty" json:"outputs,omitempty"`
+}
+
+// Plan resolves every step that Run would execute, in order, without running
+// anything: services in dependency order, then each service's recipe steps.
+// Tag filters and MaxSteps apply as they do for Run.
+func (r *Runner) Plan() ([]PlannedStep, error) {
+	if err := r.validateServices(); err != nil {
+		return nil, err
+	}
+
+	for _, svc := range r.services {
+		_, imageName := build.GenerateBuildCommand(svc, svc.RegistryName, r.options.Tag)
+		r.imageNames[svc.DisplayName()] = imageName
+	}
+
+	maxSteps := r.findMaxSteps()
+
+	var plan []PlannedStep
+	for _, svc := range r.services {
+		recipe := r.recipes[recipeKey(svc)]
+		for i := range recipe.Steps {
+			if i >= maxSteps {
+				break
+			}
+			step := &recipe.Steps[i]
+			if r.shouldSkipStep(step) {
+				continue
+			}
+
+			cmd, err := r.generateCommand(svc, step)
+			if err != nil {
+				return nil, errors.Wrap(err, "service '"+svc.DisplayName()+"'")
+			}
+
+			_, cwd, env := stepEnvironment(svc, step)
+			if cwd == "" {
+				cwd = "."
+			}
+
+			planned := PlannedStep{
+				Service: svc.DisplayName(),
+				Step:    step.Name,
+				Command: cmd,
+				Cwd:     cwd,
+			}
+			if len(env) > 0 {
+				planned.Env = env
+			}
+			if len(step.Inputs) > 0 {
+				if planned.Inputs, err = interpolate.ExpandArgs(step.Inputs, r.interpolationContext(svc)); err != nil {
+					return nil, errors.Wrap(err, "service '"+svc.DisplayName()+"': inputs")
+				}
+			}
+			if len(step.Outputs) > 0 {
+				if planned.Outputs, err = interpolate.ExpandArgs(step.Outputs, r.interpolationContext(svc)); err != nil {
+					return nil, errors.Wrap(err, "service '"+svc.DisplayName()+"': outputs")
+				}
+			}
+			plan = append(plan, planned)
+		}
+	}
+
+	return plan, nil
+}
+
+// StepNames returns the names of the steps Run would execute for svc, after
+// tag filters and MaxSteps. A service without a recipe has none.
+func (r *Runner) StepNames(svc serviceinfo.ServiceInfo) []string {
+	recipe, exists := r.recipes[recipeKey(svc)]
+	if !exists {
+		return nil
+	}
+
+	maxSteps := r.findMaxSteps()
+	var names []string
+	for i := range recipe.Steps {
+		if i >= maxSteps {
+			break
+		}
+		if !r.shouldSkipStep(&recipe.Steps[i]) {
+			names = append(names, recipe.Steps[i].Name)
+		}
+	}
+	return names
+}
+
+// stepEnvironment returns the execution mode, working directory ("" for the
+// current directory) and environment variables a step runs with.
+func stepEnvironment(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep) (string, string, map[string]string) {
+	execMode := step.ExecutionMode
+	if execMode == "" {
+		execMode = "root"
+	}
+	cwd := ""
+	if execMode == "service_dir" {
+		cwd = svc.Path
+	}
+
+	// Step env vars win over the service's build env vars
+	envVars := make(map[string]string)
+	for _, ev := range svc.BuildConfig.EnvVars {
+		envVars[ev.Name] = ev.Value
+	}
+	for k, v := range step.EnvVars {
+		envVars[k] = v
+	}
+
+	return execMode, cwd, envVars
+}
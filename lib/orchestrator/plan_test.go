@@ -0,0 +1,158 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/plugin"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunnerPlan(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Provider: "custom", Path: "services/api", DependsOn: []string{"db"},
+			BuildConfig: serviceinfo.BuildConfig{EnvVars: []serviceinfo.EnvVars{{Name: "CGO_ENABLED", Value: "0"}}}},
+		{Name: "db", Provider: "custom", Path: "services/db"},
+	}
+	recipes := []recepie.RecipeInfo{{
+		Provider: "custom",
+		Recipe: recepie.Recipe{
+			Name:     "custom",
+			Provider: "custom",
+			Steps: []recepie.RecipeStep{
+				{Name: "build", Command: "make ${name}", ExecutionMode: "service_dir", Tags: []string{"build"}},
+				{Name: "deploy", Command: []any{"deploy", "${name}", "${git.short_sha}", "${env.HOME | default \"none\"}"},
+					EnvVars: map[string]string{"CGO_ENABLED": "1"}, Tags: []string{"deploy"}},
+				{Name: "unhandled"},
+			},
+		},
+	}}
+
+	runner := NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true})
+	plan, err := runner.Plan()
+	require.NoError(t, err)
+
+	// Dependencies first, then steps in recipe order
+	require.Len(t, plan, 6)
+	require.Equal(t, PlannedStep{Service: "db", Step: "build", Command: "make db", Cwd: "services/db"}, plan[0])
+	require.Equal(t, PlannedStep{
+		Service: "api",
+		Step:    "deploy",
+		Command: []any{"deploy", "api", "0000000", "none"},
+		Cwd:     ".",
+		Env:     map[string]string{"CGO_ENABLED": "1"},
+	}, plan[4])
+	require.Equal(t, map[string]string{"CGO_ENABLED": "0"}, plan[3].Env)
+	require.Nil(t, plan[5].Command)
+
+	runner = NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true, OnlyTags: []string{"deploy"}})
+	plan, err = runner.Plan()
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, "deploy", plan[0].Step)
+}
+
+func TestRunnerPlanHandlerPlugin(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pilum-handler-k8s")
+	script := "#!/bin/sh\ncat > \"$0.request\"\n" +
+		`echo '{"version": 1, "command": ["kubectl", "apply"], "outputs": {"namespace": "prod"}}'` + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755)) //nolint:gosec // test plugin must be executable
+
+	services := []serviceinfo.ServiceInfo{{Name: "api", Provider: "k8s", Path: "services/api"}}
+	recipes := []recepie.RecipeInfo{{
+		Provider: "k8s",
+		Recipe: recepie.Recipe{
+			Name:     "k8s",
+			Provider: "k8s",
+			Steps: []recepie.RecipeStep{
+				{Name: "apply", Handler: "exec:" + path, With: map[string]any{"image": "${name}:${tag}"}},
+				{Name: "verify", Command: "kubectl -n ${outputs.namespace} rollout status ${name}"},
+			},
+		},
+	}}
+
+	runner := NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true})
+	plan, err := runner.Plan()
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Equal(t, []any{"kubectl", "apply"}, plan[0].Command)
+	require.Equal(t, "kubectl -n prod rollout status api", plan[1].Command)
+
+	data, err := os.ReadFile(path + ".request")
+	require.NoError(t, err)
+	var req plugin.Request
+	require.NoError(t, json.Unmarshal(data, &req))
+	require.Equal(t, "apply", req.Step)
+	require.Equal(t, map[string]any{"image": "api:v1"}, req.Params)
+	require.Equal(t, "services/api", req.Service.Path)
+
+	recipes[0].Recipe.Steps[0].Handler = "exec:" + filepath.Join(dir, "pilum-handler-missing")
+	_, err = NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true}).Plan()
+	require.ErrorContains(t, err, "step 'apply'")
+}
+
+func TestRunnerPlanUses(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{{Name: "api", Provider: "gcp", Template: "gcp-cloud-run", Path: "services/api"}}
+	recipes := []recepie.RecipeInfo{{
+		Provider: "gcp",
+		Recipe: recepie.Recipe{
+			Name:     "gcp",
+			Provider: "gcp",
+			Steps: []recepie.RecipeStep{
+				// Renamed steps keep their handler
+				{Name: "docker build", Uses: "docker/build", With: map[string]any{"dockerfile": "${service.path}/Dockerfile"}},
+				{Name: "build docker image"},
+				{Name: "push", Uses: "docker/pushh"},
+			},
+		},
+	}}
+
+	runner := NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true, MaxSteps: 2})
+	plan, err := runner.Plan()
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	require.Contains(t, plan[0].Command, "services/api/Dockerfile")
+	require.Contains(t, plan[1].Command, "./_templates/gcp-cloud-run")
+
+	_, err = NewRunner(services, recipes, RunnerOptions{Tag: "v1", Hermetic: true}).Plan()
+	require.ErrorContains(t, err, "unknown handler 'docker/pushh'")
+}
+
+func TestRunnerStepNames(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Provider: "custom"},
+		{Name: "other", Provider: "unknown"},
+	}
+	recipes := []recepie.RecipeInfo{{
+		Provider: "custom",
+		Recipe: recepie.Recipe{
+			Name:     "custom",
+			Provider: "custom",
+			Steps: []recepie.RecipeStep{
+				{Name: "build", Command: "make", Tags: []string{"build"}},
+				{Name: "deploy", Command: "deploy", Tags: []string{"deploy"}},
+			},
+		},
+	}}
+
+	runner := NewRunner(services, recipes, RunnerOptions{Hermetic: true})
+	require.Equal(t, []string{"build", "deploy"}, runner.StepNames(services[0]))
+	require.Empty(t, runner.StepNames(services[1]))
+
+	runner = NewRunner(services, recipes, RunnerOptions{Hermetic: true, ExcludeTags: []string{"deploy"}})
+	require.Equal(t, []string{"build"}, runner.StepNames(services[0]))
+}
@@ -0,0 +1,82 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/plugin"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+)
+
+// runHandlerPlugin asks a step's handler plugin for its command and records
+// the outputs it returns for later steps of the same service, and the
+// artifacts it declares into specs.
+func (r *Runner) runHandlerPlugin(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, specs *artifact.Specs) (any, error) {
+	name, ok := plugin.ParseHandler(step.Handler)
+	if !ok || name == "" {
+		return nil, errors.New("invalid handler '%s' (expected %s<executable>)", step.Handler, plugin.HandlerPrefix)
+	}
+
+	params, err := r.stepParams(svc, step)
+	if err != nil {
+		return nil, err
+	}
+
+	req := plugin.Request{
+		Step:   step.Name,
+		Params: params,
+		Service: plugin.Service{
+			Name:        svc.Name,
+			DisplayName: svc.DisplayName(),
+			Provider:    svc.Provider,
+			Region:      svc.Region,
+			Project:     svc.Project,
+			Path:        svc.Path,
+			Config:      svc.Config,
+		},
+		Outputs:      r.serviceOutputs(svc),
+		ImageName:    r.imageNames[svc.DisplayName()],
+		Tag:          r.options.Tag,
+		Registry:     svc.RegistryName,
+		TemplatePath: r.templatePath(),
+	}
+
+	resp, err := plugin.Invoke(name, req, time.Duration(step.HandlerTimeout)*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Outputs) > 0 {
+		r.outputsMu.Lock()
+		outputs := r.outputs[svc.DisplayName()]
+		if outputs == nil {
+			outputs = make(map[string]string)
+			r.outputs[svc.DisplayName()] = outputs
+		}
+		for key, value := range resp.Outputs {
+			outputs[key] = value
+		}
+		r.outputsMu.Unlock()
+	}
+
+	specs.Add(resp.Artifacts...)
+	return resp.Command, nil
+}
+
+// serviceOutputs returns a copy of the plugin outputs recorded for a service.
+func (r *Runner) serviceOutputs(svc serviceinfo.ServiceInfo) map[string]string {
+	r.outputsMu.Lock()
+	defer r.outputsMu.Unlock()
+
+	recorded := r.outputs[svc.DisplayName()]
+	if len(recorded) == 0 {
+		return nil
+	}
+	outputs := make(map[string]string, len(recorded))
+	for key, value := range recorded {
+		outputs[key] = value
+	}
+	return outputs
+}
@@ -2,12 +2,19 @@ package orchestrator
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/sid-technologies/pilum/ingredients/build"
+	"github.com/sid-technologies/pilum/ingredients/docker"
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/cache"
+	"github.com/sid-technologies/pilum/lib/configutil"
 	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/interpolate"
 	"github.com/sid-technologies/pilum/lib/output"
 	"github.com/sid-technologies/pilum/lib/recepie"
 	"github.com/sid-technologies/pilum/lib/registry"
@@ -20,20 +27,41 @@ type TaskResult struct {
 	ServiceName string
 	StepName    string
 	Success     bool
+	Cached      bool // Skipped because its inputs and outputs were unchanged
+	Shared      bool // Skipped because another target of the service ran the same build
 	Duration    time.Duration
 	Error       error
 }
 
 // Runner executes deployment pipelines for multiple services.
 type Runner struct {
-	services   []serviceinfo.ServiceInfo
-	recipes    map[string]recepie.Recipe
-	imageNames map[string]string // service name -> image name
-	options    RunnerOptions
-	output     *OutputManager
-	results    []TaskResult
-	resultsMu  sync.Mutex
-	registry   *registry.CommandRegistry
+	services    []serviceinfo.ServiceInfo
+	recipes     map[string]recepie.Recipe // keyed by recipeKey
+	pinErrors   map[string]error          // recipe reference -> why it didn't resolve
+	imageNames  map[string]string         // service display name -> image name (per region)
+	options     RunnerOptions
+	output      *OutputManager
+	results     []TaskResult
+	resultsMu   sync.Mutex
+	registry    *registry.CommandRegistry
+	git         git.Info
+	gitOnce     sync.Once
+	outputs     map[string]map[string]string // service display name -> handler plugin outputs
+	outputsMu   sync.Mutex
+	cache       *cache.Store        // nil if caching is disabled
+	remote      *cache.Remote       // nil if there is no remote cache or caching is disabled
+	artifacts   []artifact.Artifact // Produced by successful steps in this run
+	artifactsMu sync.Mutex
+	builds      map[string]*sharedBuild // buildKey -> build step shared by a service's targets
+	buildsMu    sync.Mutex
+	imageDigest func(image string) (string, error) // Registry digest of a pushed image
+}
+
+// sharedBuild is a build step run once for every target of a service.
+type sharedBuild struct {
+	once   sync.Once
+	owner  string // Display name of the target that ran it
+	result TaskResult
 }
 
 // stepTask represents a task for a specific service at a specific step.
@@ -56,6 +84,19 @@ type RunnerOptions struct {
 	MaxSteps     int      // Maximum number of steps to run (0 = all)
 	ExcludeTags  []string // Exclude steps with these tags (e.g., "deploy")
 	OnlyTags     []string // Only run steps with these tags (e.g., "deploy")
+	// Hermetic hides the machine from ${...} expressions: ${git.*} gets a fixed
+	// placeholder commit and ${env.*} is empty. Used for reproducible plans.
+	Hermetic bool
+	NoCache  bool   // Run every step, ignoring the step cache
+	CacheDir string // Step cache location (default .pilum/cache)
+	// RemoteCache is a shared cache consulted when a step misses the local
+	// cache, and uploaded to after it succeeds.
+	RemoteCache *cache.Remote
+	// ArtifactsPath is where the artifact manifest is written (default dist/artifacts.json).
+	ArtifactsPath string
+	// ReuseImages takes image names from the artifact manifest of an earlier
+	// run with the same tag, e.g. to push images built by a previous command.
+	ReuseImages bool
 }
 
 // NewRunner creates a new deployment runner.
@@ -77,17 +118,60 @@ func NewRunner(services []serviceinfo.ServiceInfo, recipes []recepie.RecipeInfo,
 	r := &Runner{
 		services:   sortedServices,
 		recipes:    make(map[string]recepie.Recipe),
+		pinErrors:  make(map[string]error),
 		imageNames: make(map[string]string),
+		outputs:    make(map[string]map[string]string),
+		builds:     make(map[string]*sharedBuild),
 		options:    opts,
 		output:     NewOutputManager(),
 		registry:   cmdRegistry,
 	}
 
-	// Index recipes by provider
-	for _, rec := range recipes {
-		r.recipes[rec.Provider] = rec.Recipe
+	r.imageDigest = docker.ImageDigest
+
+	// Steps with inputs are cached unless disabled; plans never touch the cache
+	if !opts.NoCache && !opts.DryRun && !opts.Hermetic {
+		dir := opts.CacheDir
+		if dir == "" {
+			dir = cache.DefaultDir
+		}
+		r.cache = cache.NewStore(dir)
+		r.remote = opts.RemoteCache
 	}
 
+	// Index recipes by provider, preferring the highest version
+	byProvider := recepie.Index(recipes, func(info recepie.RecipeInfo) string { return info.Provider })
+	for provider, rec := range byProvider {
+		r.recipes[provider] = rec.Recipe
+	}
+
+	// Services that pin a recipe ("recipe: gcp-cloud-run@2") use exactly that recipe
+	for _, svc := range r.services {
+		key := recipeKey(svc)
+		if svc.Recipe == "" || r.pinErrors[svc.Recipe] != nil {
+			continue
+		}
+		if _, done := r.recipes[key]; done {
+			continue
+		}
+		rec, err := recepie.Select(recipes, svc.Recipe)
+		if err != nil {
+			r.pinErrors[svc.Recipe] = err
+			continue
+		}
+		r.recipes[key] = rec.Recipe
+	}
+
+	// Fill in recipe defaults so handlers and substitution see the effective config
+	effective := make([]serviceinfo.ServiceInfo, len(r.services))
+	for i, svc := range r.services {
+		if recipe, exists := r.recipes[recipeKey(svc)]; exists {
+			svc = recipe.ApplyDefaults(svc)
+		}
+		effective[i] = svc
+	}
+	r.services = effective
+
 	// Calculate max name length for output alignment (use DisplayName for multi-region)
 	maxLen := 0
 	for _, svc := range services {
@@ -120,14 +204,28 @@ func (r *Runner) Run() error {
 		return nil
 	}
 
-	r.output.PrintHeader(fmt.Sprintf("Deploying %d service(s)", len(r.services)))
+	header := fmt.Sprintf("Deploying %d service(s)", len(r.services))
+	if env := r.services[0].Environment; env != "" {
+		header += " to " + env
+	}
+	r.output.PrintHeader(header)
 
 	// Pre-calculate image names for all services
+	var recorded map[string]string
+	if r.options.ReuseImages {
+		recorded = r.manifestImages()
+	}
 	for _, svc := range r.services {
 		_, imageName := build.GenerateBuildCommand(svc, svc.RegistryName, r.options.Tag)
-		r.imageNames[svc.Name] = imageName
+		if image, ok := recorded[svc.DisplayName()]; ok {
+			imageName = image
+		}
+		r.imageNames[svc.DisplayName()] = imageName
 	}
 
+	// Record what the successful steps produced, even if a later step fails
+	defer r.writeManifest()
+
 	// Execute step by step
 	for stepIdx := 0; stepIdx < maxSteps; stepIdx++ {
 		err := r.executeStep(stepIdx, maxSteps)
@@ -150,7 +248,10 @@ func (r *Runner) validateServices() error {
 		}
 
 		// Check for matching recipe
-		if _, exists := r.recipes[svc.Provider]; !exists {
+		if err := r.pinErrors[svc.Recipe]; err != nil {
+			return errors.Wrap(err, "service '"+svc.Name+"'")
+		}
+		if _, exists := r.recipes[recipeKey(svc)]; !exists {
 			return errors.New("service '%s' has provider '%s' but no recipe found for that provider",
 				svc.Name, svc.Provider)
 		}
@@ -158,11 +259,20 @@ func (r *Runner) validateServices() error {
 	return nil
 }
 
+// recipeKey returns the key a service's recipe is indexed under: its pinned
+// recipe reference if it has one, otherwise its provider.
+func recipeKey(svc serviceinfo.ServiceInfo) string {
+	if svc.Recipe != "" {
+		return "recipe:" + svc.Recipe
+	}
+	return svc.Provider
+}
+
 // findMaxSteps returns the max number of steps across all recipes.
 func (r *Runner) findMaxSteps() int {
 	maxSteps := 0
 	for _, svc := range r.services {
-		recipe, exists := r.recipes[svc.Provider]
+		recipe, exists := r.recipes[recipeKey(svc)]
 		if !exists {
 			continue
 		}
@@ -216,7 +326,7 @@ func (r *Runner) executeStep(stepIdx, totalSteps int) error {
 	stepNames := make(map[string]bool)
 
 	for _, svc := range r.services {
-		recipe, exists := r.recipes[svc.Provider]
+		recipe, exists := r.recipes[recipeKey(svc)]
 		if !exists {
 			continue
 		}
@@ -242,7 +352,7 @@ func (r *Runner) executeStep(stepIdx, totalSteps int) error {
 
 	// Show skipped services
 	for _, svc := range r.services {
-		recipe, exists := r.recipes[svc.Provider]
+		recipe, exists := r.recipes[recipeKey(svc)]
 		if !exists {
 			r.output.PrintSkipped(svc.DisplayName(), "no recipe")
 		} else if stepIdx >= len(recipe.Steps) {
@@ -252,7 +362,10 @@ func (r *Runner) executeStep(stepIdx, totalSteps int) error {
 
 	if r.options.DryRun {
 		for _, t := range tasks {
-			cmd := r.generateCommand(t.service, t.step)
+			cmd, err := r.generateCommand(t.service, t.step)
+			if err != nil {
+				return errors.Wrap(err, "service '"+t.service.DisplayName()+"'")
+			}
 			r.output.PrintDryRun(t.service.DisplayName(), t.step.Name, cmd)
 		}
 		return nil
@@ -306,7 +419,14 @@ func (r *Runner) executeTasksParallel(tasks []stepTask) error {
 			result := r.executeTask(task.service, task.step)
 			result.Duration = time.Since(startTime)
 
-			spinner.Complete(task.service.DisplayName(), result.Success, result.Duration, result.Error)
+			switch {
+			case result.Cached:
+				spinner.CompleteCached(task.service.DisplayName())
+			case result.Shared && result.Success:
+				spinner.CompleteShared(task.service.DisplayName())
+			default:
+				spinner.Complete(task.service.DisplayName(), result.Success, result.Duration, result.Error)
+			}
 
 			resultChan <- result
 		}()
@@ -343,20 +463,45 @@ func (r *Runner) executeTask(svc serviceinfo.ServiceInfo, step *recepie.RecipeSt
 		StepName:    step.Name,
 	}
 
-	cmd := r.generateCommand(svc, step)
+	var specs artifact.Specs
+	cmd, err := r.generate(svc, step, &specs)
+	if err != nil {
+		result.Error = err
+		return result
+	}
 	if cmd == nil {
 		result.Success = true
 		return result
 	}
 
-	// Determine working directory
-	cwd := ""
-	execMode := step.ExecutionMode
-	if execMode == "" {
-		execMode = "root"
+	execMode, cwd, envVars := stepEnvironment(svc, step)
+
+	// Targets of a service share identical build steps
+	if key := r.buildKey(svc, step, cmd, execMode, cwd, envVars); key != "" {
+		return r.shareBuild(key, svc, step, func() TaskResult {
+			return r.runCommand(svc, step, cmd, execMode, cwd, envVars, &specs)
+		})
+	}
+	return r.runCommand(svc, step, cmd, execMode, cwd, envVars, &specs)
+}
+
+// runCommand runs a step's generated command, or skips it if it's cached.
+func (r *Runner) runCommand(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cmd any, execMode, cwd string, envVars map[string]string, specs *artifact.Specs) TaskResult {
+	result := TaskResult{
+		ServiceName: svc.DisplayName(),
+		StepName:    step.Name,
+	}
+
+	hash, err := r.stepHash(svc, step, cmd, cwd, envVars)
+	if err != nil {
+		result.Error = errors.Wrap(err, "step '"+step.Name+"'")
+		return result
 	}
-	if execMode == "service_dir" {
-		cwd = svc.Path
+	if hash != "" && (r.isCached(svc, step, hash, cwd) || r.fetchRemote(svc, step, hash, cwd)) {
+		result.Success = true
+		result.Cached = true
+		r.recordArtifacts(svc, step, cwd, specs)
+		return result
 	}
 
 	// Get timeout and retries
@@ -369,15 +514,6 @@ func (r *Runner) executeTask(svc serviceinfo.ServiceInfo, step *recepie.RecipeSt
 		retries = step.Retries
 	}
 
-	// Build env vars from step and service
-	envVars := make(map[string]string)
-	for _, ev := range svc.BuildConfig.EnvVars {
-		envVars[ev.Name] = ev.Value
-	}
-	for k, v := range step.EnvVars {
-		envVars[k] = v
-	}
-
 	taskInfo := workerqueue.NewTaskInfo(
 		cmd,
 		cwd,
@@ -393,73 +529,225 @@ func (r *Runner) executeTask(svc serviceinfo.ServiceInfo, step *recepie.RecipeSt
 	success, err := workerqueue.CommandWorker(taskInfo)
 	result.Success = success
 	result.Error = err
+	if success && hash != "" {
+		r.saveCache(svc, step, hash, cwd)
+		r.uploadRemote(svc, step, hash, cwd)
+	}
+	if success {
+		r.recordArtifacts(svc, step, cwd, specs)
+	}
 	return result
 }
 
+// buildKey identifies a build step that the targets of a service share: the
+// same command, run the same way for the same service. It's "" for steps
+// that aren't shared.
+func (r *Runner) buildKey(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, cmd any, execMode, cwd string, envVars map[string]string) string {
+	if svc.Target == "" || !r.stepHasAnyTag(step, []string{"build"}) {
+		return ""
+	}
+	return fmt.Sprintf("%s\x00%s\x00%q\x00%s\x00%s\x00%q", svc.Name, svc.Path, cmd, execMode, cwd, envVars)
+}
+
+// shareBuild runs a shared build step once. The other targets get its result.
+func (r *Runner) shareBuild(key string, svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, run func() TaskResult) TaskResult {
+	r.buildsMu.Lock()
+	build, ok := r.builds[key]
+	if !ok {
+		build = &sharedBuild{}
+		r.builds[key] = build
+	}
+	r.buildsMu.Unlock()
+
+	build.once.Do(func() {
+		build.owner = svc.DisplayName()
+		build.result = run()
+	})
+	if build.owner == svc.DisplayName() {
+		return build.result
+	}
+
+	output.Debugf("%s shares %s with %s", svc.DisplayName(), step.Name, build.owner)
+	return TaskResult{
+		ServiceName: svc.DisplayName(),
+		StepName:    step.Name,
+		Success:     build.result.Success,
+		Shared:      true,
+		Error:       build.result.Error,
+	}
+}
+
 // generateCommand creates the command for a step based on step name and provider.
-func (r *Runner) generateCommand(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep) any {
+func (r *Runner) generateCommand(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep) (any, error) {
+	return r.generate(svc, step, nil)
+}
+
+// generate creates the command for a step, collecting the artifacts its
+// handler declares into specs (if not nil).
+func (r *Runner) generate(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep, specs *artifact.Specs) (any, error) {
 	// If step has explicit command, use it (with variable substitution)
 	if step.Command != nil {
-		return r.substituteVars(step.Command, svc)
+		cmd, err := r.substituteVars(step.Command, svc)
+		if err != nil {
+			return nil, errors.Wrap(err, "step '"+step.Name+"'")
+		}
+		return cmd, nil
 	}
 
-	// Look up handler from registry
-	handler, found := r.registry.GetHandler(step.Name, svc.Provider)
-	if !found {
-		// Unknown step - let it pass (might be handled elsewhere)
-		return nil
+	// Steps naming a handler plugin get their command from it
+	if step.Handler != "" {
+		cmd, err := r.runHandlerPlugin(svc, step, specs)
+		if err != nil {
+			return nil, errors.Wrap(err, "step '"+step.Name+"'")
+		}
+		return cmd, nil
 	}
 
-	// Build context and execute handler
-	// Use service's registry_name, fall back to template path from options if service doesn't specify
-	templatePath := r.options.TemplatePath
-	if templatePath == "" {
-		templatePath = "./_templates"
+	// Look up handler from registry: by ID if the step has uses:, else by step name
+	var handler registry.StepHandler
+	if step.Uses != "" {
+		var found bool
+		handler, found = r.registry.Handler(step.Uses)
+		if !found {
+			return nil, errors.New("step '%s' uses unknown handler '%s'", step.Name, step.Uses)
+		}
+	} else {
+		var found bool
+		handler, found = r.registry.GetHandler(step.Name, svc.Provider)
+		if !found {
+			// Unknown step - let it pass (might be handled elsewhere)
+			return nil, nil
+		}
 	}
+
+	params, err := r.stepParams(svc, step)
+	if err != nil {
+		return nil, errors.Wrap(err, "step '"+step.Name+"'")
+	}
+
+	// Build context and execute handler
 	ctx := registry.StepContext{
 		Service:      svc,
-		ImageName:    r.imageNames[svc.Name],
+		ImageName:    r.imageNames[svc.DisplayName()],
 		Tag:          r.options.Tag,
 		Registry:     svc.RegistryName,
-		TemplatePath: templatePath,
+		TemplatePath: r.templatePath(),
+		Params:       params,
+		Artifacts:    specs,
 	}
 
-	return handler(ctx)
+	return handler(ctx), nil
 }
 
-// substituteVars replaces ${var} patterns in commands.
-func (r *Runner) substituteVars(cmd any, svc serviceinfo.ServiceInfo) any {
-	replacer := strings.NewReplacer(
-		"${name}", svc.Name,
-		"${service.name}", svc.Name,
-		"${provider}", svc.Provider,
-		"${region}", svc.Region,
-		"${project}", svc.Project,
-		"${build.version}", r.options.Tag,
-		"${tag}", r.options.Tag,
-	)
+// stepParams returns a step's with: values with ${...} expressions expanded.
+func (r *Runner) stepParams(svc serviceinfo.ServiceInfo, step *recepie.RecipeStep) (registry.Params, error) {
+	if len(step.With) == 0 {
+		return nil, nil
+	}
+	expanded, err := interpolate.ExpandValue(step.With, r.interpolationContext(svc))
+	if err != nil {
+		return nil, errors.Wrap(err, "with")
+	}
+	return configutil.MapFromAny(expanded), nil
+}
+
+// DefaultTemplatePath is the template path handlers use when
+// RunnerOptions.TemplatePath is empty.
+const DefaultTemplatePath = "./" + serviceinfo.TemplateDir
+
+// templatePath returns the template path handlers use, defaulting to DefaultTemplatePath.
+func (r *Runner) templatePath() string {
+	if r.options.TemplatePath == "" {
+		return DefaultTemplatePath
+	}
+	return r.options.TemplatePath
+}
 
-	switch v := cmd.(type) {
-	case string:
-		return replacer.Replace(v)
-	case []string:
-		result := make([]string, len(v))
-		for i, s := range v {
-			result[i] = replacer.Replace(s)
+// interpolationContext builds the values ${...} expressions can reference:
+// the service's effective config, service info, tag, git, env, matrix and
+// outputs of earlier handler plugin steps.
+func (r *Runner) interpolationContext(svc serviceinfo.ServiceInfo) interpolate.Context {
+	ctx := interpolate.Context(configutil.CloneMap(svc.Config))
+	if ctx == nil {
+		ctx = interpolate.Context{}
+	}
+
+	// Struct fields win over raw config (e.g., region for multi-region expansions)
+	for key, value := range map[string]string{
+		"name":          svc.Name,
+		"provider":      svc.Provider,
+		"region":        svc.Region,
+		"project":       svc.Project,
+		"registry_name": svc.RegistryName,
+	} {
+		if value != "" {
+			ctx[key] = value
 		}
-		return result
-	case []any:
-		result := make([]any, len(v))
-		for i, item := range v {
-			if s, ok := item.(string); ok {
-				result[i] = replacer.Replace(s)
-			} else {
-				result[i] = item
+	}
+
+	ctx["service"] = map[string]any{
+		"name":         svc.Name,
+		"display_name": svc.DisplayName(),
+		"target":       svc.Target,
+		"provider":     svc.Provider,
+		"region":       svc.Region,
+		"project":      svc.Project,
+		"path":         svc.Path,
+	}
+
+	ctx["tag"] = r.options.Tag
+	configutil.SetNested(ctx, r.options.Tag, "build", "version")
+
+	info := r.gitInfo()
+	ctx["git"] = map[string]any{
+		"sha":       info.SHA,
+		"short_sha": info.ShortSHA,
+		"branch":    info.Branch,
+	}
+
+	env := make(map[string]any)
+	if !r.options.Hermetic {
+		for _, kv := range os.Environ() {
+			if key, value, ok := strings.Cut(kv, "="); ok {
+				env[key] = value
 			}
 		}
-		return result
 	}
-	return cmd
+	// env.name is the active environment (--env); it shadows a variable named "name"
+	env["name"] = svc.Environment
+	ctx["env"] = env
+
+	matrix := make(map[string]any)
+	if svc.IsMultiRegion {
+		matrix["region"] = svc.Region
+	}
+	ctx["matrix"] = matrix
+
+	outputs := make(map[string]any)
+	for key, value := range r.serviceOutputs(svc) {
+		outputs[key] = value
+	}
+	ctx["outputs"] = outputs
+
+	return ctx
+}
+
+// gitInfo returns the current commit, looked up once per run.
+func (r *Runner) gitInfo() git.Info {
+	r.gitOnce.Do(func() {
+		if r.options.Hermetic {
+			r.git = hermeticGit
+			return
+		}
+		r.git = git.CurrentInfo()
+	})
+	return r.git
+}
+
+// substituteVars expands ${...} expressions in commands.
+// Returns an error for undefined variables or invalid expressions.
+func (r *Runner) substituteVars(cmd any, svc serviceinfo.ServiceInfo) (any, error) {
+	return interpolate.ExpandCommand(cmd, r.interpolationContext(svc))
 }
 
 // getWorkerCount returns the number of workers to use.
@@ -1,8 +1,14 @@
 package orchestrator
 
 import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/cache"
 	"github.com/sid-technologies/pilum/lib/recepie"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 
@@ -416,7 +422,8 @@ func TestRunnerSubstituteVars(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			result := runner.substituteVars(tt.cmd, svc)
+			result, err := runner.substituteVars(tt.cmd, svc)
+			require.NoError(t, err)
 			require.Equal(t, tt.expected, result)
 		})
 	}
@@ -545,7 +552,8 @@ func TestRunnerGenerateCommand(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			result := runner.generateCommand(svc, tt.step)
+			result, err := runner.generateCommand(svc, tt.step)
+			require.NoError(t, err)
 			require.Equal(t, tt.expected, result)
 		})
 	}
@@ -625,7 +633,8 @@ func TestRunnerGenerateCommandWithRegistry(t *testing.T) {
 
 	// Test with a registered handler (build)
 	step := &recepie.RecipeStep{Name: "build"}
-	result := runner.generateCommand(svc, step)
+	result, err := runner.generateCommand(svc, step)
+	require.NoError(t, err)
 	// Build command should return something (may be nil if no build cmd configured)
 	// Just verify it doesn't panic
 	_ = result
@@ -649,7 +658,8 @@ func TestRunnerGenerateCommandWithAnySlice(t *testing.T) {
 		Command: []any{"echo", "${name}", "--tag", "${tag}"},
 	}
 
-	result := runner.generateCommand(svc, step)
+	result, err := runner.generateCommand(svc, step)
+	require.NoError(t, err)
 	expected := []any{"echo", "myservice", "--tag", "v1.0.0"}
 	require.Equal(t, expected, result)
 }
@@ -1020,6 +1030,146 @@ func TestRunnerExecuteTaskServiceDirMode(t *testing.T) {
 	require.True(t, result.Success)
 }
 
+func TestRunnerExecuteTaskCache(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o600))
+
+	svc := serviceinfo.ServiceInfo{Name: "myservice", Provider: "test", Path: dir}
+	step := &recepie.RecipeStep{
+		Name:          "build",
+		Command:       "echo built >> dist/log && echo artifact > dist/app",
+		ExecutionMode: "service_dir",
+		Timeout:       5,
+		Inputs:        []string{"*.go", "config:provider"},
+		Outputs:       []string{"dist/app"},
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "dist"), 0o755))
+
+	opts := RunnerOptions{Tag: "v1.0.0", Timeout: 10, CacheDir: filepath.Join(dir, ".pilum", "cache")}
+	run := func(opts RunnerOptions) TaskResult {
+		result := NewRunner(nil, nil, opts).executeTask(svc, step)
+		require.True(t, result.Success, "%v", result.Error)
+		return result
+	}
+	builds := func() int {
+		data, err := os.ReadFile(filepath.Join(dir, "dist", "log"))
+		require.NoError(t, err)
+		return strings.Count(string(data), "built")
+	}
+
+	require.False(t, run(opts).Cached)
+	require.True(t, run(opts).Cached)
+	require.Equal(t, 1, builds())
+
+	// Changed inputs rebuild
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // v2"), 0o600))
+	require.False(t, run(opts).Cached)
+	require.True(t, run(opts).Cached)
+
+	// Changed or missing outputs rebuild
+	require.NoError(t, os.Remove(filepath.Join(dir, "dist", "app")))
+	require.False(t, run(opts).Cached)
+
+	// A different tag changes nothing the step depends on
+	opts.Tag = "v2.0.0"
+	require.True(t, run(opts).Cached)
+
+	// --no-cache always runs
+	opts.NoCache = true
+	require.False(t, run(opts).Cached)
+	require.Equal(t, 4, builds())
+}
+
+func TestRunnerExecuteTaskCachesBuiltInBuild(t *testing.T) {
+	t.Parallel()
+
+	recipes, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{})
+	require.NoError(t, err)
+	info, err := recepie.Select(recipes, "gcp-cloud-run")
+	require.NoError(t, err)
+	require.Equal(t, "build binary", info.Recipe.Steps[0].Name)
+	step := &info.Recipe.Steps[0]
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o600))
+	svc := serviceinfo.ServiceInfo{
+		Name:        "api",
+		Provider:    "gcp",
+		Path:        dir,
+		BuildConfig: serviceinfo.BuildConfig{Cmd: "mkdir -p dist && echo built >> dist/log && echo artifact > dist/api"},
+	}
+
+	opts := RunnerOptions{Tag: "v1.0.0", Timeout: 10, CacheDir: filepath.Join(dir, ".pilum", "cache")}
+	run := func() TaskResult {
+		result := NewRunner([]serviceinfo.ServiceInfo{svc}, recipes, opts).executeTask(svc, step)
+		require.True(t, result.Success, "%v", result.Error)
+		return result
+	}
+
+	require.False(t, run().Cached)
+	require.True(t, run().Cached)
+	data, err := os.ReadFile(filepath.Join(dir, "dist", "log"))
+	require.NoError(t, err)
+	require.Equal(t, "built\n", string(data))
+
+	// A source change rebuilds
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main // v2"), 0o600))
+	require.False(t, run().Cached)
+}
+
+func TestRunnerExecuteTaskRemoteCache(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(cache.NewServer(t.TempDir(), "", false))
+	t.Cleanup(server.Close)
+
+	step := &recepie.RecipeStep{
+		Name:          "build",
+		Command:       "mkdir -p dist && echo artifact > dist/app",
+		ExecutionMode: "service_dir",
+		Timeout:       5,
+		Inputs:        []string{"*.go"},
+		Outputs:       []string{"dist/app"},
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main"), 0o600))
+	svc := serviceinfo.ServiceInfo{Name: "myservice", Provider: "test", Path: dir}
+
+	// Each run starts like a fresh CI runner: no outputs and an empty local cache
+	run := func(readOnly bool) TaskResult {
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "dist")))
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, ".pilum")))
+
+		opts := RunnerOptions{
+			Timeout:     10,
+			CacheDir:    filepath.Join(dir, ".pilum", "cache"),
+			RemoteCache: cache.NewRemote(cache.RemoteOptions{URL: server.URL, ReadOnly: readOnly}),
+		}
+		result := NewRunner(nil, nil, opts).executeTask(svc, step)
+		require.True(t, result.Success, "%v", result.Error)
+		return result
+	}
+
+	// A read-only runner builds but doesn't share
+	require.False(t, run(true).Cached)
+	require.False(t, run(true).Cached)
+
+	require.False(t, run(false).Cached)
+
+	// Later runners restore the outputs instead of running the step
+	require.True(t, run(true).Cached)
+	data, err := os.ReadFile(filepath.Join(dir, "dist", "app"))
+	require.NoError(t, err)
+	require.Equal(t, "artifact\n", string(data))
+
+	// The restored step is now in the local cache too
+	opts := RunnerOptions{Timeout: 10, CacheDir: filepath.Join(dir, ".pilum", "cache")}
+	require.True(t, NewRunner(nil, nil, opts).executeTask(svc, step).Cached)
+}
+
 func TestRunnerExecuteTaskWithStepTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -1186,6 +1336,89 @@ func TestRunnerFullRunWithExecution(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestRunnerRunArtifactManifest(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	manifestPath := filepath.Join(t.TempDir(), "artifacts.json")
+	services := []serviceinfo.ServiceInfo{{Name: "myservice", Provider: "test", Path: dir}}
+	recipes := []recepie.RecipeInfo{{Provider: "test", Recipe: recepie.Recipe{
+		Name:     "test-recipe",
+		Provider: "test",
+		Steps: []recepie.RecipeStep{{
+			Name:          "build",
+			Command:       "mkdir -p dist && echo binary > dist/${name}_linux_amd64",
+			ExecutionMode: "service_dir",
+			Timeout:       5,
+			Artifacts: []artifact.Spec{
+				{Kind: artifact.KindBinary, Path: "dist/${name}_*"},
+				{Kind: artifact.KindImage, Image: "registry.example.com/${name}:${tag}"},
+			},
+		}},
+	}}}
+
+	run := func(opts RunnerOptions) *Runner {
+		opts.Timeout = 10
+		opts.ArtifactsPath = manifestPath
+		runner := NewRunner(services, recipes, opts)
+		require.NoError(t, runner.Run())
+		return runner
+	}
+
+	run(RunnerOptions{Tag: "v1.0.0"})
+	manifest, err := artifact.Read(manifestPath)
+	require.NoError(t, err)
+	require.Equal(t, "v1.0.0", manifest.Tag)
+	require.Len(t, manifest.Artifacts, 2)
+
+	binary := manifest.Artifacts[0]
+	require.Equal(t, artifact.KindBinary, binary.Kind)
+	require.Equal(t, "myservice", binary.Service)
+	require.Equal(t, "build", binary.Step)
+	require.Equal(t, filepath.ToSlash(filepath.Join(dir, "dist", "myservice_linux_amd64")), binary.Path)
+	require.Equal(t, "linux/amd64", binary.Platform)
+	require.Equal(t, int64(len("binary\n")), binary.Size)
+	require.True(t, strings.HasPrefix(binary.Digest, "sha256:"))
+	require.Equal(t, "registry.example.com/myservice:v1.0.0", manifest.Artifacts[1].Image)
+
+	// Later commands for the same tag reuse the recorded image
+	manifest.Artifacts[1].Image = "mirror.example.com/myservice:v1.0.0"
+	require.NoError(t, artifact.Write(manifestPath, manifest))
+	runner := run(RunnerOptions{Tag: "v1.0.0", ReuseImages: true})
+	require.Equal(t, "mirror.example.com/myservice:v1.0.0", runner.imageNames["myservice"])
+
+	// A new tag starts a new manifest; dry runs don't touch it
+	run(RunnerOptions{Tag: "v2.0.0"})
+	run(RunnerOptions{Tag: "v3.0.0", DryRun: true})
+	manifest, err = artifact.Read(manifestPath)
+	require.NoError(t, err)
+	require.Equal(t, "v2.0.0", manifest.Tag)
+	require.Len(t, manifest.Artifacts, 2)
+	require.Equal(t, "registry.example.com/myservice:v2.0.0", manifest.Artifacts[1].Image)
+}
+
+func TestResolveArtifactsImageDigest(t *testing.T) {
+	t.Parallel()
+
+	svc := serviceinfo.ServiceInfo{Name: "myservice", Provider: "test"}
+	runner := NewRunner([]serviceinfo.ServiceInfo{svc}, nil, RunnerOptions{Tag: "v1.0.0"})
+	runner.imageDigest = func(image string) (string, error) {
+		if image == "registry.example.com/myservice:v1.0.0" {
+			return "sha256:abc", nil
+		}
+		return "", nil
+	}
+
+	artifacts, err := runner.resolveArtifacts(svc, &recepie.RecipeStep{Name: "push"}, "", []artifact.Spec{
+		{Kind: artifact.KindImage, Image: "registry.example.com/${name}:${tag}"},
+		{Kind: artifact.KindImage, Image: "local/${name}:${tag}"},
+	})
+	require.NoError(t, err)
+	require.Len(t, artifacts, 2)
+	require.Equal(t, "sha256:abc", artifacts[0].Digest)
+	require.Empty(t, artifacts[1].Digest, "images that weren't pushed have no digest")
+}
+
 func TestRunnerFullRunMultipleServicesParallel(t *testing.T) {
 	t.Parallel()
 
@@ -1271,3 +1504,202 @@ func TestRunnerExecuteTaskEmptyExecutionMode(t *testing.T) {
 
 	require.True(t, result.Success)
 }
+
+func TestRunnerAppliesRecipeDefaults(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{{
+		Name:     "api",
+		Provider: "gcp",
+		Config: map[string]any{
+			"cloud_run": map[string]any{"max_instances": 3},
+		},
+	}}
+
+	recipes := []recepie.RecipeInfo{{
+		Provider: "gcp",
+		Recipe: recepie.Recipe{
+			Name:           "gcp-cloud-run",
+			Provider:       "gcp",
+			RequiredFields: []recepie.Field{{Name: "region", Type: "string", Default: "us-central1"}},
+			OptionalFields: []recepie.Field{
+				{Name: "cloud_run.max_instances", Type: "int", Default: "10"},
+				{Name: "cloud_run.memory", Type: "string", Default: "512Mi"},
+			},
+			Steps: []recepie.RecipeStep{{Name: "deploy to cloud run"}},
+		},
+	}}
+
+	runner := NewRunner(services, recipes, RunnerOptions{Tag: "v1"})
+	svc := runner.services[0]
+
+	require.Equal(t, "us-central1", svc.Region)
+
+	result, err := runner.generateCommand(svc, &recipes[0].Recipe.Steps[0])
+	require.NoError(t, err)
+	cmd, ok := result.([]string)
+	require.True(t, ok)
+	require.Contains(t, cmd, "--max-instances=3")
+	require.Contains(t, cmd, "512Mi")
+	require.Contains(t, cmd, "us-central1")
+
+	substituted, err := runner.substituteVars("deploy --memory ${cloud_run.memory}", svc)
+	require.NoError(t, err)
+	require.Equal(t, "deploy --memory 512Mi", substituted)
+
+	// The caller's config is left untouched
+	require.NotContains(t, services[0].Config["cloud_run"], "memory")
+}
+
+func TestRunnerRecipePins(t *testing.T) {
+	t.Parallel()
+
+	recipe := func(version, step string) recepie.RecipeInfo {
+		return recepie.RecipeInfo{
+			Provider: "gcp",
+			Recipe: recepie.Recipe{
+				Name:     "gcp-cloud-run",
+				Version:  version,
+				Provider: "gcp",
+				Steps:    []recepie.RecipeStep{{Name: step}},
+			},
+		}
+	}
+	recipes := []recepie.RecipeInfo{recipe("2", "deploy v2"), recipe("1", "deploy v1")}
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "latest", Provider: "gcp"},
+		{Name: "pinned", Provider: "gcp", Recipe: "gcp-cloud-run@1"},
+	}
+
+	runner := NewRunner(services, recipes, RunnerOptions{DryRun: true})
+	require.NoError(t, runner.validateServices())
+	require.Equal(t, "deploy v2", runner.recipes[recipeKey(services[0])].Steps[0].Name)
+	require.Equal(t, "deploy v1", runner.recipes[recipeKey(services[1])].Steps[0].Name)
+
+	services = []serviceinfo.ServiceInfo{{Name: "broken", Provider: "gcp", Recipe: "gcp-cloud-run@3"}}
+	runner = NewRunner(services, recipes, RunnerOptions{DryRun: true})
+	err := runner.Run()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "service 'broken'")
+	require.Contains(t, err.Error(), "no version matching '3' (available: 1, 2)")
+}
+
+func TestRunnerSubstituteVarsContext(t *testing.T) {
+	t.Setenv("PILUM_TEST_VALUE", "from-env")
+
+	svc := serviceinfo.ServiceInfo{
+		Name:          "api",
+		Provider:      "gcp",
+		Region:        "europe-west1",
+		IsMultiRegion: true,
+		Config: map[string]any{
+			"build":     map[string]any{"language": "go"},
+			"cloud_run": map[string]any{"flags": []any{"--a", "--b"}},
+		},
+	}
+	runner := NewRunner(nil, nil, RunnerOptions{Tag: "v2"})
+
+	result, err := runner.substituteVars([]any{
+		"echo",
+		"${env.PILUM_TEST_VALUE}",
+		"${matrix.region}",
+		"${build.language}-${build.version}",
+		"${service.display_name | lower}",
+		"${cloud_run.flags}",
+	}, svc)
+	require.NoError(t, err)
+	require.Equal(t, []any{"echo", "from-env", "europe-west1", "go-v2", "api (europe-west1)", "--a", "--b"}, result)
+
+	_, err = runner.substituteVars("echo ${git.sha}", svc)
+	require.NoError(t, err)
+}
+
+func TestRunnerSubstituteVarsEnvironment(t *testing.T) {
+	t.Parallel()
+
+	svc := serviceinfo.ServiceInfo{Name: "api", Provider: "gcp", Environment: "staging"}
+	runner := NewRunner(nil, nil, RunnerOptions{})
+
+	result, err := runner.substituteVars("deploy ${name} --env ${env.name}", svc)
+	require.NoError(t, err)
+	require.Equal(t, "deploy api --env staging", result)
+}
+
+func TestRunnerUndefinedVariableFailsTask(t *testing.T) {
+	t.Parallel()
+
+	svc := serviceinfo.ServiceInfo{Name: "api", Provider: "custom"}
+	runner := NewRunner(nil, nil, RunnerOptions{})
+	step := &recepie.RecipeStep{Name: "deploy", Command: "deploy --bucket ${bucket}"}
+
+	_, err := runner.generateCommand(svc, step)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "step 'deploy'")
+	require.Contains(t, err.Error(), "undefined variable 'bucket'")
+
+	result := runner.executeTask(svc, step)
+	require.False(t, result.Success)
+	require.Error(t, result.Error)
+}
+
+func TestRunnerSharesBuildAcrossTargets(t *testing.T) {
+	t.Parallel()
+
+	log := filepath.Join(t.TempDir(), "builds.log")
+	build := recepie.RecipeStep{
+		Name:          "build",
+		Command:       []string{"sh", "-c", "echo built >> " + log},
+		ExecutionMode: "root",
+		Tags:          []string{"build"},
+		Timeout:       5,
+	}
+	recipe := func(provider string) recepie.RecipeInfo {
+		return recepie.RecipeInfo{
+			Provider: provider,
+			Recipe:   recepie.Recipe{Name: provider, Provider: provider, Steps: []recepie.RecipeStep{build}},
+		}
+	}
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Provider: "one", Target: "one"},
+		{Name: "api", Provider: "two", Target: "two"},
+		{Name: "worker", Provider: "one"},
+	}
+	runner := NewRunner(services, []recepie.RecipeInfo{recipe("one"), recipe("two")}, RunnerOptions{Timeout: 10, MaxWorkers: 3})
+
+	var tasks []stepTask
+	for _, svc := range runner.services {
+		tasks = append(tasks, stepTask{service: svc, step: &build})
+	}
+	require.NoError(t, runner.executeTasksParallel(tasks))
+
+	data, err := os.ReadFile(log)
+	require.NoError(t, err)
+	require.Equal(t, 2, strings.Count(string(data), "built"), "api's targets should build once, worker separately")
+
+	shared := 0
+	for _, result := range runner.results {
+		require.True(t, result.Success)
+		if result.Shared {
+			shared++
+			require.Contains(t, result.ServiceName, "api [")
+		}
+	}
+	require.Equal(t, 1, shared)
+}
+
+func TestRunnerDoesNotShareOtherSteps(t *testing.T) {
+	t.Parallel()
+
+	deploy := &recepie.RecipeStep{Name: "deploy", Tags: []string{"deploy"}}
+	runner := NewRunner(nil, nil, RunnerOptions{})
+	svc := serviceinfo.ServiceInfo{Name: "api", Target: "lambda"}
+	require.Empty(t, runner.buildKey(svc, deploy, []string{"deploy"}, "root", "", nil))
+
+	build := &recepie.RecipeStep{Name: "build", Tags: []string{"build"}}
+	require.NotEmpty(t, runner.buildKey(svc, build, []string{"go", "build"}, "root", "", nil))
+
+	svc.Target = ""
+	require.Empty(t, runner.buildKey(svc, build, []string{"go", "build"}, "root", "", nil))
+}
@@ -41,6 +41,8 @@ type serviceSpinner struct {
 	frame    int
 	done     bool
 	success  bool
+	cached   bool
+	shared   bool
 	err      error
 	duration time.Duration
 }
@@ -140,6 +142,42 @@ func (sm *SpinnerManager) Complete(serviceName string, success bool, duration ti
 	}
 }
 
+// CompleteCached marks a spinner as complete because its step was cached.
+func (sm *SpinnerManager) CompleteCached(serviceName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if s, ok := sm.spinners[serviceName]; ok {
+		s.done = true
+		s.success = true
+		s.cached = true
+	}
+}
+
+// CompleteShared marks a spinner as complete because another target of the
+// service ran the same build step.
+func (sm *SpinnerManager) CompleteShared(serviceName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if s, ok := sm.spinners[serviceName]; ok {
+		s.done = true
+		s.success = true
+		s.shared = true
+	}
+}
+
+// detail returns what a successful spinner shows in parentheses.
+func (s *serviceSpinner) detail() string {
+	if s.cached {
+		return "cached"
+	}
+	if s.shared {
+		return "shared"
+	}
+	return formatDuration(s.duration)
+}
+
 // render updates all spinner displays.
 func (sm *SpinnerManager) render() {
 	sm.mu.Lock()
@@ -161,7 +199,7 @@ func (sm *SpinnerManager) render() {
 				fmt.Printf("\033[2K  %s%s%s %s %s(%s)%s\n",
 					colorSuccess, symbolSuccess, colorReset,
 					s.name,
-					colorMuted, formatDuration(s.duration), colorReset)
+					colorMuted, s.detail(), colorReset)
 			} else {
 				errMsg := ""
 				if s.err != nil {
@@ -200,7 +238,7 @@ func (sm *SpinnerManager) RenderFinal() {
 				fmt.Printf("  %s%s%s %s %s(%s)%s\n",
 					colorSuccess, symbolSuccess, colorReset,
 					s.name,
-					colorMuted, formatDuration(s.duration), colorReset)
+					colorMuted, s.detail(), colorReset)
 			} else if s.done {
 				errMsg := ""
 				if s.err != nil {
@@ -229,7 +267,7 @@ func (sm *SpinnerManager) RenderFinal() {
 			fmt.Printf("\033[2K  %s%s%s %s %s(%s)%s\n",
 				colorSuccess, symbolSuccess, colorReset,
 				s.name,
-				colorMuted, formatDuration(s.duration), colorReset)
+				colorMuted, s.detail(), colorReset)
 		} else if s.done {
 			errMsg := ""
 			if s.err != nil {
@@ -9,6 +9,7 @@ import (
 )
 
 var ProjectConfig = []string{
+	"pilum.workspace.yaml",
 	"package.json",
 	"cdk.json",
 	"tsconfig.json",
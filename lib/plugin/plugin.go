@@ -0,0 +1,222 @@
+// Package plugin runs external step handlers.
+//
+// A recipe step with `handler: exec:<name>` gets its command from the named
+// executable instead of a built-in handler. Pilum writes a JSON Request to the
+// plugin's stdin and reads a JSON Response from its stdout:
+//
+//	{"version": 1, "action": "generate", "step": "deploy", "service": {...}, ...}
+//	{"version": 1, "command": ["kubectl", "apply", "-f", "k8s/"], "outputs": {"namespace": "api"}}
+//
+// A plugin that exits non-zero, writes invalid JSON, or sets "error" fails the
+// step; its stderr is included in the error. Plugins are looked up on PATH, and
+// executables named pilum-handler-* are listed by `pilum plugin list`.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// ProtocolVersion is the request/response format pilum speaks.
+const ProtocolVersion = 1
+
+// HandlerPrefix marks a step handler that runs an executable.
+const HandlerPrefix = "exec:"
+
+// NamePrefix is the naming convention for plugins discovered on PATH.
+const NamePrefix = "pilum-handler-"
+
+// DefaultTimeout bounds a single plugin invocation.
+const DefaultTimeout = 30 * time.Second
+
+// Request actions.
+const (
+	ActionGenerate = "generate" // Produce the command for a step
+	ActionDescribe = "describe" // Describe the plugin, for `pilum plugin list`
+)
+
+// maxStderr is the number of trailing stderr bytes kept in error messages.
+const maxStderr = 2048
+
+// Request is sent to a plugin on stdin.
+type Request struct {
+	Version      int               `json:"version"`
+	Action       string            `json:"action"`
+	Step         string            `json:"step,omitempty"`
+	Params       map[string]any    `json:"params,omitempty"`  // The step's with: values, interpolated
+	Outputs      map[string]string `json:"outputs,omitempty"` // Outputs of earlier plugin steps for this service
+	Service      Service           `json:"service"`
+	ImageName    string            `json:"image_name,omitempty"`
+	Tag          string            `json:"tag,omitempty"`
+	Registry     string            `json:"registry,omitempty"`
+	TemplatePath string            `json:"template_path,omitempty"`
+}
+
+// Service is the service a step runs for.
+type Service struct {
+	Name        string         `json:"name"`
+	DisplayName string         `json:"display_name"`
+	Provider    string         `json:"provider"`
+	Region      string         `json:"region,omitempty"`
+	Project     string         `json:"project,omitempty"`
+	Path        string         `json:"path"`
+	Config      map[string]any `json:"config,omitempty"` // Effective service config
+}
+
+// Response is read from a plugin's stdout.
+type Response struct {
+	Version     int               `json:"version"`
+	Command     any               `json:"command,omitempty"` // string (run with sh -c) or list of args; omit to skip the step
+	Outputs     map[string]string `json:"outputs,omitempty"` // Available to later steps as ${outputs.<key>}
+	Artifacts   []artifact.Spec   `json:"artifacts,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+// Info describes a plugin found on PATH.
+type Info struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// ParseHandler returns the executable named by a step handler such as
+// "exec:pilum-handler-k8s", and whether the handler is an exec handler.
+func ParseHandler(handler string) (string, bool) {
+	name, ok := strings.CutPrefix(strings.TrimSpace(handler), HandlerPrefix)
+	return strings.TrimSpace(name), ok
+}
+
+// Invoke runs a plugin with req and returns its response. A zero timeout uses DefaultTimeout.
+func Invoke(name string, req Request, timeout time.Duration) (Response, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return Response{}, errors.New("handler plugin '%s' not found on PATH", name)
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	req.Version = ProtocolVersion
+	if req.Action == "" {
+		req.Action = ActionGenerate
+	}
+	input, err := json.Marshal(req)
+	if err != nil {
+		return Response{}, errors.Wrap(err, "failed to encode request for plugin '"+name+"'")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path) //nolint:gosec // plugin named by trusted recipe config
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return Response{}, errors.New("plugin '%s' timed out after %s", name, timeout)
+	}
+	if err != nil {
+		return Response{}, errors.New("plugin '%s' failed: %s%s", name, err.Error(), stderrSuffix(stderr.Bytes()))
+	}
+
+	var resp Response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		return Response{}, errors.New("plugin '%s' returned invalid JSON: %s%s", name, err.Error(), stderrSuffix(stderr.Bytes()))
+	}
+	if resp.Version != ProtocolVersion {
+		return Response{}, errors.New("plugin '%s' replied with protocol version %d (pilum supports %d)",
+			name, resp.Version, ProtocolVersion)
+	}
+	if resp.Error != "" {
+		return Response{}, errors.New("plugin '%s': %s", name, resp.Error)
+	}
+	if err := checkCommand(resp.Command); err != nil {
+		return Response{}, errors.Wrap(err, "plugin '"+name+"'")
+	}
+
+	return resp, nil
+}
+
+// Describe asks a plugin for its description.
+func Describe(name string, timeout time.Duration) (string, error) {
+	resp, err := Invoke(name, Request{Action: ActionDescribe}, timeout)
+	if err != nil {
+		return "", err
+	}
+	return resp.Description, nil
+}
+
+// Discover returns the pilum-handler-* executables on PATH, sorted by name.
+// Earlier PATH entries shadow later ones, as they do for exec.
+func Discover() []Info {
+	seen := make(map[string]bool)
+	var plugins []Info
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !strings.HasPrefix(name, NamePrefix) || seen[name] {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Info{Name: name, Path: path})
+		}
+	}
+
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// checkCommand accepts the command shapes a recipe step can have.
+func checkCommand(command any) error {
+	switch v := command.(type) {
+	case nil, string:
+		return nil
+	case []any:
+		for i, arg := range v {
+			if _, ok := arg.(string); !ok {
+				return errors.New("command argument %d is not a string", i+1)
+			}
+		}
+		return nil
+	default:
+		data, _ := json.Marshal(v)
+		return errors.New("command must be a string or a list of strings, got %s", data)
+	}
+}
+
+// stderrSuffix formats the tail of a plugin's stderr for an error message.
+func stderrSuffix(stderr []byte) string {
+	text := strings.TrimSpace(string(stderr))
+	if text == "" {
+		return ""
+	}
+	if len(text) > maxStderr {
+		text = "..." + text[len(text)-maxStderr:]
+	}
+	return "\n" + text
+}
@@ -0,0 +1,116 @@
+package plugin_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/plugin"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writePlugin writes an executable shell script plugin that saves its request
+// to <path>.request and runs body.
+func writePlugin(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	script := "#!/bin/sh\ncat > \"$0.request\"\n" + body + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(script), 0o755)) //nolint:gosec // test plugin must be executable
+	return path
+}
+
+func TestParseHandler(t *testing.T) {
+	t.Parallel()
+
+	name, ok := plugin.ParseHandler("exec:pilum-handler-k8s")
+	require.True(t, ok)
+	require.Equal(t, "pilum-handler-k8s", name)
+
+	_, ok = plugin.ParseHandler("docker/build")
+	require.False(t, ok)
+}
+
+func TestInvoke(t *testing.T) {
+	t.Parallel()
+
+	path := writePlugin(t, t.TempDir(), "pilum-handler-k8s",
+		`echo '{"version": 1, "command": ["kubectl", "apply", "-f", "k8s/"], "outputs": {"namespace": "api"}}'`)
+
+	resp, err := plugin.Invoke(path, plugin.Request{
+		Step:    "deploy",
+		Params:  map[string]any{"replicas": 3},
+		Service: plugin.Service{Name: "api", Provider: "k8s", Path: "services/api"},
+		Tag:     "v1",
+	}, 0)
+	require.NoError(t, err)
+	require.Equal(t, []any{"kubectl", "apply", "-f", "k8s/"}, resp.Command)
+	require.Equal(t, map[string]string{"namespace": "api"}, resp.Outputs)
+
+	data, err := os.ReadFile(path + ".request")
+	require.NoError(t, err)
+	var req plugin.Request
+	require.NoError(t, json.Unmarshal(data, &req))
+	require.Equal(t, plugin.ProtocolVersion, req.Version)
+	require.Equal(t, plugin.ActionGenerate, req.Action)
+	require.Equal(t, "deploy", req.Step)
+	require.Equal(t, map[string]any{"replicas": float64(3)}, req.Params)
+	require.Equal(t, "api", req.Service.Name)
+	require.Equal(t, "v1", req.Tag)
+}
+
+func TestInvokeErrors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		body    string
+		timeout time.Duration
+		msg     string
+	}{
+		{"exit", "echo 'cluster unreachable' >&2; exit 3", 0, "cluster unreachable"},
+		{"json", "echo 'not json'", 0, "returned invalid JSON"},
+		{"version", `echo '{"version": 2}'`, 0, "protocol version 2"},
+		{"error", `echo '{"version": 1, "error": "missing namespace"}'`, 0, "missing namespace"},
+		{"command", `echo '{"version": 1, "command": {"run": "x"}}'`, 0, "must be a string or a list"},
+		{"args", `echo '{"version": 1, "command": ["sleep", 3]}'`, 0, "argument 2 is not a string"},
+		{"timeout", "sleep 5", 100 * time.Millisecond, "timed out after 100ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := writePlugin(t, dir, "pilum-handler-"+tt.name, tt.body)
+			_, err := plugin.Invoke(path, plugin.Request{}, tt.timeout)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.msg)
+		})
+	}
+
+	_, err := plugin.Invoke(filepath.Join(dir, "pilum-handler-missing"), plugin.Request{}, 0)
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestDiscover(t *testing.T) {
+	first, second := t.TempDir(), t.TempDir()
+	writePlugin(t, first, "pilum-handler-k8s", `echo '{"version": 1, "description": "Kubernetes"}'`)
+	writePlugin(t, second, "pilum-handler-k8s", "exit 1")
+	writePlugin(t, second, "pilum-handler-fly", "exit 1")
+	writePlugin(t, second, "unrelated", "exit 1")
+	require.NoError(t, os.WriteFile(filepath.Join(second, "pilum-handler-noexec"), nil, 0o600))
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	plugins := plugin.Discover()
+	require.Equal(t, []plugin.Info{
+		{Name: "pilum-handler-fly", Path: filepath.Join(second, "pilum-handler-fly")},
+		{Name: "pilum-handler-k8s", Path: filepath.Join(first, "pilum-handler-k8s")},
+	}, plugins)
+
+	description, err := plugin.Describe("pilum-handler-k8s", 0)
+	require.NoError(t, err)
+	require.Equal(t, "Kubernetes", description)
+}
@@ -0,0 +1,75 @@
+package recepie
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+)
+
+// DefaultValue returns the field's default coerced to its declared type:
+// "10" becomes 10 for int fields, "true" becomes true for bool fields, and
+// "a, b" becomes []any{"a", "b"} for list fields. Returns false if the field
+// has no default or the default doesn't parse as the declared type.
+func (f *Field) DefaultValue() (any, bool) {
+	if f.Default == "" {
+		return nil, false
+	}
+
+	switch f.Type {
+	case "int":
+		n, err := strconv.Atoi(strings.TrimSpace(f.Default))
+		if err != nil {
+			return nil, false
+		}
+		return n, true
+	case "bool":
+		b, err := strconv.ParseBool(strings.TrimSpace(f.Default))
+		if err != nil {
+			return nil, false
+		}
+		return b, true
+	case "list":
+		parts := strings.Split(f.Default, ",")
+		items := make([]any, 0, len(parts))
+		for _, part := range parts {
+			if item := strings.TrimSpace(part); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, true
+	default:
+		return f.Default, true
+	}
+}
+
+// ApplyDefaults returns a copy of svc with the default of every declared field
+// the service doesn't set filled in. This is the effective config handlers and
+// command substitution see. The original service and its config are not modified.
+func (r *Recipe) ApplyDefaults(svc serviceinfo.ServiceInfo) serviceinfo.ServiceInfo {
+	svc.Config = configutil.CloneMap(svc.Config)
+	if svc.Config == nil {
+		svc.Config = make(map[string]any)
+	}
+
+	for _, fields := range [][]Field{r.RequiredFields, r.OptionalFields} {
+		for _, field := range fields {
+			if _, found := lookupServiceValue(&svc, field.Name); found {
+				continue
+			}
+
+			value, ok := field.DefaultValue()
+			if !ok {
+				continue
+			}
+
+			if structField, exists := serviceStructFields[field.Name]; exists {
+				*structField(&svc) = field.Default
+			}
+			configutil.SetNested(svc.Config, value, strings.Split(field.Name, ".")...)
+		}
+	}
+
+	return svc
+}
@@ -0,0 +1,94 @@
+package recepie_test
+
+import (
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldDefaultValue(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		field    recepie.Field
+		expected any
+		ok       bool
+	}{
+		{name: "no default", field: recepie.Field{Type: "int"}, ok: false},
+		{name: "untyped", field: recepie.Field{Default: "512Mi"}, expected: "512Mi", ok: true},
+		{name: "string", field: recepie.Field{Type: "string", Default: "1"}, expected: "1", ok: true},
+		{name: "int", field: recepie.Field{Type: "int", Default: "10"}, expected: 10, ok: true},
+		{name: "invalid int", field: recepie.Field{Type: "int", Default: "ten"}, ok: false},
+		{name: "bool", field: recepie.Field{Type: "bool", Default: "true"}, expected: true, ok: true},
+		{name: "invalid bool", field: recepie.Field{Type: "bool", Default: "yes please"}, ok: false},
+		{name: "list", field: recepie.Field{Type: "list", Default: "a, b,"}, expected: []any{"a", "b"}, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			value, ok := tt.field.DefaultValue()
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestApplyDefaults(t *testing.T) {
+	t.Parallel()
+
+	recipe := recepie.Recipe{
+		Name: "gcp-cloud-run",
+		RequiredFields: []recepie.Field{
+			{Name: "project", Type: "string", Default: "default-project"},
+			{Name: "region", Type: "string", Default: "us-central1"},
+		},
+		OptionalFields: []recepie.Field{
+			{Name: "cloud_run.min_instances", Type: "int", Default: "0"},
+			{Name: "cloud_run.max_instances", Type: "int", Default: "10"},
+			{Name: "cloud_run.cpu_throttling", Type: "bool", Default: "true"},
+			{Name: "cloud_run.cpu", Type: "string"},
+		},
+	}
+
+	svc := serviceinfo.ServiceInfo{
+		Name:   "api",
+		Region: "europe-west1",
+		Config: map[string]any{
+			"region":    "europe-west1",
+			"cloud_run": map[any]any{"max_instances": 3},
+		},
+	}
+
+	effective := recipe.ApplyDefaults(svc)
+
+	require.Equal(t, "default-project", effective.Project)
+	require.Equal(t, "europe-west1", effective.Region)
+	require.Equal(t, "default-project", effective.Config["project"])
+	require.Equal(t, map[string]any{
+		"min_instances":  0,
+		"max_instances":  3,
+		"cpu_throttling": true,
+	}, effective.Config["cloud_run"])
+
+	// The original service is not modified
+	require.Empty(t, svc.Project)
+	require.NotContains(t, svc.Config, "project")
+	require.Equal(t, map[any]any{"max_instances": 3}, svc.Config["cloud_run"])
+}
+
+func TestApplyDefaultsNilConfig(t *testing.T) {
+	t.Parallel()
+
+	recipe := recepie.Recipe{
+		OptionalFields: []recepie.Field{{Name: "lambda.runtime", Default: "provided.al2023"}},
+	}
+
+	effective := recipe.ApplyDefaults(serviceinfo.ServiceInfo{Name: "fn"})
+	require.Equal(t, map[string]any{"runtime": "provided.al2023"}, effective.Config["lambda"])
+}
@@ -0,0 +1,318 @@
+package recepie
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// Violation describes a single field that failed recipe validation.
+type Violation struct {
+	Field   string
+	Message string
+}
+
+// ValidationError collects every violation found when validating a service.
+type ValidationError struct {
+	Recipe     string
+	Violations []Violation
+}
+
+// Error implements the error interface, listing every violation.
+func (e *ValidationError) Error() string {
+	if len(e.Violations) == 1 {
+		return fmt.Sprintf("recipe '%s' %s", e.Recipe, e.Violations[0].Message)
+	}
+
+	lines := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		lines = append(lines, "  - "+v.Message)
+	}
+	return fmt.Sprintf("recipe '%s' found %d problems:\n%s", e.Recipe, len(e.Violations), strings.Join(lines, "\n"))
+}
+
+// validateFields checks every declared field against the service and returns
+// all violations: missing required values, wrong types, enum, pattern and
+// range constraints, and unknown keys inside sections the recipe declares.
+func (r *Recipe) validateFields(svc *serviceinfo.ServiceInfo) []Violation {
+	var violations []Violation
+	add := func(field, msg string, args ...any) {
+		violations = append(violations, Violation{Field: field, Message: fmt.Sprintf(msg, args...)})
+	}
+
+	for _, field := range r.RequiredFields {
+		value, found := lookupServiceValue(svc, field.Name)
+		if !found {
+			if field.Default == "" {
+				add(field.Name, "requires field '%s': %s", field.Name, field.Description)
+			}
+			continue
+		}
+		for _, msg := range field.check(value) {
+			add(field.Name, "%s", msg)
+		}
+	}
+
+	for _, field := range r.OptionalFields {
+		value, found := lookupServiceValue(svc, field.Name)
+		if !found {
+			if field.Default == "" && field.RequiredIf != "" && conditionMet(svc, field.RequiredIf) {
+				add(field.Name, "requires field '%s' when %s: %s", field.Name, field.RequiredIf, field.Description)
+			}
+			continue
+		}
+		for _, msg := range field.check(value) {
+			add(field.Name, "%s", msg)
+		}
+	}
+
+	violations = append(violations, r.unknownSectionKeys(svc)...)
+	return violations
+}
+
+// check validates a present value against the field's type and constraints.
+func (f *Field) check(value any) []string {
+	if msg := checkType(f.Name, f.Type, value); msg != "" {
+		// Further constraints assume the declared type
+		return []string{msg}
+	}
+
+	var problems []string
+
+	if len(f.Enum) > 0 {
+		str := scalarString(value)
+		if !slices.Contains(f.Enum, str) {
+			msg := fmt.Sprintf("expects field '%s' to be one of: %s (got '%s')", f.Name, strings.Join(f.Enum, ", "), str)
+			if suggestion := suggest.FormatSuggestion(str, f.Enum); suggestion != "" {
+				msg += " - " + suggestion
+			}
+			problems = append(problems, msg)
+		}
+	}
+
+	if f.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + f.Pattern + ")$")
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("declares invalid pattern for field '%s': %v", f.Name, err))
+		} else if str := scalarString(value); !re.MatchString(str) {
+			problems = append(problems, fmt.Sprintf("expects field '%s' to match pattern '%s' (got '%s')", f.Name, f.Pattern, str))
+		}
+	}
+
+	if f.Min != nil || f.Max != nil {
+		if num, ok := toFloat(value); ok {
+			if f.Min != nil && num < *f.Min {
+				problems = append(problems, fmt.Sprintf("expects field '%s' to be at least %s (got %s)", f.Name, formatNumber(*f.Min), formatNumber(num)))
+			}
+			if f.Max != nil && num > *f.Max {
+				problems = append(problems, fmt.Sprintf("expects field '%s' to be at most %s (got %s)", f.Name, formatNumber(*f.Max), formatNumber(num)))
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkType returns a violation message if value doesn't match fieldType.
+// Strings accept any scalar; an empty type accepts anything.
+func checkType(name, fieldType string, value any) string {
+	ok := true
+	switch fieldType {
+	case "string":
+		_, isMap := value.(map[string]any)
+		_, isAnyMap := value.(map[any]any)
+		_, isList := value.([]any)
+		ok = !isMap && !isAnyMap && !isList
+	case "int":
+		num, isNum := toFloat(value)
+		ok = isNum && num == math.Trunc(num)
+	case "bool":
+		_, ok = value.(bool)
+	case "list":
+		switch value.(type) {
+		case []any, []string:
+		default:
+			ok = false
+		}
+	default:
+		// Untyped field - accept anything
+	}
+
+	if ok {
+		return ""
+	}
+	return fmt.Sprintf("expects field '%s' to be %s, got %s", name, article(fieldType), describeValue(value))
+}
+
+// unknownSectionKeys reports keys inside config sections the recipe declares
+// fields for (e.g., cloud_run.*) that don't match any declared field.
+func (r *Recipe) unknownSectionKeys(svc *serviceinfo.ServiceInfo) []Violation {
+	declared := make(map[string][]string)
+	for _, field := range append(append([]Field{}, r.RequiredFields...), r.OptionalFields...) {
+		section, key, nested := strings.Cut(field.Name, ".")
+		if nested {
+			declared[section] = append(declared[section], key)
+		}
+	}
+
+	sections := make([]string, 0, len(declared))
+	for section := range declared {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+
+	var violations []Violation
+	for _, section := range sections {
+		raw, found := configutil.GetNested(svc.Config, section)
+		if !found {
+			continue
+		}
+
+		values := configutil.MapFromAny(raw)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if slices.Contains(declared[section], key) {
+				continue
+			}
+			name := section + "." + key
+			msg := fmt.Sprintf("has no field '%s'", name)
+			if suggestion := suggest.FormatSuggestion(key, declared[section]); suggestion != "" {
+				msg += " - " + suggestion
+			}
+			violations = append(violations, Violation{Field: name, Message: msg})
+		}
+	}
+
+	return violations
+}
+
+// conditionMet evaluates a required_if condition against the service.
+// "key=value" is met when key equals value; "key" alone is met when key is set.
+func conditionMet(svc *serviceinfo.ServiceInfo, condition string) bool {
+	key, expected, hasValue := strings.Cut(condition, "=")
+	value, found := lookupServiceValue(svc, strings.TrimSpace(key))
+	if !found {
+		return false
+	}
+	if !hasValue {
+		return true
+	}
+	return scalarString(value) == strings.TrimSpace(expected)
+}
+
+// lookupServiceValue returns the value of a (possibly nested) field for a service.
+// Struct-backed fields like name and region are read from ServiceInfo; everything
+// else is read from the raw config with its original type. Empty values are not found.
+func lookupServiceValue(svc *serviceinfo.ServiceInfo, fieldName string) (any, bool) {
+	if field, exists := serviceStructFields[fieldName]; exists {
+		if value := *field(svc); value != "" {
+			return value, true
+		}
+		return nil, false
+	}
+
+	value, found := configutil.GetNested(svc.Config, strings.Split(fieldName, ".")...)
+	if found && value != nil && value != "" {
+		return value, true
+	}
+
+	// Fall back to any other string field on ServiceInfo by yaml tag or name
+	v := reflect.ValueOf(svc).Elem()
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if field.Tag.Get("yaml") != fieldName && field.Name != fieldName {
+			continue
+		}
+		if fieldVal := v.Field(i); fieldVal.Kind() == reflect.String && fieldVal.String() != "" {
+			return fieldVal.String(), true
+		}
+	}
+
+	return nil, false
+}
+
+// serviceStructFields maps top-level field names to ServiceInfo struct fields.
+var serviceStructFields = map[string]func(*serviceinfo.ServiceInfo) *string{
+	"name":          func(s *serviceinfo.ServiceInfo) *string { return &s.Name },
+	"description":   func(s *serviceinfo.ServiceInfo) *string { return &s.Description },
+	"project":       func(s *serviceinfo.ServiceInfo) *string { return &s.Project },
+	"license":       func(s *serviceinfo.ServiceInfo) *string { return &s.License },
+	"region":        func(s *serviceinfo.ServiceInfo) *string { return &s.Region },
+	"provider":      func(s *serviceinfo.ServiceInfo) *string { return &s.Provider },
+	"template":      func(s *serviceinfo.ServiceInfo) *string { return &s.Template },
+	"registry_name": func(s *serviceinfo.ServiceInfo) *string { return &s.RegistryName },
+}
+
+// scalarString formats a scalar value for enum and pattern matching.
+func scalarString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return formatNumber(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// toFloat converts numeric values from YAML to float64.
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// describeValue names the YAML type of a value for error messages.
+func describeValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case bool:
+		return fmt.Sprintf("bool %v", v)
+	case int, int64, uint64:
+		return fmt.Sprintf("int %v", v)
+	case float64:
+		return "number " + formatNumber(v)
+	case []any, []string:
+		return "list"
+	case map[string]any, map[any]any:
+		return "mapping"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func article(fieldType string) string {
+	if fieldType == "int" {
+		return "an int"
+	}
+	return "a " + fieldType
+}
@@ -0,0 +1,270 @@
+package recepie
+
+import (
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// ResolveRecipes flattens every recipe that uses `extends:` into a standalone recipe.
+// Parents are resolved first, so chains (a extends b extends c) work. An unpinned
+// parent ("base") resolves to its highest version; "base@2" pins it.
+// Returns an error if a parent doesn't exist, a step patch names an unknown step,
+// or the inheritance chain contains a cycle.
+func ResolveRecipes(recipes []RecipeInfo) ([]RecipeInfo, error) {
+	byID := make(map[string]RecipeInfo, len(recipes))
+	versions := make(map[string]int)
+	names := make([]string, 0, len(recipes))
+	for _, info := range recipes {
+		byID[info.Recipe.ID()] = info
+		if versions[info.Recipe.Name] == 0 {
+			names = append(names, info.Recipe.Name)
+		}
+		versions[info.Recipe.Name]++
+	}
+
+	r := &resolver{
+		recipes:  recipes,
+		byID:     byID,
+		versions: versions,
+		names:    names,
+		resolved: make(map[string]Recipe),
+	}
+
+	result := make([]RecipeInfo, 0, len(recipes))
+	for _, info := range recipes {
+		recipe, err := r.resolve(info.Recipe.ID(), nil)
+		if err != nil {
+			return nil, err
+		}
+		info.Recipe = recipe
+		info.Provider = recipe.Provider
+		info.Service = recipe.Service
+		result = append(result, info)
+	}
+
+	return result, nil
+}
+
+// resolver memoizes resolved recipes, keyed by ID, while walking inheritance chains.
+type resolver struct {
+	recipes  []RecipeInfo
+	byID     map[string]RecipeInfo
+	versions map[string]int // number of versions of each recipe name
+	names    []string
+	resolved map[string]Recipe
+}
+
+// resolve returns the flattened recipe for id. chain holds the IDs of the
+// recipes currently being resolved and is used to report cycles.
+func (r *resolver) resolve(id string, chain []string) (Recipe, error) {
+	if recipe, ok := r.resolved[id]; ok {
+		return recipe, nil
+	}
+
+	for i, seen := range chain {
+		if seen == id {
+			cycle := make([]string, 0, len(chain)-i+1)
+			for _, link := range append(chain[i:], id) {
+				cycle = append(cycle, r.label(link))
+			}
+			return Recipe{}, errors.New("recipe inheritance cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	recipe := r.byID[id].Recipe
+	if recipe.Extends != "" {
+		parentID, err := r.parentID(recipe)
+		if err != nil {
+			return Recipe{}, err
+		}
+		parent, err := r.resolve(parentID, append(chain, id))
+		if err != nil {
+			return Recipe{}, err
+		}
+		recipe, err = extendRecipe(parent, recipe)
+		if err != nil {
+			return Recipe{}, err
+		}
+	}
+
+	r.resolved[id] = recipe
+	return recipe, nil
+}
+
+// parentID finds the recipe that child extends.
+func (r *resolver) parentID(child Recipe) (string, error) {
+	name, _ := ParseReference(child.Extends)
+	if r.versions[name] == 0 {
+		if suggestion := suggest.FormatSuggestion(name, r.names); suggestion != "" {
+			return "", errors.New("recipe '%s' extends unknown recipe '%s' - %s", child.Name, name, suggestion)
+		}
+		return "", errors.New("recipe '%s' extends unknown recipe '%s'", child.Name, name)
+	}
+
+	parent, err := Select(r.recipes, child.Extends)
+	if err != nil {
+		return "", errors.Wrap(err, "recipe '"+child.Name+"'")
+	}
+	return parent.Recipe.ID(), nil
+}
+
+// label returns the recipe name for id, with the version only if several versions exist.
+func (r *resolver) label(id string) string {
+	recipe := r.byID[id].Recipe
+	if r.versions[recipe.Name] > 1 {
+		return recipe.Name + "@" + recipe.VersionString()
+	}
+	return recipe.Name
+}
+
+// extendRecipe applies child on top of an already-resolved parent.
+// Scalars set in the child win, field lists are merged by name and
+// child steps are applied as patches to the parent's steps.
+func extendRecipe(parent, child Recipe) (Recipe, error) {
+	result := parent
+	result.Name = child.Name
+	result.Version = child.Version
+	result.Extends = ""
+
+	if child.RequiresPilum != "" {
+		result.RequiresPilum = child.RequiresPilum
+	}
+
+	if child.Description != "" {
+		result.Description = child.Description
+	}
+	if child.Provider != "" {
+		result.Provider = child.Provider
+	}
+	if child.Service != "" {
+		result.Service = child.Service
+	}
+
+	result.RequiredFields = mergeFields(parent.RequiredFields, child.RequiredFields)
+	result.OptionalFields = mergeFields(parent.OptionalFields, child.OptionalFields)
+
+	steps, err := patchSteps(parent.Steps, child.Steps)
+	if err != nil {
+		return Recipe{}, errors.Wrap(err, "recipe '"+child.Name+"'")
+	}
+	result.Steps = steps
+
+	return result, nil
+}
+
+// mergeFields merges two field lists by name. A child field replaces the parent
+// field with the same name in place; new fields are appended.
+func mergeFields(parent, child []Field) []Field {
+	if len(child) == 0 {
+		return parent
+	}
+
+	merged := make([]Field, len(parent), len(parent)+len(child))
+	copy(merged, parent)
+
+	index := make(map[string]int, len(merged))
+	for i, f := range merged {
+		index[f.Name] = i
+	}
+
+	for _, f := range child {
+		if i, exists := index[f.Name]; exists {
+			merged[i] = f
+			continue
+		}
+		index[f.Name] = len(merged)
+		merged = append(merged, f)
+	}
+
+	return merged
+}
+
+// patchSteps applies child steps to the parent's steps in order.
+// A child step without a patch operation is appended.
+func patchSteps(parent, child []RecipeStep) ([]RecipeStep, error) {
+	steps := make([]RecipeStep, len(parent), len(parent)+len(child))
+	copy(steps, parent)
+
+	for _, step := range child {
+		var err error
+		switch {
+		case step.Remove != "":
+			steps, err = removeStep(steps, step.Remove)
+		case step.Replace != "":
+			steps, err = replaceStep(steps, step)
+		case step.InsertBefore != "":
+			steps, err = insertStep(steps, step, step.InsertBefore, 0)
+		case step.InsertAfter != "":
+			steps, err = insertStep(steps, step, step.InsertAfter, 1)
+		default:
+			steps = append(steps, step)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return steps, nil
+}
+
+func removeStep(steps []RecipeStep, name string) ([]RecipeStep, error) {
+	i, err := findStep(steps, name, "remove")
+	if err != nil {
+		return nil, err
+	}
+	return append(steps[:i], steps[i+1:]...), nil
+}
+
+func replaceStep(steps []RecipeStep, step RecipeStep) ([]RecipeStep, error) {
+	i, err := findStep(steps, step.Replace, "replace")
+	if err != nil {
+		return nil, err
+	}
+	if step.Name == "" {
+		step.Name = step.Replace
+	}
+	step.Replace = ""
+	steps[i] = step
+	return steps, nil
+}
+
+// insertStep inserts step relative to the named target. offset is 0 to insert
+// before the target and 1 to insert after it.
+func insertStep(steps []RecipeStep, step RecipeStep, target string, offset int) ([]RecipeStep, error) {
+	op := "insert_before"
+	if offset == 1 {
+		op = "insert_after"
+	}
+
+	i, err := findStep(steps, target, op)
+	if err != nil {
+		return nil, err
+	}
+
+	step.InsertBefore = ""
+	step.InsertAfter = ""
+
+	pos := i + offset
+	steps = append(steps, RecipeStep{})
+	copy(steps[pos+1:], steps[pos:])
+	steps[pos] = step
+	return steps, nil
+}
+
+// findStep returns the index of the named step (case-insensitive).
+func findStep(steps []RecipeStep, name, op string) (int, error) {
+	names := make([]string, 0, len(steps))
+	for i, s := range steps {
+		if strings.EqualFold(s.Name, name) {
+			return i, nil
+		}
+		names = append(names, s.Name)
+	}
+
+	if suggestion := suggest.FormatSuggestion(name, names); suggestion != "" {
+		return -1, errors.New("%s: parent has no step '%s' - %s", op, name, suggestion)
+	}
+	return -1, errors.New("%s: parent has no step '%s'", op, name)
+}
@@ -0,0 +1,239 @@
+package recepie_test
+
+import (
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/recepie"
+
+	"github.com/stretchr/testify/require"
+)
+
+func baseRecipe() recepie.RecipeInfo {
+	return recepie.RecipeInfo{
+		Provider: "gcp",
+		Service:  "cloud-run",
+		Recipe: recepie.Recipe{
+			Name:        "base",
+			Description: "Base recipe",
+			Provider:    "gcp",
+			Service:     "cloud-run",
+			RequiredFields: []recepie.Field{
+				{Name: "project", Description: "GCP project"},
+				{Name: "region", Default: "us-central1"},
+			},
+			Steps: []recepie.RecipeStep{
+				{Name: "build", Tags: []string{"build"}},
+				{Name: "push", Tags: []string{"push"}},
+				{Name: "deploy", Tags: []string{"deploy"}},
+			},
+		},
+	}
+}
+
+func stepNames(r recepie.Recipe) []string {
+	names := make([]string, 0, len(r.Steps))
+	for _, s := range r.Steps {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func resolveOne(t *testing.T, child recepie.Recipe) recepie.RecipeInfo {
+	t.Helper()
+
+	resolved, err := recepie.ResolveRecipes([]recepie.RecipeInfo{baseRecipe(), {Recipe: child}})
+	require.NoError(t, err)
+	require.Len(t, resolved, 2)
+	return resolved[1]
+}
+
+func TestResolveRecipesInheritsParent(t *testing.T) {
+	t.Parallel()
+
+	info := resolveOne(t, recepie.Recipe{Name: "child", Extends: "base"})
+
+	require.Equal(t, "child", info.Recipe.Name)
+	require.Empty(t, info.Recipe.Extends)
+	require.Equal(t, "Base recipe", info.Recipe.Description)
+	require.Equal(t, "gcp", info.Provider)
+	require.Equal(t, "cloud-run", info.Service)
+	require.Equal(t, []string{"build", "push", "deploy"}, stepNames(info.Recipe))
+}
+
+func TestResolveRecipesStepPatches(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		steps    []recepie.RecipeStep
+		expected []string
+	}{
+		{
+			name:     "append",
+			steps:    []recepie.RecipeStep{{Name: "notify"}},
+			expected: []string{"build", "push", "deploy", "notify"},
+		},
+		{
+			name:     "insert before",
+			steps:    []recepie.RecipeStep{{Name: "lint", InsertBefore: "build"}},
+			expected: []string{"lint", "build", "push", "deploy"},
+		},
+		{
+			name:     "insert after",
+			steps:    []recepie.RecipeStep{{Name: "scan", InsertAfter: "push"}},
+			expected: []string{"build", "push", "scan", "deploy"},
+		},
+		{
+			name:     "insert after last",
+			steps:    []recepie.RecipeStep{{Name: "smoke test", InsertAfter: "deploy"}},
+			expected: []string{"build", "push", "deploy", "smoke test"},
+		},
+		{
+			name:     "replace keeps name",
+			steps:    []recepie.RecipeStep{{Replace: "push", Command: "echo push"}},
+			expected: []string{"build", "push", "deploy"},
+		},
+		{
+			name:     "replace with new name",
+			steps:    []recepie.RecipeStep{{Name: "upload", Replace: "push"}},
+			expected: []string{"build", "upload", "deploy"},
+		},
+		{
+			name:     "remove",
+			steps:    []recepie.RecipeStep{{Remove: "push"}},
+			expected: []string{"build", "deploy"},
+		},
+		{
+			name: "operations apply in order",
+			steps: []recepie.RecipeStep{
+				{Remove: "deploy"},
+				{Name: "release", InsertAfter: "push"},
+				{Name: "lint", InsertBefore: "release"},
+			},
+			expected: []string{"build", "push", "lint", "release"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			info := resolveOne(t, recepie.Recipe{Name: "child", Extends: "base", Steps: tt.steps})
+			require.Equal(t, tt.expected, stepNames(info.Recipe))
+
+			for _, s := range info.Recipe.Steps {
+				require.Empty(t, s.InsertBefore)
+				require.Empty(t, s.InsertAfter)
+				require.Empty(t, s.Replace)
+			}
+		})
+	}
+}
+
+func TestResolveRecipesReplaceUsesChildStep(t *testing.T) {
+	t.Parallel()
+
+	info := resolveOne(t, recepie.Recipe{
+		Name:    "child",
+		Extends: "base",
+		Steps:   []recepie.RecipeStep{{Replace: "push", Command: "echo push", Timeout: 30}},
+	})
+
+	require.Equal(t, "echo push", info.Recipe.Steps[1].Command)
+	require.Equal(t, 30, info.Recipe.Steps[1].Timeout)
+	require.Empty(t, info.Recipe.Steps[1].Tags)
+}
+
+func TestResolveRecipesUnknownStep(t *testing.T) {
+	t.Parallel()
+
+	child := recepie.Recipe{
+		Name:    "child",
+		Extends: "base",
+		Steps:   []recepie.RecipeStep{{Name: "x", InsertAfter: "pussh"}},
+	}
+
+	_, err := recepie.ResolveRecipes([]recepie.RecipeInfo{baseRecipe(), {Recipe: child}})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "did you mean 'push'?")
+}
+
+func TestResolveRecipesMergesFields(t *testing.T) {
+	t.Parallel()
+
+	info := resolveOne(t, recepie.Recipe{
+		Name:    "child",
+		Extends: "base",
+		RequiredFields: []recepie.Field{
+			{Name: "region", Default: "europe-west1"},
+			{Name: "cluster"},
+		},
+		OptionalFields: []recepie.Field{{Name: "replicas", Default: "2"}},
+	})
+
+	fields := info.Recipe.RequiredFields
+	require.Len(t, fields, 3)
+	require.Equal(t, "project", fields[0].Name)
+	require.Equal(t, "region", fields[1].Name)
+	require.Equal(t, "europe-west1", fields[1].Default)
+	require.Equal(t, "cluster", fields[2].Name)
+	require.Len(t, info.Recipe.OptionalFields, 1)
+}
+
+func TestResolveRecipesChain(t *testing.T) {
+	t.Parallel()
+
+	recipes := []recepie.RecipeInfo{
+		{Recipe: recepie.Recipe{Name: "grandchild", Extends: "child", Steps: []recepie.RecipeStep{{Remove: "build"}}}},
+		{Recipe: recepie.Recipe{Name: "child", Extends: "base", Steps: []recepie.RecipeStep{{Name: "lint", InsertBefore: "build"}}}},
+		baseRecipe(),
+	}
+
+	resolved, err := recepie.ResolveRecipes(recipes)
+	require.NoError(t, err)
+	require.Equal(t, []string{"lint", "push", "deploy"}, stepNames(resolved[0].Recipe))
+	require.Equal(t, []string{"lint", "build", "push", "deploy"}, stepNames(resolved[1].Recipe))
+	require.Equal(t, "gcp", resolved[0].Provider)
+
+	// The parent itself must be unchanged by its children's patches
+	require.Equal(t, []string{"build", "push", "deploy"}, stepNames(resolved[2].Recipe))
+}
+
+func TestResolveRecipesDetectsCycle(t *testing.T) {
+	t.Parallel()
+
+	recipes := []recepie.RecipeInfo{
+		{Recipe: recepie.Recipe{Name: "a", Extends: "b"}},
+		{Recipe: recepie.Recipe{Name: "b", Extends: "c"}},
+		{Recipe: recepie.Recipe{Name: "c", Extends: "a"}},
+	}
+
+	_, err := recepie.ResolveRecipes(recipes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "a -> b -> c -> a")
+}
+
+func TestResolveRecipesUnknownParent(t *testing.T) {
+	t.Parallel()
+
+	recipes := []recepie.RecipeInfo{
+		baseRecipe(),
+		{Recipe: recepie.Recipe{Name: "child", Extends: "bsae"}},
+	}
+
+	_, err := recepie.ResolveRecipes(recipes)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown recipe 'bsae'")
+	require.Contains(t, err.Error(), "did you mean 'base'?")
+}
+
+func TestResolveRecipesWithoutExtendsUnchanged(t *testing.T) {
+	t.Parallel()
+
+	recipes, err := recepie.LoadEmbeddedRecipes()
+	require.NoError(t, err)
+
+	resolved, err := recepie.ResolveRecipes(recipes)
+	require.NoError(t, err)
+	require.Equal(t, recipes, resolved)
+}
@@ -0,0 +1,137 @@
+package recepie
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+)
+
+// Recipe layers, from lowest to highest precedence.
+// A recipe in a later layer overrides a recipe with the same name and version
+// in an earlier one; other versions of that recipe remain available.
+const (
+	LayerEmbedded = "embedded"
+	LayerRemote   = "remote"
+	LayerUser     = "user"
+	LayerProject  = "project"
+)
+
+// DefaultProjectDir is the project recipe directory used when --recipe-path is not set.
+const DefaultProjectDir = "recepies"
+
+// LoadOptions configures layered recipe loading.
+type LoadOptions struct {
+	RemoteDirs []string // Fetched recipe source directories (see lib/workspace), in order
+	UserDir    string   // User recipe directory (e.g., ~/.config/pilum/recipes)
+	ProjectDir string   // Project recipe directory (--recipe-path or ./recepies)
+}
+
+// DefaultLoadOptions returns the standard layer locations.
+func DefaultLoadOptions() LoadOptions {
+	return LoadOptions{
+		UserDir:    DefaultUserDir(),
+		ProjectDir: DefaultProjectDir,
+	}
+}
+
+// DefaultUserDir returns the user recipe directory.
+// Honors $XDG_CONFIG_HOME, falling back to ~/.config/pilum/recipes.
+func DefaultUserDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "pilum", "recipes")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "pilum", "recipes")
+}
+
+// LoadLayeredRecipes loads recipes from every layer and resolves `extends:`
+// so each returned recipe is standalone.
+func LoadLayeredRecipes(opts LoadOptions) ([]RecipeInfo, error) {
+	recipes, err := LoadRecipeLayers(opts)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveRecipes(recipes)
+}
+
+// LoadRecipeLayers loads recipes from every layer: embedded, then remote sources,
+// then the user directory, then the project directory. Directories that don't exist are skipped.
+// Recipes are returned as written, without resolving `extends:`.
+func LoadRecipeLayers(opts LoadOptions) ([]RecipeInfo, error) {
+	embedded, err := LoadEmbeddedRecipes()
+	if err != nil {
+		return nil, err
+	}
+
+	layers := [][]RecipeInfo{withSource(embedded, LayerEmbedded)}
+
+	type layerDir struct {
+		layer string
+		path  string
+	}
+	var dirs []layerDir
+	for _, dir := range opts.RemoteDirs {
+		dirs = append(dirs, layerDir{LayerRemote, dir})
+	}
+	dirs = append(dirs, layerDir{LayerUser, opts.UserDir}, layerDir{LayerProject, opts.ProjectDir})
+
+	for _, dir := range dirs {
+		if !isDir(dir.path) {
+			output.Debugf("Skipping %s recipe layer: %s not found", dir.layer, dir.path)
+			continue
+		}
+
+		recipes, err := LoadRecipesFromDirectory(dir.path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load "+dir.layer+" recipes")
+		}
+		layers = append(layers, withSource(recipes, dir.layer))
+	}
+
+	return MergeLayers(layers...), nil
+}
+
+// MergeLayers merges recipe layers in order. A recipe in a later layer replaces
+// any earlier recipe with the same ID (name and version), keeping the earlier
+// recipe's position.
+func MergeLayers(layers ...[]RecipeInfo) []RecipeInfo {
+	var merged []RecipeInfo
+	index := make(map[string]int)
+
+	for _, layer := range layers {
+		for _, info := range layer {
+			id := info.Recipe.ID()
+			if i, exists := index[id]; exists {
+				output.Debugf("Recipe %s from %s layer overrides %s layer", id, info.Source, merged[i].Source)
+				merged[i] = info
+				continue
+			}
+			index[id] = len(merged)
+			merged = append(merged, info)
+		}
+	}
+
+	return merged
+}
+
+// withSource tags each recipe with the layer it was loaded from.
+func withSource(recipes []RecipeInfo, layer string) []RecipeInfo {
+	for i := range recipes {
+		recipes[i].Source = layer
+	}
+	return recipes
+}
+
+// isDir returns true if path exists and is a directory.
+func isDir(path string) bool {
+	if path == "" {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
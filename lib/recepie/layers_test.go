@@ -0,0 +1,200 @@
+package recepie_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/recepie"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeLayersOverridesByName(t *testing.T) {
+	t.Parallel()
+
+	embedded := []recepie.RecipeInfo{
+		{Provider: "gcp", Source: recepie.LayerEmbedded, Recipe: recepie.Recipe{Name: "gcp-cloud-run"}},
+		{Provider: "aws", Source: recepie.LayerEmbedded, Recipe: recepie.Recipe{Name: "aws-lambda"}},
+	}
+	project := []recepie.RecipeInfo{
+		{Provider: "gcp", Source: recepie.LayerProject, Recipe: recepie.Recipe{Name: "gcp-cloud-run"}},
+		{Provider: "k8s", Source: recepie.LayerProject, Recipe: recepie.Recipe{Name: "kubernetes"}},
+	}
+
+	merged := recepie.MergeLayers(embedded, project)
+
+	require.Len(t, merged, 3)
+	require.Equal(t, "gcp-cloud-run", merged[0].Recipe.Name)
+	require.Equal(t, recepie.LayerProject, merged[0].Source)
+	require.Equal(t, "aws-lambda", merged[1].Recipe.Name)
+	require.Equal(t, recepie.LayerEmbedded, merged[1].Source)
+	require.Equal(t, "kubernetes", merged[2].Recipe.Name)
+}
+
+func TestMergeLayersEmpty(t *testing.T) {
+	t.Parallel()
+
+	require.Empty(t, recepie.MergeLayers())
+	require.Empty(t, recepie.MergeLayers(nil, nil))
+}
+
+func TestLoadLayeredRecipes(t *testing.T) {
+	t.Parallel()
+
+	userDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	userRecipe := `
+name: homebrew
+provider: homebrew
+steps:
+  - name: user build
+`
+	userOnly := `
+name: user-only
+provider: custom
+steps:
+  - name: build
+`
+	projectRecipe := `
+name: gcp-cloud-run
+provider: gcp
+service: cloud-run
+steps:
+  - name: project deploy
+`
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "homebrew.yaml"), []byte(userRecipe), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(userDir, "user-only.yaml"), []byte(userOnly), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "cloud-run.yaml"), []byte(projectRecipe), 0644))
+
+	recipes, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{
+		UserDir:    userDir,
+		ProjectDir: projectDir,
+	})
+	require.NoError(t, err)
+
+	byName := make(map[string]recepie.RecipeInfo)
+	for _, r := range recipes {
+		byName[r.Recipe.Name] = r
+	}
+
+	require.Equal(t, recepie.LayerEmbedded, byName["aws-lambda"].Source)
+	require.Equal(t, recepie.LayerUser, byName["homebrew"].Source)
+	require.Equal(t, "user build", byName["homebrew"].Recipe.Steps[0].Name)
+	require.Equal(t, recepie.LayerUser, byName["user-only"].Source)
+	require.Equal(t, recepie.LayerProject, byName["gcp-cloud-run"].Source)
+	require.Equal(t, filepath.Join(projectDir, "cloud-run.yaml"), byName["gcp-cloud-run"].Path)
+}
+
+func TestLoadLayeredRecipesSkipsMissingDirectories(t *testing.T) {
+	t.Parallel()
+
+	missing := filepath.Join(t.TempDir(), "does-not-exist")
+
+	recipes, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{
+		UserDir:    missing,
+		ProjectDir: missing,
+	})
+	require.NoError(t, err)
+
+	embedded, err := recepie.LoadEmbeddedRecipes()
+	require.NoError(t, err)
+	require.Len(t, recipes, len(embedded))
+	for _, r := range recipes {
+		require.Equal(t, recepie.LayerEmbedded, r.Source)
+	}
+}
+
+func TestLoadLayeredRecipesInvalidProjectRecipe(t *testing.T) {
+	t.Parallel()
+
+	projectDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "bad.yaml"), []byte("name: [unclosed"), 0644))
+
+	_, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{ProjectDir: projectDir})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "project")
+}
+
+func TestDefaultUserDirHonorsXDG(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+
+	require.Equal(t, filepath.Join("/tmp/xdg", "pilum", "recipes"), recepie.DefaultUserDir())
+}
+
+func TestLoadLayeredRecipesResolvesExtends(t *testing.T) {
+	t.Parallel()
+
+	projectDir := t.TempDir()
+	child := `
+name: cloud-run-with-migrations
+extends: gcp-cloud-run
+steps:
+  - name: run migrations
+    command: ./migrate.sh
+    execution_mode: service_dir
+    insert_before: deploy to cloud run
+`
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "child.yaml"), []byte(child), 0644))
+
+	raw, err := recepie.LoadRecipeLayers(recepie.LoadOptions{ProjectDir: projectDir})
+	require.NoError(t, err)
+	last := raw[len(raw)-1]
+	require.Equal(t, "gcp-cloud-run", last.Recipe.Extends)
+	require.Len(t, last.Recipe.Steps, 1)
+
+	recipes, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{ProjectDir: projectDir})
+	require.NoError(t, err)
+	resolved := recipes[len(recipes)-1]
+	require.Equal(t, "cloud-run-with-migrations", resolved.Recipe.Name)
+	require.Equal(t, "gcp", resolved.Provider)
+	require.Len(t, resolved.Recipe.Steps, 5)
+	require.Equal(t, "run migrations", resolved.Recipe.Steps[3].Name)
+	require.Equal(t, "deploy to cloud run", resolved.Recipe.Steps[4].Name)
+}
+
+func TestLoadLayeredRecipesRemoteLayer(t *testing.T) {
+	t.Parallel()
+
+	remoteDir := t.TempDir()
+	projectDir := t.TempDir()
+
+	shared := `
+name: shared
+provider: custom
+steps:
+  - name: deploy
+    command: echo remote
+`
+	overridden := `
+name: shared
+provider: custom
+steps:
+  - name: deploy
+    command: echo project
+`
+	require.NoError(t, os.WriteFile(filepath.Join(remoteDir, "shared.yaml"), []byte(shared), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(remoteDir, "homebrew.yaml"), []byte("name: homebrew\nprovider: homebrew\nsteps:\n  - name: remote build\n    command: make\n"), 0644))
+
+	recipes, err := recepie.LoadLayeredRecipes(recepie.LoadOptions{RemoteDirs: []string{remoteDir}})
+	require.NoError(t, err)
+
+	byName := make(map[string]recepie.RecipeInfo)
+	for _, r := range recipes {
+		byName[r.Recipe.Name] = r
+	}
+	require.Equal(t, recepie.LayerRemote, byName["shared"].Source)
+	require.Equal(t, recepie.LayerRemote, byName["homebrew"].Source)
+
+	// Project recipes override remote ones
+	require.NoError(t, os.WriteFile(filepath.Join(projectDir, "shared.yaml"), []byte(overridden), 0644))
+	recipes, err = recepie.LoadLayeredRecipes(recepie.LoadOptions{RemoteDirs: []string{remoteDir}, ProjectDir: projectDir})
+	require.NoError(t, err)
+	for _, r := range recipes {
+		if r.Recipe.Name == "shared" {
+			require.Equal(t, recepie.LayerProject, r.Source)
+			require.Equal(t, "echo project", r.Recipe.Steps[0].Command)
+		}
+	}
+}
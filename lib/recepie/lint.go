@@ -0,0 +1,640 @@
+package recepie
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/artifact"
+	"github.com/sid-technologies/pilum/lib/cache"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/plugin"
+	"github.com/sid-technologies/pilum/lib/semver"
+	"github.com/sid-technologies/pilum/lib/suggest"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity levels for lint diagnostics.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Lint rule identifiers.
+const (
+	RuleSyntax         = "yaml-syntax"
+	RuleUnknownKey     = "unknown-key"
+	RuleDuplicateKey   = "duplicate-key"
+	RuleInvalidType    = "invalid-type"
+	RuleInvalidValue   = "invalid-value"
+	RuleMissingName    = "missing-name"
+	RuleDuplicateStep  = "duplicate-step"
+	RuleDuplicateField = "duplicate-field"
+	RuleMissingCommand = "missing-command"
+	RuleUnusedField    = "unused-field"
+	RuleUnknownHandler = "unknown-handler"
+)
+
+// RuleDescriptions describes every lint rule, keyed by rule ID.
+var RuleDescriptions = map[string]string{
+	RuleSyntax:         "Recipe file is not valid YAML",
+	RuleUnknownKey:     "Key is not part of the recipe schema",
+	RuleDuplicateKey:   "Key is defined more than once in the same mapping",
+	RuleInvalidType:    "Value has the wrong type for its key",
+	RuleInvalidValue:   "Value is not valid for its key",
+	RuleMissingName:    "Recipe or step has no name",
+	RuleDuplicateStep:  "Two steps share the same name",
+	RuleDuplicateField: "A field is declared more than once",
+	RuleMissingCommand: "Step has neither a command nor a registered handler",
+	RuleUnusedField:    "Declared field is never referenced by a step command",
+	RuleUnknownHandler: "Step uses a handler ID that is not registered",
+}
+
+// ValidExecutionModes lists the accepted values for a step's execution_mode.
+var ValidExecutionModes = []string{"root", "service_dir"}
+
+// ValidFieldTypes lists the accepted values for a field's type.
+var ValidFieldTypes = []string{"string", "int", "bool", "list"}
+
+// Diagnostic describes a single problem found in a recipe file.
+type Diagnostic struct {
+	Path     string `json:"path"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Severity string `json:"severity"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// String formats the diagnostic as "path:line:col: severity: message (rule)".
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s (%s)", d.Path, d.Line, d.Column, d.Severity, d.Message, d.Rule)
+}
+
+// LintOptions configures recipe linting.
+type LintOptions struct {
+	// HasHandler reports whether a registered handler exists for a step.
+	// If nil, steps without a command are not checked.
+	HasHandler func(stepName, provider string) bool
+
+	// HandlerIDs lists the handler IDs steps can reference with `uses:`.
+	// If nil, `uses:` references are not checked.
+	HandlerIDs []string
+}
+
+// HasErrors returns true if any diagnostic has error severity.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckHandlers returns an error listing every step in the given (resolved) recipes
+// that uses an unknown handler ID, or has neither a command, a handler ID, a
+// handler plugin, nor a handler registered for its name.
+func CheckHandlers(recipes []RecipeInfo, hasHandler func(stepName, provider string) bool, handlerIDs []string) error {
+	var problems []string
+	for _, info := range recipes {
+		for _, step := range info.Recipe.Steps {
+			if step.Uses != "" {
+				if !slices.Contains(handlerIDs, step.Uses) {
+					problems = append(problems, fmt.Sprintf("recipe '%s' step '%s' uses unknown handler '%s'%s",
+						info.Recipe.Name, step.Name, step.Uses, handlerSuggestion(step.Uses, handlerIDs)))
+				}
+				continue
+			}
+			if step.Command == nil && step.Handler == "" && !hasHandler(step.Name, info.Recipe.Provider) {
+				problems = append(problems, fmt.Sprintf("recipe '%s' step '%s' has no command and no registered handler",
+					info.Recipe.Name, step.Name))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return errors.New("%s", strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+// LintRecipe checks a recipe file against the recipe schema and returns every
+// problem found, sorted by position.
+func LintRecipe(data []byte, path string, opts LintOptions) []Diagnostic {
+	l := &linter{path: path}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		l.add(nil, SeverityError, RuleSyntax, "%s", err.Error())
+		return l.diags
+	}
+	if len(doc.Content) == 0 {
+		l.add(nil, SeverityError, RuleMissingName, "recipe file is empty")
+		return l.diags
+	}
+
+	root := doc.Content[0]
+	l.checkSchema(root, reflect.TypeOf(Recipe{}), "")
+	if HasErrors(l.diags) {
+		// Semantic checks need a decodable recipe
+		return l.sorted()
+	}
+
+	var recipe Recipe
+	if err := root.Decode(&recipe); err != nil {
+		l.add(root, SeverityError, RuleInvalidType, "%s", err.Error())
+		return l.sorted()
+	}
+
+	l.checkRecipe(root, recipe, opts)
+	return l.sorted()
+}
+
+// linter accumulates diagnostics for a single file.
+type linter struct {
+	path  string
+	diags []Diagnostic
+}
+
+func (l *linter) add(node *yaml.Node, severity, rule, msg string, args ...any) {
+	d := Diagnostic{
+		Path:     l.path,
+		Line:     1,
+		Column:   1,
+		Severity: severity,
+		Rule:     rule,
+		Message:  fmt.Sprintf(msg, args...),
+	}
+	if node != nil {
+		d.Line = node.Line
+		d.Column = node.Column
+	}
+	l.diags = append(l.diags, d)
+}
+
+func (l *linter) sorted() []Diagnostic {
+	sort.SliceStable(l.diags, func(i, j int) bool {
+		if l.diags[i].Line != l.diags[j].Line {
+			return l.diags[i].Line < l.diags[j].Line
+		}
+		return l.diags[i].Column < l.diags[j].Column
+	})
+	return l.diags
+}
+
+// checkSchema walks a YAML node against the Go type it decodes into.
+// Known keys come from the yaml struct tags, so the schema always matches
+// what the loader accepts.
+func (l *linter) checkSchema(node *yaml.Node, t reflect.Type, key string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	// command accepts a string or a list of strings
+	if key == "command" {
+		switch node.Kind {
+		case yaml.ScalarNode:
+			return
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					l.add(item, SeverityError, RuleInvalidType, "command arguments must be strings")
+				}
+			}
+			return
+		default:
+			l.add(node, SeverityError, RuleInvalidType, "command must be a string or a list of strings")
+			return
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		l.checkSchema(node, t.Elem(), key)
+	case reflect.Struct:
+		l.checkStruct(node, t, key)
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be a list", describeKey(key))
+			return
+		}
+		for _, item := range node.Content {
+			l.checkSchema(item, t.Elem(), key)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be a mapping", describeKey(key))
+			return
+		}
+		l.checkDuplicateKeys(node)
+		for i := 1; i < len(node.Content); i += 2 {
+			l.checkSchema(node.Content[i], t.Elem(), key)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be a string", describeKey(key))
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be an integer", describeKey(key))
+		}
+	case reflect.Float64:
+		if node.Kind != yaml.ScalarNode || (node.Tag != "!!int" && node.Tag != "!!float") {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be a number", describeKey(key))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			l.add(node, SeverityError, RuleInvalidType, "%s must be true or false", describeKey(key))
+		}
+	default:
+		// any: accept whatever the user wrote
+	}
+}
+
+// checkStruct validates the keys of a mapping node against a struct's yaml tags.
+func (l *linter) checkStruct(node *yaml.Node, t reflect.Type, key string) {
+	if node.Kind != yaml.MappingNode {
+		l.add(node, SeverityError, RuleInvalidType, "%s must be a mapping", describeKey(key))
+		return
+	}
+
+	fields := yamlFields(t)
+	known := make([]string, 0, len(fields))
+	for name := range fields {
+		known = append(known, name)
+	}
+
+	l.checkDuplicateKeys(node)
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		fieldType, exists := fields[keyNode.Value]
+		if !exists {
+			msg := fmt.Sprintf("unknown key '%s'", keyNode.Value)
+			if key != "" {
+				msg = fmt.Sprintf("unknown key '%s' in %s", keyNode.Value, key)
+			}
+			if suggestion := suggest.FormatSuggestion(keyNode.Value, known); suggestion != "" {
+				msg += " - " + suggestion
+			}
+			l.add(keyNode, SeverityError, RuleUnknownKey, "%s", msg)
+			continue
+		}
+		l.checkSchema(valueNode, fieldType, keyNode.Value)
+	}
+}
+
+// checkDuplicateKeys reports keys that appear more than once in a mapping.
+func (l *linter) checkDuplicateKeys(node *yaml.Node) {
+	seen := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if first, exists := seen[keyNode.Value]; exists {
+			l.add(keyNode, SeverityError, RuleDuplicateKey, "key '%s' already defined at line %d", keyNode.Value, first.Line)
+			continue
+		}
+		seen[keyNode.Value] = keyNode
+	}
+}
+
+// checkRecipe runs semantic checks on a recipe that matches the schema.
+func (l *linter) checkRecipe(root *yaml.Node, recipe Recipe, opts LintOptions) {
+	if recipe.Name == "" {
+		l.add(root, SeverityError, RuleMissingName, "recipe has no name")
+	}
+	if problem := InvalidName(recipe.Name); recipe.Name != "" && problem != "" {
+		l.add(valueNodeOr(root, "name"), SeverityError, RuleInvalidValue, "%s", problem)
+	}
+
+	if recipe.Version != "" {
+		if _, err := semver.Parse(recipe.Version); err != nil {
+			l.add(valueNodeOr(root, "version"), SeverityError, RuleInvalidValue,
+				"invalid version '%s' (expected major[.minor[.patch]], e.g. 2 or 2.1.0)", recipe.Version)
+		}
+	}
+	if recipe.RequiresPilum != "" {
+		if _, err := semver.ParseConstraint(recipe.RequiresPilum); err != nil {
+			l.add(valueNodeOr(root, "requires_pilum"), SeverityError, RuleInvalidValue,
+				"invalid requires_pilum '%s' (expected a constraint, e.g. >=0.4)", recipe.RequiresPilum)
+		}
+	}
+	if _, constraint := ParseReference(recipe.Extends); constraint != "" {
+		if _, err := semver.ParseConstraint(constraint); err != nil {
+			l.add(valueNodeOr(root, "extends"), SeverityError, RuleInvalidValue,
+				"invalid version constraint '%s' in extends", constraint)
+		}
+	}
+
+	stepNodes := sequenceItems(mappingValue(root, "steps"))
+	seenSteps := make(map[string]int)
+	hasHandlerSteps := false
+
+	for i, step := range recipe.Steps {
+		node := root
+		if i < len(stepNodes) {
+			node = stepNodes[i]
+		}
+
+		if step.Remove != "" {
+			continue
+		}
+
+		if step.Name == "" && step.Replace == "" {
+			l.add(node, SeverityError, RuleMissingName, "step %d has no name", i+1)
+		}
+
+		if step.Name != "" {
+			lower := strings.ToLower(step.Name)
+			if first, exists := seenSteps[lower]; exists {
+				l.add(node, SeverityError, RuleDuplicateStep, "step '%s' duplicates step %d", step.Name, first+1)
+			} else {
+				seenSteps[lower] = i
+			}
+		}
+
+		if step.ExecutionMode != "" && !slices.Contains(ValidExecutionModes, step.ExecutionMode) {
+			l.add(valueNodeOr(node, "execution_mode"), SeverityError, RuleInvalidValue,
+				"invalid execution_mode '%s' (expected one of: %s)",
+				step.ExecutionMode, strings.Join(ValidExecutionModes, ", "))
+		}
+
+		if step.Uses != "" {
+			l.checkUses(node, step, opts.HandlerIDs)
+		}
+		if step.Handler != "" {
+			l.checkHandler(node, step)
+		}
+		l.checkCacheGlobs(node, step)
+		l.checkArtifacts(node, step)
+
+		if step.Command == nil {
+			hasHandlerSteps = true
+			name := step.Name
+			if name == "" {
+				name = step.Replace
+			}
+			if step.Uses == "" && step.Handler == "" && opts.HasHandler != nil && !opts.HasHandler(name, recipe.Provider) {
+				l.add(node, SeverityError, RuleMissingCommand,
+					"step '%s' has no command and no registered handler", name)
+			}
+		}
+	}
+
+	l.checkFields(root, recipe, hasHandlerSteps)
+}
+
+// checkUses validates a step's handler ID reference.
+func (l *linter) checkUses(node *yaml.Node, step RecipeStep, handlerIDs []string) {
+	if step.Command != nil {
+		l.add(valueNodeOr(node, "uses"), SeverityError, RuleInvalidValue,
+			"step '%s' has both a command and uses", step.Name)
+	}
+	if step.Handler != "" {
+		l.add(valueNodeOr(node, "uses"), SeverityError, RuleInvalidValue,
+			"step '%s' has both uses and a handler", step.Name)
+	}
+	if handlerIDs != nil && !slices.Contains(handlerIDs, step.Uses) {
+		l.add(valueNodeOr(node, "uses"), SeverityError, RuleUnknownHandler,
+			"unknown handler '%s'%s", step.Uses, handlerSuggestion(step.Uses, handlerIDs))
+	}
+}
+
+// handlerSuggestion suggests a registered handler ID for an unknown one.
+func handlerSuggestion(id string, handlerIDs []string) string {
+	if suggestion := suggest.FormatSuggestion(id, handlerIDs); suggestion != "" {
+		return " - " + suggestion
+	}
+	if len(handlerIDs) == 0 {
+		return ""
+	}
+	return " (available: " + strings.Join(handlerIDs, ", ") + ")"
+}
+
+// checkHandler validates a step's handler plugin reference.
+func (l *linter) checkHandler(node *yaml.Node, step RecipeStep) {
+	name, ok := plugin.ParseHandler(step.Handler)
+	if !ok || name == "" {
+		l.add(valueNodeOr(node, "handler"), SeverityError, RuleInvalidValue,
+			"invalid handler '%s' (expected %s<executable>, e.g. %spilum-handler-k8s)",
+			step.Handler, plugin.HandlerPrefix, plugin.HandlerPrefix)
+	}
+	if step.Command != nil {
+		l.add(valueNodeOr(node, "handler"), SeverityError, RuleInvalidValue,
+			"step '%s' has both a command and a handler", step.Name)
+	}
+	if step.HandlerTimeout < 0 {
+		l.add(valueNodeOr(node, "handler_timeout"), SeverityError, RuleInvalidValue,
+			"handler_timeout must not be negative")
+	}
+}
+
+// checkCacheGlobs validates a step's inputs and outputs.
+func (l *linter) checkCacheGlobs(node *yaml.Node, step RecipeStep) {
+	for _, input := range step.Inputs {
+		if key, ok := strings.CutPrefix(input, cache.ConfigInputPrefix); ok {
+			if strings.TrimSpace(key) == "" {
+				l.add(valueNodeOr(node, "inputs"), SeverityError, RuleInvalidValue,
+					"input '%s' has no config key", input)
+			}
+			continue
+		}
+		if err := cache.ValidateGlob(input); err != nil {
+			l.add(valueNodeOr(node, "inputs"), SeverityError, RuleInvalidValue, "invalid input: %s", err.Error())
+		}
+	}
+	for _, out := range step.Outputs {
+		if err := cache.ValidateOutputGlob(out); err != nil {
+			l.add(valueNodeOr(node, "outputs"), SeverityError, RuleInvalidValue, "invalid output: %s", err.Error())
+		}
+	}
+	if len(step.Outputs) > 0 && len(step.Inputs) == 0 {
+		l.add(valueNodeOr(node, "outputs"), SeverityWarning, RuleInvalidValue,
+			"step '%s' has outputs but no inputs, so it is never cached", step.Name)
+	}
+}
+
+// checkArtifacts validates a step's artifact declarations.
+func (l *linter) checkArtifacts(node *yaml.Node, step RecipeStep) {
+	nodes := sequenceItems(mappingValue(node, "artifacts"))
+	for i, spec := range step.Artifacts {
+		at := valueNodeOr(node, "artifacts")
+		if i < len(nodes) {
+			at = nodes[i]
+		}
+
+		if !slices.Contains(artifact.Kinds, spec.Kind) {
+			l.add(at, SeverityError, RuleInvalidValue,
+				"artifact %d has invalid kind '%s' (expected one of: %s)", i+1, spec.Kind, strings.Join(artifact.Kinds, ", "))
+		}
+		if (spec.Path == "") == (spec.Image == "") {
+			l.add(at, SeverityError, RuleInvalidValue, "artifact %d needs exactly one of path or image", i+1)
+			continue
+		}
+		if spec.Path != "" {
+			if err := cache.ValidateGlob(spec.Path); err != nil {
+				l.add(at, SeverityError, RuleInvalidValue, "invalid artifact path: %s", err.Error())
+			}
+		}
+	}
+}
+
+// checkFields validates field declarations. Unused fields are only reported when
+// every step has an explicit command, since handlers may read any field.
+func (l *linter) checkFields(root *yaml.Node, recipe Recipe, hasHandlerSteps bool) {
+	references := commandReferences(recipe.Steps)
+	seen := make(map[string]bool)
+
+	for _, section := range []string{"required_fields", "optional_fields"} {
+		var fields []Field
+		if section == "required_fields" {
+			fields = recipe.RequiredFields
+		} else {
+			fields = recipe.OptionalFields
+		}
+
+		nodes := sequenceItems(mappingValue(root, section))
+		for i, field := range fields {
+			node := root
+			if i < len(nodes) {
+				node = nodes[i]
+			}
+
+			if field.Name == "" {
+				l.add(node, SeverityError, RuleMissingName, "%s entry %d has no name", section, i+1)
+				continue
+			}
+
+			if seen[field.Name] {
+				l.add(node, SeverityError, RuleDuplicateField, "field '%s' is declared more than once", field.Name)
+			}
+			seen[field.Name] = true
+
+			if field.Type != "" && !slices.Contains(ValidFieldTypes, field.Type) {
+				l.add(valueNodeOr(node, "type"), SeverityError, RuleInvalidValue,
+					"field '%s' has invalid type '%s' (expected one of: %s)",
+					field.Name, field.Type, strings.Join(ValidFieldTypes, ", "))
+			}
+
+			if _, ok := field.DefaultValue(); field.Default != "" && !ok {
+				l.add(valueNodeOr(node, "default"), SeverityError, RuleInvalidValue,
+					"field '%s' has default '%s' that is not a valid %s", field.Name, field.Default, field.Type)
+			}
+
+			if field.Pattern != "" {
+				if _, err := regexp.Compile(field.Pattern); err != nil {
+					l.add(valueNodeOr(node, "pattern"), SeverityError, RuleInvalidValue,
+						"field '%s' has invalid pattern: %v", field.Name, err)
+				}
+			}
+
+			if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+				l.add(valueNodeOr(node, "min"), SeverityError, RuleInvalidValue,
+					"field '%s' has min %s greater than max %s",
+					field.Name, formatNumber(*field.Min), formatNumber(*field.Max))
+			}
+
+			if field.RequiredIf != "" && section == "required_fields" {
+				l.add(valueNodeOr(node, "required_if"), SeverityWarning, RuleInvalidValue,
+					"field '%s' is already required; required_if has no effect", field.Name)
+			}
+
+			if !hasHandlerSteps && len(recipe.Steps) > 0 && !references[field.Name] {
+				l.add(node, SeverityWarning, RuleUnusedField,
+					"field '%s' is declared but never referenced by a step command", field.Name)
+			}
+		}
+	}
+}
+
+// placeholderPattern matches ${name} references in commands.
+var placeholderPattern = regexp.MustCompile(`\$\{\s*([A-Za-z0-9_.\-]+)`)
+
+// commandReferences returns the set of ${...} names used by step commands.
+// Step env_vars are passed to the command as written, so they reference nothing.
+func commandReferences(steps []RecipeStep) map[string]bool {
+	refs := make(map[string]bool)
+	collect := func(s string) {
+		s = strings.ReplaceAll(s, "$${", "") // escaped, not a reference
+		for _, m := range placeholderPattern.FindAllStringSubmatch(s, -1) {
+			refs[m[1]] = true
+		}
+	}
+
+	for _, step := range steps {
+		switch cmd := step.Command.(type) {
+		case string:
+			collect(cmd)
+		case []any:
+			for _, arg := range cmd {
+				if s, ok := arg.(string); ok {
+					collect(s)
+				}
+			}
+		case []string:
+			for _, arg := range cmd {
+				collect(arg)
+			}
+		}
+	}
+
+	return refs
+}
+
+// yamlFields maps yaml tag names to field types for a struct.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fields[tag] = f.Type
+	}
+	return fields
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// valueNodeOr returns the value node for key, falling back to node itself.
+func valueNodeOr(node *yaml.Node, key string) *yaml.Node {
+	if v := mappingValue(node, key); v != nil {
+		return v
+	}
+	return node
+}
+
+// sequenceItems returns the items of a sequence node, or nil.
+func sequenceItems(node *yaml.Node) []*yaml.Node {
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	return node.Content
+}
+
+// describeKey returns a readable name for a key in error messages.
+func describeKey(key string) string {
+	if key == "" {
+		return "recipe"
+	}
+	return "'" + key + "'"
+}
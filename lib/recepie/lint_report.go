@@ -0,0 +1,160 @@
+package recepie
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Lint report formats.
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+)
+
+// FormatDiagnostics renders diagnostics in the given format (text, json or sarif).
+// toolVersion is recorded in SARIF output.
+func FormatDiagnostics(diags []Diagnostic, format, toolVersion string) (string, error) {
+	switch format {
+	case FormatText, "":
+		return formatText(diags), nil
+	case FormatJSON:
+		if diags == nil {
+			diags = []Diagnostic{}
+		}
+		data, err := json.MarshalIndent(diags, "", "  ")
+		if err != nil {
+			return "", errors.Wrap(err, "failed to encode diagnostics")
+		}
+		return string(data) + "\n", nil
+	case FormatSARIF:
+		data, err := json.MarshalIndent(buildSARIF(diags, toolVersion), "", "  ")
+		if err != nil {
+			return "", errors.Wrap(err, "failed to encode SARIF report")
+		}
+		return string(data) + "\n", nil
+	default:
+		return "", errors.New("unknown format '%s' (expected text, json or sarif)", format)
+	}
+}
+
+func formatText(diags []Diagnostic) string {
+	var sb strings.Builder
+	errorCount, warningCount := 0, 0
+	for _, d := range diags {
+		sb.WriteString(d.String())
+		sb.WriteString("\n")
+		if d.Severity == SeverityError {
+			errorCount++
+		} else {
+			warningCount++
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%d error(s), %d warning(s)\n", errorCount, warningCount))
+	return sb.String()
+}
+
+// SARIF 2.1.0 types, limited to what pilum reports.
+// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+}
+
+func buildSARIF(diags []Diagnostic, toolVersion string) sarifLog {
+	ruleIDs := make([]string, 0, len(RuleDescriptions))
+	for id := range RuleDescriptions {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for _, id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: RuleDescriptions[id]}})
+	}
+
+	results := make([]sarifResult, 0, len(diags))
+	for _, d := range diags {
+		results = append(results, sarifResult{
+			RuleID:  d.Rule,
+			Level:   d.Severity,
+			Message: sarifMessage{Text: d.Message},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: d.Path},
+					Region:           sarifRegion{StartLine: d.Line, StartColumn: d.Column},
+				},
+			}},
+		})
+	}
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "pilum",
+				Version:        toolVersion,
+				InformationURI: "https://github.com/sid-technologies/pilum",
+				Rules:          rules,
+			}},
+			Results: results,
+		}},
+	}
+}
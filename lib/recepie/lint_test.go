@@ -0,0 +1,515 @@
+package recepie_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/recepie"
+
+	"github.com/stretchr/testify/require"
+)
+
+func rulesOf(diags []recepie.Diagnostic) []string {
+	rules := make([]string, 0, len(diags))
+	for _, d := range diags {
+		rules = append(rules, d.Rule)
+	}
+	return rules
+}
+
+func TestLintRecipeValid(t *testing.T) {
+	t.Parallel()
+
+	recipe := `
+name: valid
+provider: custom
+required_fields:
+  - name: bucket
+    type: string
+steps:
+  - name: sync
+    command: aws s3 sync ./dist s3://${bucket}
+    execution_mode: service_dir
+    timeout: 60
+    tags: [deploy]
+`
+	diags := recepie.LintRecipe([]byte(recipe), "valid.yaml", recepie.LintOptions{})
+	require.Empty(t, diags)
+}
+
+func TestLintRecipeUnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: typos
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+    executon_mode: root
+    default_retries: 2
+`
+	diags := recepie.LintRecipe([]byte(recipe), "typos.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 2)
+	require.Equal(t, recepie.RuleUnknownKey, diags[0].Rule)
+	require.Equal(t, 6, diags[0].Line)
+	require.Equal(t, 5, diags[0].Column)
+	require.Contains(t, diags[0].Message, "executon_mode")
+	require.Contains(t, diags[0].Message, "did you mean 'execution_mode'?")
+	require.Equal(t, 7, diags[1].Line)
+	require.Contains(t, diags[1].Message, "default_retries")
+	require.True(t, recepie.HasErrors(diags))
+}
+
+func TestLintRecipeInvalidExecutionMode(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: bad-mode
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+    execution_mode: servicedir
+`
+	diags := recepie.LintRecipe([]byte(recipe), "bad.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleInvalidValue, diags[0].Rule)
+	require.Equal(t, 6, diags[0].Line)
+	require.Contains(t, diags[0].Message, "servicedir")
+}
+
+func TestLintRecipeInvalidTypes(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: types
+provider: custom
+required_fields:
+  - name: replicas
+    type: integer
+steps:
+  - name: deploy
+    command: {run: deploy}
+    timeout: soon
+    debug: maybe
+    tags: deploy
+    env_vars: [A, B]
+`
+	diags := recepie.LintRecipe([]byte(recipe), "types.yaml", recepie.LintOptions{})
+
+	require.Equal(t, []string{
+		recepie.RuleInvalidType,
+		recepie.RuleInvalidType,
+		recepie.RuleInvalidType,
+		recepie.RuleInvalidType,
+		recepie.RuleInvalidType,
+	}, rulesOf(diags))
+	require.Contains(t, diags[0].Message, "command")
+	require.Contains(t, diags[1].Message, "'timeout' must be an integer")
+	require.Contains(t, diags[2].Message, "'debug' must be true or false")
+	require.Contains(t, diags[3].Message, "'tags' must be a list")
+	require.Contains(t, diags[4].Message, "'env_vars' must be a mapping")
+}
+
+func TestLintRecipeInvalidFieldType(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: field-type
+provider: custom
+required_fields:
+  - name: replicas
+    type: integer
+steps:
+  - name: deploy
+    command: echo ${replicas}
+`
+	diags := recepie.LintRecipe([]byte(recipe), "f.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleInvalidValue, diags[0].Rule)
+	require.Equal(t, 5, diags[0].Line)
+	require.Contains(t, diags[0].Message, "invalid type 'integer'")
+}
+
+func TestLintRecipeDuplicates(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: dupes
+provider: custom
+name: dupes-again
+required_fields:
+  - name: region
+  - name: region
+steps:
+  - name: build
+    command: make
+  - name: Build
+    command: make
+`
+	diags := recepie.LintRecipe([]byte(recipe), "dupes.yaml", recepie.LintOptions{})
+
+	require.Contains(t, rulesOf(diags), recepie.RuleDuplicateKey)
+	require.Equal(t, 3, diags[0].Line)
+}
+
+func TestLintRecipeDuplicateStepsAndFields(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: dupes
+provider: custom
+required_fields:
+  - name: region
+  - name: region
+steps:
+  - name: build
+    command: make ${region}
+  - name: Build
+    command: make
+`
+	diags := recepie.LintRecipe([]byte(recipe), "dupes.yaml", recepie.LintOptions{})
+
+	require.Equal(t, []string{recepie.RuleDuplicateField, recepie.RuleDuplicateStep}, rulesOf(diags))
+	require.Equal(t, 5, diags[0].Line)
+	require.Equal(t, 9, diags[1].Line)
+}
+
+func TestLintRecipeMissingCommand(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: handlers
+provider: gcp
+steps:
+  - name: build binary
+  - name: bild binary
+`
+	hasHandler := func(stepName, _ string) bool { return stepName == "build binary" }
+
+	diags := recepie.LintRecipe([]byte(recipe), "h.yaml", recepie.LintOptions{HasHandler: hasHandler})
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleMissingCommand, diags[0].Rule)
+	require.Equal(t, 5, diags[0].Line)
+
+	// Without a handler lookup the check is skipped
+	require.Empty(t, recepie.LintRecipe([]byte(recipe), "h.yaml", recepie.LintOptions{}))
+}
+
+func TestLintRecipeHandlerPlugins(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: plugins
+provider: k8s
+steps:
+  - name: apply
+    handler: exec:pilum-handler-k8s
+    with:
+      replicas: 3
+      manifests: [k8s/]
+    handler_timeout: 10
+  - name: typo
+    handler: pilum-handler-k8s
+  - name: both
+    command: kubectl apply
+    handler: exec:pilum-handler-k8s
+`
+	noHandlers := func(string, string) bool { return false }
+	diags := recepie.LintRecipe([]byte(recipe), "plugins.yaml", recepie.LintOptions{HasHandler: noHandlers})
+
+	require.Equal(t, []string{recepie.RuleInvalidValue, recepie.RuleInvalidValue}, rulesOf(diags))
+	require.Equal(t, 11, diags[0].Line)
+	require.Contains(t, diags[0].Message, "invalid handler 'pilum-handler-k8s'")
+	require.Equal(t, 14, diags[1].Line)
+	require.Contains(t, diags[1].Message, "both a command and a handler")
+}
+
+func TestLintRecipeUses(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: uses
+provider: gcp
+steps:
+  - name: docker build
+    uses: docker/build
+    with:
+      dockerfile: Dockerfile.prod
+  - name: push
+    uses: docker/psuh
+  - name: deploy
+    uses: gcp/cloud-run-deploy
+    command: gcloud run deploy
+`
+	opts := recepie.LintOptions{
+		HasHandler: func(string, string) bool { return false },
+		HandlerIDs: []string{"docker/build", "docker/push", "gcp/cloud-run-deploy"},
+	}
+	diags := recepie.LintRecipe([]byte(recipe), "uses.yaml", opts)
+
+	require.Equal(t, []string{recepie.RuleUnknownHandler, recepie.RuleInvalidValue}, rulesOf(diags))
+	require.Equal(t, 9, diags[0].Line)
+	require.Contains(t, diags[0].Message, "unknown handler 'docker/psuh' - did you mean 'docker/push'?")
+	require.Equal(t, 11, diags[1].Line)
+	require.Contains(t, diags[1].Message, "both a command and uses")
+}
+
+func TestLintRecipeCacheGlobs(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: cached
+provider: custom
+steps:
+  - name: build
+    command: go build -o dist/${name}
+    inputs: ["**/*.go", go.mod, "config:build.language"]
+    outputs: ["dist/${name}"]
+  - name: bad
+    command: make
+    inputs: ["src/[a-z", "config:"]
+    outputs: ["../dist"]
+  - name: uncached
+    command: make
+    outputs: [dist]
+`
+	diags := recepie.LintRecipe([]byte(recipe), "cached.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 4)
+	require.Contains(t, diags[0].Message, "unterminated character class")
+	require.Contains(t, diags[1].Message, "input 'config:' has no config key")
+	require.Contains(t, diags[2].Message, "output '../dist' must be inside the working directory")
+	require.Equal(t, recepie.SeverityWarning, diags[3].Severity)
+	require.Contains(t, diags[3].Message, "never cached")
+}
+
+func TestLintRecipeArtifacts(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: artifacts
+provider: custom
+steps:
+  - name: build
+    command: make
+    artifacts:
+      - kind: archive
+        path: dist/*.tar.gz
+      - kind: image
+        image: "${name}:${tag}"
+      - kind: tarball
+        path: dist/app
+      - kind: file
+      - kind: file
+        path: "dist/[a-z"
+`
+	diags := recepie.LintRecipe([]byte(recipe), "a.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 3)
+	require.Contains(t, diags[0].Message, "invalid kind 'tarball'")
+	require.Equal(t, 11, diags[0].Line)
+	require.Contains(t, diags[1].Message, "artifact 4 needs exactly one of path or image")
+	require.Contains(t, diags[2].Message, "unterminated character class")
+}
+
+func TestLintRecipeUnusedFields(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: unused
+provider: custom
+required_fields:
+  - name: bucket
+  - name: cluster
+steps:
+  - name: sync
+    command: ["aws", "s3", "sync", ".", "s3://${bucket}"]
+    env_vars:
+      CLUSTER: ${cluster}
+`
+	diags := recepie.LintRecipe([]byte(recipe), "u.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleUnusedField, diags[0].Rule)
+	require.Equal(t, recepie.SeverityWarning, diags[0].Severity)
+	require.Contains(t, diags[0].Message, "cluster", "env_vars aren't interpolated, so they don't reference fields")
+	require.False(t, recepie.HasErrors(diags))
+}
+
+func TestLintRecipeSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	diags := recepie.LintRecipe([]byte("name: [unclosed"), "s.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleSyntax, diags[0].Rule)
+}
+
+func TestLintEmbeddedRecipes(t *testing.T) {
+	t.Parallel()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "recepies", "*.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		diags := recepie.LintRecipe(data, file, recepie.LintOptions{})
+		require.Empty(t, diags, "%s: %v", file, diags)
+	}
+}
+
+func TestLoadRecipesRejectsSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	recipe := `name: typo
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+    execution_mode: nowhere
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "typo.yaml"), []byte(recipe), 0644))
+
+	_, err := recepie.LoadRecipesFromDirectory(tmpDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "typo.yaml:6:21")
+}
+
+func TestCheckHandlers(t *testing.T) {
+	t.Parallel()
+
+	recipes := []recepie.RecipeInfo{{Recipe: recepie.Recipe{
+		Name:     "r",
+		Provider: "gcp",
+		Steps: []recepie.RecipeStep{
+			{Name: "known"},
+			{Name: "explicit", Command: "echo hi"},
+			{Name: "plugin", Handler: "exec:pilum-handler-k8s"},
+			{Name: "renamed", Uses: "docker/build"},
+			{Name: "typo", Uses: "docker/biuld"},
+			{Name: "unknown"},
+		},
+	}}}
+	hasHandler := func(stepName, _ string) bool { return stepName == "known" }
+
+	err := recepie.CheckHandlers(recipes, hasHandler, []string{"docker/build", "docker/push"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "step 'unknown'")
+	require.Contains(t, err.Error(), "step 'typo' uses unknown handler 'docker/biuld' - did you mean 'docker/build'?")
+	require.NotContains(t, err.Error(), "renamed")
+	require.NotContains(t, err.Error(), "explicit")
+	require.NotContains(t, err.Error(), "plugin")
+}
+
+func TestFormatDiagnostics(t *testing.T) {
+	t.Parallel()
+
+	diags := []recepie.Diagnostic{{
+		Path:     "r.yaml",
+		Line:     3,
+		Column:   5,
+		Severity: recepie.SeverityError,
+		Rule:     recepie.RuleUnknownKey,
+		Message:  "unknown key 'x'",
+	}}
+
+	text, err := recepie.FormatDiagnostics(diags, recepie.FormatText, "v1.0.0")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(text, "r.yaml:3:5: error: unknown key 'x' (unknown-key)\n"))
+	require.Contains(t, text, "1 error(s), 0 warning(s)")
+
+	jsonOut, err := recepie.FormatDiagnostics(diags, recepie.FormatJSON, "v1.0.0")
+	require.NoError(t, err)
+	var decoded []recepie.Diagnostic
+	require.NoError(t, json.Unmarshal([]byte(jsonOut), &decoded))
+	require.Equal(t, diags, decoded)
+
+	emptyJSON, err := recepie.FormatDiagnostics(nil, recepie.FormatJSON, "v1.0.0")
+	require.NoError(t, err)
+	require.Equal(t, "[]\n", emptyJSON)
+
+	sarif, err := recepie.FormatDiagnostics(diags, recepie.FormatSARIF, "v1.0.0")
+	require.NoError(t, err)
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name    string `json:"name"`
+					Version string `json:"version"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine   int `json:"startLine"`
+							StartColumn int `json:"startColumn"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(sarif), &log))
+	require.Equal(t, "2.1.0", log.Version)
+	require.Equal(t, "pilum", log.Runs[0].Tool.Driver.Name)
+	require.Equal(t, "v1.0.0", log.Runs[0].Tool.Driver.Version)
+	result := log.Runs[0].Results[0]
+	require.Equal(t, recepie.RuleUnknownKey, result.RuleID)
+	require.Equal(t, "error", result.Level)
+	require.Equal(t, "r.yaml", result.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	require.Equal(t, 3, result.Locations[0].PhysicalLocation.Region.StartLine)
+	require.Equal(t, 5, result.Locations[0].PhysicalLocation.Region.StartColumn)
+
+	_, err = recepie.FormatDiagnostics(diags, "xml", "v1.0.0")
+	require.Error(t, err)
+}
+
+func TestLintRecipeFieldConstraints(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: constraints
+provider: custom
+optional_fields:
+  - name: memory
+    type: string
+    pattern: "[0-9+(Mi"
+  - name: replicas
+    type: int
+    min: 10
+    max: 1
+  - name: region
+    required_if: memory
+    enum: [us, eu]
+`
+	diags := recepie.LintRecipe([]byte(recipe), "c.yaml", recepie.LintOptions{})
+
+	require.Equal(t, []string{recepie.RuleInvalidValue, recepie.RuleInvalidValue}, rulesOf(diags))
+	require.Equal(t, 6, diags[0].Line)
+	require.Contains(t, diags[0].Message, "field 'memory' has invalid pattern")
+	require.Equal(t, 9, diags[1].Line)
+	require.Contains(t, diags[1].Message, "min 10 greater than max 1")
+}
+
+func TestLintRecipeInvalidDefault(t *testing.T) {
+	t.Parallel()
+
+	recipe := `name: defaults
+provider: custom
+optional_fields:
+  - name: replicas
+    type: int
+    default: many
+`
+	diags := recepie.LintRecipe([]byte(recipe), "d.yaml", recepie.LintOptions{})
+
+	require.Len(t, diags, 1)
+	require.Equal(t, recepie.RuleInvalidValue, diags[0].Rule)
+	require.Equal(t, 6, diags[0].Line)
+	require.Contains(t, diags[0].Message, "default 'many' that is not a valid int")
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/output"
@@ -18,6 +19,8 @@ type RecipeInfo struct {
 	Provider string
 	Service  string
 	Recipe   Recipe
+	Source   string // Layer the recipe was loaded from (embedded, user, project)
+	Path     string // File the recipe was loaded from
 }
 
 // LoadRecipesFromDirectory loads all recipe YAML files from the specified directory
@@ -51,23 +54,9 @@ func LoadRecipesFromDirectory(dirPath string) ([]RecipeInfo, error) {
 			return nil, errors.Wrap(err, "failed to read file %s", filePath)
 		}
 
-		// To ensure order is preserved, first unmarshal into a map
-		// YAML doesn't guarantee order but we'll enforce it in our transformation
-		var rawData map[string]any
-		if err := yaml.Unmarshal(data, &rawData); err != nil {
-			return nil, errors.Wrap(err, "failed to parse YAML from %s", filePath)
-		}
-
-		// Now marshal back to YAML with consistent ordering
-		orderedYAML, err := yaml.Marshal(rawData)
+		recipe, err := parseRecipe(data, filePath)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to reorder YAML from %s", filePath)
-		}
-
-		// Finally unmarshal to Recipe struct
-		var recipe Recipe
-		if err := yaml.Unmarshal(orderedYAML, &recipe); err != nil {
-			return nil, errors.Wrap(err, "failed to parse ordered YAML from %s", filePath)
+			return nil, err
 		}
 
 		// Create RecipeInfo with provider and service info
@@ -75,6 +64,7 @@ func LoadRecipesFromDirectory(dirPath string) ([]RecipeInfo, error) {
 			Provider: recipe.Provider,
 			Service:  recipe.Service,
 			Recipe:   recipe,
+			Path:     filePath,
 		}
 
 		output.Debugf("Loaded recipe: %s from file: %s", recipe.Name, filePath)
@@ -124,25 +114,16 @@ func loadRecipesFromFS(fsys fs.FS, root string) ([]RecipeInfo, error) {
 			return nil, errors.Wrap(err, "failed to read embedded file %s", filePath)
 		}
 
-		var rawData map[string]any
-		if err := yaml.Unmarshal(data, &rawData); err != nil {
-			return nil, errors.Wrap(err, "failed to parse YAML from %s", filePath)
-		}
-
-		orderedYAML, err := yaml.Marshal(rawData)
+		recipe, err := parseRecipe(data, filePath)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to reorder YAML from %s", filePath)
-		}
-
-		var recipe Recipe
-		if err := yaml.Unmarshal(orderedYAML, &recipe); err != nil {
-			return nil, errors.Wrap(err, "failed to parse ordered YAML from %s", filePath)
+			return nil, err
 		}
 
 		recipeInfo := RecipeInfo{
 			Provider: recipe.Provider,
 			Service:  recipe.Service,
 			Recipe:   recipe,
+			Path:     filePath,
 		}
 
 		output.Debugf("Loaded embedded recipe: %s", recipe.Name)
@@ -155,3 +136,42 @@ func loadRecipesFromFS(fsys fs.FS, root string) ([]RecipeInfo, error) {
 
 	return recipeInfos, nil
 }
+
+// parseRecipe decodes a single recipe file, rejecting files that fail the
+// schema checks in LintRecipe. Warnings are logged at debug level.
+// requires_pilum is checked first, so a recipe written for a newer pilum
+// reports the version it needs rather than the keys this version doesn't know.
+func parseRecipe(data []byte, filePath string) (Recipe, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Recipe{}, errors.Wrap(err, "failed to parse YAML from %s", filePath)
+	}
+
+	var header struct {
+		RequiresPilum string `yaml:"requires_pilum"`
+	}
+	_ = doc.Decode(&header) // Malformed documents are reported by LintRecipe
+	if err := checkRequiresPilum(header.RequiresPilum, PilumVersion, filePath); err != nil {
+		return Recipe{}, err
+	}
+
+	diags := LintRecipe(data, filePath, LintOptions{})
+	var problems []string
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			problems = append(problems, d.String())
+		} else {
+			output.Debugf("%s", d.String())
+		}
+	}
+	if len(problems) > 0 {
+		return Recipe{}, errors.New("invalid recipe %s:\n  %s", filePath, strings.Join(problems, "\n  "))
+	}
+
+	var recipe Recipe
+	if err := doc.Decode(&recipe); err != nil {
+		return Recipe{}, errors.Wrap(err, "failed to decode recipe from %s", filePath)
+	}
+
+	return recipe, nil
+}
@@ -1,10 +1,7 @@
 package recepie
 
 import (
-	"reflect"
-
-	"github.com/sid-technologies/pilum/lib/configutil"
-	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/artifact"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 )
 
@@ -15,6 +12,13 @@ type Field struct {
 	Description string `yaml:"description"`
 	Type        string `yaml:"type"`    // string, int, bool, list
 	Default     string `yaml:"default"` // default value if not provided
+
+	// Constraints checked by ValidateService when the field is set.
+	Enum       []string `yaml:"enum,omitempty"`        // allowed values
+	Pattern    string   `yaml:"pattern,omitempty"`     // regular expression the whole value must match
+	Min        *float64 `yaml:"min,omitempty"`         // minimum for numeric values
+	Max        *float64 `yaml:"max,omitempty"`         // maximum for numeric values
+	RequiredIf string   `yaml:"required_if,omitempty"` // "key=value" or "key"; optional fields only
 }
 
 // RequiredField is an alias for Field for backwards compatibility.
@@ -23,6 +27,9 @@ type RequiredField = Field
 // Recipe defines a deployment workflow.
 type Recipe struct {
 	Name           string       `yaml:"name"`
+	Version        string       `yaml:"version,omitempty"`        // Recipe version, e.g. "2" or "2.1.0" (default: 1)
+	RequiresPilum  string       `yaml:"requires_pilum,omitempty"` // Pilum version constraint, e.g. ">=0.4"
+	Extends        string       `yaml:"extends,omitempty"`        // Parent recipe, optionally pinned ("base@2"); see ResolveRecipes
 	Description    string       `yaml:"description"`
 	Provider       string       `yaml:"provider"`
 	Service        string       `yaml:"service"`
@@ -42,18 +49,43 @@ type RecipeStep struct {
 	Debug         bool              `yaml:"debug,omitempty"`
 	Retries       int               `yaml:"retries,omitempty"`
 	Tags          []string          `yaml:"tags,omitempty"` // Tags for filtering (e.g., "deploy", "build")
+
+	// Uses references a built-in handler by ID (e.g. "docker/build"); Handler
+	// names an external plugin instead (e.g. "exec:pilum-handler-k8s").
+	// With holds parameters passed to either. Steps with none of command,
+	// uses or handler fall back to a handler registered for the step name.
+	Uses           string         `yaml:"uses,omitempty"`
+	Handler        string         `yaml:"handler,omitempty"`
+	With           map[string]any `yaml:"with,omitempty"`
+	HandlerTimeout int            `yaml:"handler_timeout,omitempty"` // Seconds to wait for the plugin
+
+	// Inputs and Outputs make a step cacheable: it is skipped when its inputs,
+	// command and env are unchanged and its outputs still exist. Inputs are
+	// globs relative to the service directory or "config:<key>"; outputs are
+	// globs relative to the step's working directory.
+	Inputs  []string `yaml:"inputs,omitempty"`
+	Outputs []string `yaml:"outputs,omitempty"`
+
+	// Artifacts are recorded in dist/artifacts.json after the step succeeds,
+	// along with any its handler declares.
+	Artifacts []artifact.Spec `yaml:"artifacts,omitempty"`
+
+	// Step patch operations, only meaningful in a recipe that extends another.
+	// Each names a step in the parent recipe.
+	InsertBefore string `yaml:"insert_before,omitempty"`
+	InsertAfter  string `yaml:"insert_after,omitempty"`
+	Replace      string `yaml:"replace,omitempty"`
+	Remove       string `yaml:"remove,omitempty"`
 }
 
-// ValidateService checks if a service has all required fields for this recipe.
+// ValidateService checks a service against this recipe's field declarations.
+// All violations are collected and returned together as a *ValidationError.
 func (r *Recipe) ValidateService(svc *serviceinfo.ServiceInfo) error {
-	for _, field := range r.RequiredFields {
-		value := getServiceField(svc, field.Name)
-		if value == "" && field.Default == "" {
-			return errors.New("recipe '%s' requires field '%s': %s",
-				r.Name, field.Name, field.Description)
-		}
+	violations := r.validateFields(svc)
+	if len(violations) == 0 {
+		return nil
 	}
-	return nil
+	return &ValidationError{Recipe: r.Name, Violations: violations}
 }
 
 // GetRequiredFields returns the list of required fields with descriptions.
@@ -66,56 +98,13 @@ func (r *Recipe) GetOptionalFields() []Field {
 	return r.OptionalFields
 }
 
-// getServiceField extracts a field value from ServiceInfo by name.
-// Supports nested field names like "homebrew.tap_url".
+// getServiceField extracts a string field value from ServiceInfo by name.
+// Supports nested field names like "homebrew.tap_url". Non-string values return "".
 func getServiceField(svc *serviceinfo.ServiceInfo, fieldName string) string {
-	// Map common field names to ServiceInfo struct fields
-	fieldMap := map[string]func(*serviceinfo.ServiceInfo) string{
-		"name":          func(s *serviceinfo.ServiceInfo) string { return s.Name },
-		"description":   func(s *serviceinfo.ServiceInfo) string { return s.Description },
-		"project":       func(s *serviceinfo.ServiceInfo) string { return s.Project },
-		"license":       func(s *serviceinfo.ServiceInfo) string { return s.License },
-		"region":        func(s *serviceinfo.ServiceInfo) string { return s.Region },
-		"provider":      func(s *serviceinfo.ServiceInfo) string { return s.Provider },
-		"template":      func(s *serviceinfo.ServiceInfo) string { return s.Template },
-		"registry_name": func(s *serviceinfo.ServiceInfo) string { return s.RegistryName },
-		"homebrew.project_url": func(s *serviceinfo.ServiceInfo) string {
-			return configutil.GetNestedString(s.Config, "homebrew", "project_url")
-		},
-		"homebrew.tap_url": func(s *serviceinfo.ServiceInfo) string {
-			return configutil.GetNestedString(s.Config, "homebrew", "tap_url")
-		},
-		"homebrew.token_env": func(s *serviceinfo.ServiceInfo) string {
-			return configutil.GetNestedString(s.Config, "homebrew", "token_env")
-		},
+	value, found := lookupServiceValue(svc, fieldName)
+	if !found {
+		return ""
 	}
-
-	if getter, exists := fieldMap[fieldName]; exists {
-		return getter(svc)
-	}
-
-	// Try to get from the raw config map
-	if svc.Config != nil {
-		if val, exists := svc.Config[fieldName]; exists {
-			if str, ok := val.(string); ok {
-				return str
-			}
-		}
-	}
-
-	// Try reflection as fallback for any other fields
-	v := reflect.ValueOf(svc).Elem()
-	t := v.Type()
-	for i := range t.NumField() {
-		field := t.Field(i)
-		yamlTag := field.Tag.Get("yaml")
-		if yamlTag == fieldName || field.Name == fieldName {
-			fieldVal := v.Field(i)
-			if fieldVal.Kind() == reflect.String {
-				return fieldVal.String()
-			}
-		}
-	}
-
-	return ""
+	str, _ := value.(string)
+	return str
 }
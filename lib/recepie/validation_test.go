@@ -399,3 +399,183 @@ func TestRecipeStruct(t *testing.T) {
 	require.Len(t, recipe.RequiredFields, 1)
 	require.Len(t, recipe.Steps, 2)
 }
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestValidateServiceTypedFields(t *testing.T) {
+	t.Parallel()
+
+	recipe := Recipe{
+		Name: "typed",
+		OptionalFields: []Field{
+			{Name: "cloud_run.max_instances", Type: "int", Min: floatPtr(1), Max: floatPtr(100)},
+			{Name: "cloud_run.cpu_throttling", Type: "bool"},
+			{Name: "cloud_run.memory", Type: "string", Pattern: "[0-9]+(Mi|Gi)"},
+			{Name: "cloud_run.ingress", Type: "string", Enum: []string{"all", "internal"}},
+			{Name: "tags", Type: "list"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		config   map[string]any
+		messages []string
+	}{
+		{
+			name: "valid values",
+			config: map[string]any{
+				"cloud_run": map[string]any{
+					"max_instances":  10,
+					"cpu_throttling": true,
+					"memory":         "512Mi",
+					"ingress":        "internal",
+				},
+				"tags": []any{"a", "b"},
+			},
+		},
+		{
+			name:   "integral float accepted as int",
+			config: map[string]any{"cloud_run": map[any]any{"max_instances": 3.0}},
+		},
+		{
+			name: "wrong types",
+			config: map[string]any{
+				"cloud_run": map[string]any{
+					"max_instances":  "ten",
+					"cpu_throttling": "yes",
+				},
+				"tags": "a",
+			},
+			messages: []string{
+				`expects field 'cloud_run.max_instances' to be an int, got string "ten"`,
+				`expects field 'cloud_run.cpu_throttling' to be a bool, got string "yes"`,
+				`expects field 'tags' to be a list, got string "a"`,
+			},
+		},
+		{
+			name:     "fractional int",
+			config:   map[string]any{"cloud_run": map[string]any{"max_instances": 2.5}},
+			messages: []string{"to be an int, got number 2.5"},
+		},
+		{
+			name: "constraint violations",
+			config: map[string]any{
+				"cloud_run": map[string]any{
+					"max_instances": 500,
+					"memory":        "512MB",
+					"ingress":       "internl",
+				},
+			},
+			messages: []string{
+				"expects field 'cloud_run.max_instances' to be at most 100 (got 500)",
+				"expects field 'cloud_run.memory' to match pattern '[0-9]+(Mi|Gi)' (got '512MB')",
+				"expects field 'cloud_run.ingress' to be one of: all, internal (got 'internl') - did you mean 'internal'?",
+			},
+		},
+		{
+			name:     "below min",
+			config:   map[string]any{"cloud_run": map[string]any{"max_instances": 0}},
+			messages: []string{"to be at least 1 (got 0)"},
+		},
+		{
+			name:     "unknown key in declared section",
+			config:   map[string]any{"cloud_run": map[string]any{"max_instance": 5}},
+			messages: []string{"has no field 'cloud_run.max_instance' - did you mean 'max_instances'?"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := recipe.ValidateService(&serviceinfo.ServiceInfo{Name: "svc", Config: tt.config})
+			if len(tt.messages) == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Len(t, validationErr.Violations, len(tt.messages))
+			for i, msg := range tt.messages {
+				require.Contains(t, validationErr.Violations[i].Message, msg)
+			}
+		})
+	}
+}
+
+func TestValidateServiceReportsAllViolations(t *testing.T) {
+	t.Parallel()
+
+	recipe := Recipe{
+		Name: "multi",
+		RequiredFields: []Field{
+			{Name: "project", Description: "project ID"},
+			{Name: "region", Description: "region"},
+		},
+	}
+
+	err := recipe.ValidateService(&serviceinfo.ServiceInfo{Name: "svc"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "recipe 'multi' found 2 problems:")
+	require.Contains(t, err.Error(), "  - requires field 'project': project ID")
+	require.Contains(t, err.Error(), "  - requires field 'region': region")
+}
+
+func TestValidateServiceRequiredIf(t *testing.T) {
+	t.Parallel()
+
+	recipe := Recipe{
+		Name: "conditional",
+		OptionalFields: []Field{
+			{Name: "cloud_run.ingress", Type: "string"},
+			{Name: "cloud_run.vpc_connector", Description: "VPC connector", RequiredIf: "cloud_run.ingress=internal"},
+			{Name: "cloud_run.cpu"},
+			{Name: "cloud_run.memory", RequiredIf: "cloud_run.cpu"},
+		},
+	}
+
+	svc := func(config map[string]any) *serviceinfo.ServiceInfo {
+		return &serviceinfo.ServiceInfo{Name: "svc", Config: map[string]any{"cloud_run": config}}
+	}
+
+	require.NoError(t, recipe.ValidateService(svc(map[string]any{"ingress": "all"})))
+
+	err := recipe.ValidateService(svc(map[string]any{"ingress": "internal"}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires field 'cloud_run.vpc_connector' when cloud_run.ingress=internal")
+
+	require.NoError(t, recipe.ValidateService(svc(map[string]any{"ingress": "internal", "vpc_connector": "vpc"})))
+
+	err = recipe.ValidateService(svc(map[string]any{"cpu": 2}))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires field 'cloud_run.memory' when cloud_run.cpu")
+}
+
+func TestLookupServiceValueKeepsTypes(t *testing.T) {
+	t.Parallel()
+
+	svc := &serviceinfo.ServiceInfo{
+		Name: "svc",
+		Config: map[string]any{
+			"lambda": map[any]any{"memory": 256},
+			"empty":  "",
+		},
+	}
+
+	value, found := lookupServiceValue(svc, "lambda.memory")
+	require.True(t, found)
+	require.Equal(t, 256, value)
+
+	value, found = lookupServiceValue(svc, "name")
+	require.True(t, found)
+	require.Equal(t, "svc", value)
+
+	_, found = lookupServiceValue(svc, "empty")
+	require.False(t, found)
+
+	_, found = lookupServiceValue(svc, "lambda.missing")
+	require.False(t, found)
+}
@@ -0,0 +1,164 @@
+package recepie
+
+import (
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/semver"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// DefaultVersion is the version of a recipe that doesn't declare one.
+const DefaultVersion = "1"
+
+// PilumVersion is the version of the running binary, checked against each
+// recipe's requires_pilum when it is loaded. Development builds ("dev" or
+// empty) satisfy every requirement.
+var PilumVersion = ""
+
+// VersionString returns the version as written, or DefaultVersion.
+func (r *Recipe) VersionString() string {
+	if r.Version == "" {
+		return DefaultVersion
+	}
+	return r.Version
+}
+
+// SemVersion returns the parsed recipe version. Versions that don't parse
+// (rejected at load time) are treated as DefaultVersion.
+func (r *Recipe) SemVersion() semver.Version {
+	v, err := semver.Parse(r.VersionString())
+	if err != nil {
+		return semver.MustParse(DefaultVersion)
+	}
+	return v
+}
+
+// ID identifies a recipe across layers: "name@major.minor.patch".
+// Recipes with the same ID override each other; different versions coexist.
+func (r *Recipe) ID() string {
+	return r.Name + "@" + r.SemVersion().String()
+}
+
+// InvalidName returns why name can't be a recipe name, or "" if it can.
+// Names are split from versions at '@', and name recipe files and fixture
+// directories, so they must be a single plain path component.
+func InvalidName(name string) string {
+	switch {
+	case name == "":
+		return "recipe name is empty"
+	case strings.Contains(name, "@"):
+		return "recipe name '" + name + "' must not contain '@'"
+	case !filepath.IsLocal(name) || strings.ContainsAny(name, `/\`) || name == "." || name == "..":
+		return "recipe name '" + name + "' must be a single path component (no '/' or '\\', not '.' or '..')"
+	}
+	return ""
+}
+
+// ParseReference splits a recipe reference such as "gcp-cloud-run@2" into
+// the recipe name and version constraint. The constraint is empty if unpinned.
+func ParseReference(ref string) (name, constraint string) {
+	name, constraint, _ = strings.Cut(strings.TrimSpace(ref), "@")
+	return strings.TrimSpace(name), strings.TrimSpace(constraint)
+}
+
+// Select returns the highest version of a recipe matching a reference such as
+// "gcp-cloud-run", "gcp-cloud-run@2" or "gcp-cloud-run@>=1.2".
+func Select(recipes []RecipeInfo, ref string) (RecipeInfo, error) {
+	name, constraintStr := ParseReference(ref)
+	constraint, err := semver.ParseConstraint(constraintStr)
+	if err != nil {
+		return RecipeInfo{}, errors.Wrap(err, "invalid recipe reference '"+ref+"'")
+	}
+
+	var best *RecipeInfo
+	var available []*Recipe
+	var names []string
+	for i := range recipes {
+		recipe := &recipes[i].Recipe
+		if !slices.Contains(names, recipe.Name) {
+			names = append(names, recipe.Name)
+		}
+		if recipe.Name != name {
+			continue
+		}
+		available = append(available, recipe)
+		if !constraint.Check(recipe.SemVersion()) {
+			continue
+		}
+		if best == nil || recipe.SemVersion().Compare(best.Recipe.SemVersion()) >= 0 {
+			best = &recipes[i]
+		}
+	}
+
+	if best != nil {
+		return *best, nil
+	}
+	if len(available) > 0 {
+		sort.Slice(available, func(i, j int) bool {
+			return available[i].SemVersion().Compare(available[j].SemVersion()) < 0
+		})
+		versions := make([]string, 0, len(available))
+		for _, recipe := range available {
+			versions = append(versions, recipe.VersionString())
+		}
+		return RecipeInfo{}, errors.New("recipe '%s' has no version matching '%s' (available: %s)",
+			name, constraint, strings.Join(versions, ", "))
+	}
+	if suggestion := suggest.FormatSuggestion(name, names); suggestion != "" {
+		return RecipeInfo{}, errors.New("recipe '%s' not found - %s", name, suggestion)
+	}
+	return RecipeInfo{}, errors.New("recipe '%s' not found", name)
+}
+
+// Index maps each key to the highest-versioned recipe with that key.
+// Between recipes with equal versions, the later one wins.
+func Index(recipes []RecipeInfo, key func(RecipeInfo) string) map[string]RecipeInfo {
+	index := make(map[string]RecipeInfo, len(recipes))
+	for _, info := range recipes {
+		k := key(info)
+		if existing, exists := index[k]; exists && existing.Recipe.SemVersion().Compare(info.Recipe.SemVersion()) > 0 {
+			continue
+		}
+		index[k] = info
+	}
+	return index
+}
+
+// ForService returns the recipe a service uses: the recipe it pins
+// ("recipe: gcp-cloud-run@2"), or else the highest version for its provider.
+func ForService(recipes []RecipeInfo, svc serviceinfo.ServiceInfo) (RecipeInfo, bool) {
+	if svc.Recipe != "" {
+		info, err := Select(recipes, svc.Recipe)
+		return info, err == nil
+	}
+	info, ok := Index(recipes, func(info RecipeInfo) string { return info.Provider })[svc.Provider]
+	return info, ok
+}
+
+// checkRequiresPilum returns an error if pilum version current doesn't satisfy requirement.
+func checkRequiresPilum(requirement, current, filePath string) error {
+	if requirement == "" || current == "" || current == "dev" {
+		return nil
+	}
+
+	constraint, err := semver.ParseConstraint(requirement)
+	if err != nil {
+		return errors.Wrap(err, "invalid requires_pilum in "+filePath)
+	}
+	running, err := semver.Parse(current)
+	if err != nil {
+		// Unrecognized build versions can't be compared; don't block loading
+		return nil //nolint:nilerr // non-release builds skip the check
+	}
+
+	if !constraint.Check(running) {
+		return errors.New("recipe %s requires pilum %s but this is pilum %s - upgrade pilum to use it",
+			filePath, constraint, current)
+	}
+	return nil
+}
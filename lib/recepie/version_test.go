@@ -0,0 +1,259 @@
+package recepie
+
+import (
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func versioned(name, version, source string) RecipeInfo {
+	return RecipeInfo{
+		Provider: "gcp",
+		Source:   source,
+		Recipe:   Recipe{Name: name, Version: version, Provider: "gcp"},
+	}
+}
+
+func TestRecipeVersionDefaults(t *testing.T) {
+	t.Parallel()
+
+	unversioned := Recipe{Name: "gcp-cloud-run"}
+	require.Equal(t, "1", unversioned.VersionString())
+	require.Equal(t, "gcp-cloud-run@1.0.0", unversioned.ID())
+
+	pinned := Recipe{Name: "gcp-cloud-run", Version: "2.1"}
+	require.Equal(t, "2.1", pinned.VersionString())
+	require.Equal(t, "gcp-cloud-run@2.1.0", pinned.ID())
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	recipes := []RecipeInfo{
+		versioned("gcp-cloud-run", "", LayerEmbedded),
+		versioned("gcp-cloud-run", "2.0", LayerRemote),
+		versioned("gcp-cloud-run", "2.3.1", LayerRemote),
+		versioned("gcp-cloud-run", "10", LayerRemote),
+	}
+
+	tests := []struct {
+		ref      string
+		expected string
+	}{
+		{"gcp-cloud-run", "10"},
+		{"gcp-cloud-run@2", "2.3.1"},
+		{"gcp-cloud-run@2.0", "2.0"},
+		{"gcp-cloud-run@1", "1"},
+		{"gcp-cloud-run@<10", "2.3.1"},
+	}
+	for _, tt := range tests {
+		info, err := Select(recipes, tt.ref)
+		require.NoError(t, err, tt.ref)
+		require.Equal(t, tt.expected, info.Recipe.VersionString(), tt.ref)
+	}
+
+	_, err := Select(recipes, "gcp-cloud-run@3")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no version matching '3' (available: 1, 2.0, 2.3.1, 10)")
+
+	_, err = Select(recipes, "gcp-cloud-rn@2")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "did you mean 'gcp-cloud-run'?")
+
+	_, err = Select(recipes, "gcp-cloud-run@>>2")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid recipe reference")
+}
+
+func TestIndexPrefersHighestVersion(t *testing.T) {
+	t.Parallel()
+
+	recipes := []RecipeInfo{
+		versioned("gcp-cloud-run", "2", LayerRemote),
+		versioned("gcp-cloud-run", "", LayerEmbedded),
+	}
+
+	index := Index(recipes, func(info RecipeInfo) string { return info.Provider })
+	require.Equal(t, "2", index["gcp"].Recipe.Version)
+}
+
+func TestForService(t *testing.T) {
+	t.Parallel()
+
+	recipes := []RecipeInfo{
+		versioned("gcp-cloud-run", "1", LayerProject),
+		versioned("gcp-cloud-run", "2", LayerRemote),
+	}
+
+	info, ok := ForService(recipes, serviceinfo.ServiceInfo{Provider: "gcp"})
+	require.True(t, ok)
+	require.Equal(t, "2", info.Recipe.Version)
+
+	info, ok = ForService(recipes, serviceinfo.ServiceInfo{Provider: "gcp", Recipe: "gcp-cloud-run@1"})
+	require.True(t, ok)
+	require.Equal(t, LayerProject, info.Source)
+
+	_, ok = ForService(recipes, serviceinfo.ServiceInfo{Provider: "aws"})
+	require.False(t, ok)
+}
+
+func TestMergeLayersKeepsVersions(t *testing.T) {
+	t.Parallel()
+
+	embedded := []RecipeInfo{versioned("gcp-cloud-run", "1", LayerEmbedded)}
+	remote := []RecipeInfo{versioned("gcp-cloud-run", "2", LayerRemote)}
+	// An unversioned project recipe is version 1 and overrides the embedded one
+	project := []RecipeInfo{versioned("gcp-cloud-run", "", LayerProject)}
+
+	merged := MergeLayers(embedded, remote, project)
+
+	require.Len(t, merged, 2)
+	require.Equal(t, LayerProject, merged[0].Source)
+	require.Equal(t, "2", merged[1].Recipe.Version)
+}
+
+func TestResolveRecipesExtendsPinnedVersion(t *testing.T) {
+	t.Parallel()
+
+	v1 := versioned("base", "1", LayerEmbedded)
+	v1.Recipe.Steps = []RecipeStep{{Name: "old build"}}
+	v2 := versioned("base", "2", LayerEmbedded)
+	v2.Recipe.Steps = []RecipeStep{{Name: "new build"}}
+
+	latest := versioned("child", "", LayerProject)
+	latest.Recipe.Extends = "base"
+	pinned := versioned("legacy", "", LayerProject)
+	pinned.Recipe.Extends = "base@1"
+
+	resolved, err := ResolveRecipes([]RecipeInfo{v1, v2, latest, pinned})
+	require.NoError(t, err)
+	require.Equal(t, "new build", resolved[2].Recipe.Steps[0].Name)
+	require.Equal(t, "old build", resolved[3].Recipe.Steps[0].Name)
+	require.Empty(t, resolved[3].Recipe.Version)
+
+	// A new version extending its own previous version is not a cycle
+	v3 := versioned("base", "3", LayerProject)
+	v3.Recipe.Extends = "base@2"
+	v3.Recipe.Steps = []RecipeStep{{Name: "push", InsertAfter: "new build"}}
+	resolved, err = ResolveRecipes([]RecipeInfo{v1, v2, v3})
+	require.NoError(t, err)
+	require.Len(t, resolved[2].Recipe.Steps, 2)
+
+	unpinned := versioned("base", "3", LayerProject)
+	unpinned.Recipe.Extends = "base"
+	_, err = ResolveRecipes([]RecipeInfo{v1, unpinned})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "base@3 -> base@3")
+
+	missing := versioned("legacy", "", LayerProject)
+	missing.Recipe.Extends = "base@5"
+	_, err = ResolveRecipes([]RecipeInfo{v1, v2, missing})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no version matching '5'")
+}
+
+func TestCheckRequiresPilum(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, checkRequiresPilum("", "v0.3.0", "r.yaml"))
+	require.NoError(t, checkRequiresPilum(">=0.4", "dev", "r.yaml"))
+	require.NoError(t, checkRequiresPilum(">=0.4", "v0.4.2", "r.yaml"))
+	require.NoError(t, checkRequiresPilum(">=0.4", "v0.4.0-3-gabcdef", "r.yaml"))
+
+	err := checkRequiresPilum(">=0.4", "v0.3.1", "r.yaml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires pilum >=0.4 but this is pilum v0.3.1")
+}
+
+// Not parallel: sets PilumVersion. Parallel tests only start once sequential ones finish.
+func TestParseRecipeRequiresPilumBeforeSchema(t *testing.T) {
+	previous := PilumVersion
+	PilumVersion = "v0.3.0"
+	t.Cleanup(func() { PilumVersion = previous })
+
+	// Written for a newer pilum: the unknown key must not mask the version requirement
+	recipe := `
+name: future
+requires_pilum: ">=0.4"
+provider: custom
+outputs: [url]
+steps:
+  - name: deploy
+    command: echo deploy
+`
+	_, err := parseRecipe([]byte(recipe), "future.yaml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "requires pilum >=0.4 but this is pilum v0.3.0")
+
+	PilumVersion = "v0.4.0"
+	_, err = parseRecipe([]byte(recipe), "future.yaml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unknown key 'outputs'")
+}
+
+func TestInvalidName(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"gcp-cloud-run", "my.recipe", "k8s_v2"} {
+		require.Empty(t, InvalidName(name), name)
+	}
+
+	tests := map[string]string{
+		"":           "recipe name is empty",
+		"gcp@2":      "must not contain '@'",
+		"../../x":    "single path component",
+		"team/cloud": "single path component",
+		`team\cloud`: "single path component",
+		".":          "single path component",
+		"..":         "single path component",
+		"/etc/x":     "single path component",
+	}
+	for name, expected := range tests {
+		require.Contains(t, InvalidName(name), expected, name)
+	}
+
+	diags := LintRecipe([]byte("name: ../escape\nprovider: custom\nsteps:\n  - name: deploy\n    command: echo\n"), "escape.yaml", LintOptions{})
+	require.Len(t, diags, 1)
+	require.Equal(t, RuleInvalidValue, diags[0].Rule)
+	require.Contains(t, diags[0].Message, "single path component")
+}
+
+func TestLintRecipeVersions(t *testing.T) {
+	t.Parallel()
+
+	recipe := `
+name: broken@2
+version: two
+requires_pilum: ">>0.4"
+extends: base@x
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+`
+	diags := LintRecipe([]byte(recipe), "broken.yaml", LintOptions{})
+
+	var messages []string
+	for _, d := range diags {
+		require.Equal(t, RuleInvalidValue, d.Rule)
+		messages = append(messages, d.Message)
+	}
+	require.Len(t, messages, 4)
+	require.Contains(t, messages[0], "must not contain '@'")
+	require.Contains(t, messages[1], "invalid version 'two'")
+	require.Contains(t, messages[2], "invalid requires_pilum '>>0.4'")
+	require.Contains(t, messages[3], "invalid version constraint 'x' in extends")
+
+	valid := `
+name: good
+version: 2.1
+requires_pilum: ">=0.4, <1"
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+`
+	require.Empty(t, LintRecipe([]byte(valid), "good.yaml", LintOptions{}))
+}
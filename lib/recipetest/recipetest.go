@@ -0,0 +1,167 @@
+// Package recipetest checks the commands a recipe generates against golden files.
+//
+// A fixtures directory holds one subdirectory per fixture, each with a pilum.yaml.
+// The recipe is planned for each fixture with a fixed tag and no access to git
+// or the environment, and the resulting steps (command, cwd and env) are compared
+// with the golden.yaml next to the fixture's pilum.yaml.
+package recipetest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/orchestrator"
+	"github.com/sid-technologies/pilum/lib/recepie"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GoldenFileName is the expected plan stored next to each fixture's pilum.yaml.
+const GoldenFileName = "golden.yaml"
+
+// Tag is the image tag fixtures are planned with.
+const Tag = "test"
+
+// Fixture results.
+const (
+	StatusPass    = "pass"
+	StatusFail    = "fail"
+	StatusUpdated = "updated"
+)
+
+// Options configures Run.
+type Options struct {
+	Update bool // Write golden files instead of comparing against them
+}
+
+// Result is the outcome for a single fixture.
+type Result struct {
+	Fixture string // Fixture directory, relative to the fixtures directory
+	Status  string
+	Diff    string // Golden vs generated plan, when they differ
+	Err     error  // Set if the fixture couldn't be planned or has no golden file
+}
+
+// golden is the contents of a golden file.
+type golden struct {
+	Recipe string                     `yaml:"recipe"`
+	Tag    string                     `yaml:"tag"`
+	Steps  []orchestrator.PlannedStep `yaml:"steps"`
+}
+
+// Run plans recipe for every fixture under dir and compares the result with,
+// or with Update writes, each fixture's golden file.
+func Run(recipe recepie.RecipeInfo, dir string, opts Options) ([]Result, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, errors.New("fixtures directory '%s' does not exist", dir)
+	}
+
+	services, err := serviceinfo.FindServicesWithOptions(dir, serviceinfo.DiscoveryOptions{MaxDepth: -1, NoGitIgnore: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load fixtures from "+dir)
+	}
+	services, problems := serviceinfo.ResolveServices(services)
+	if len(problems) > 0 {
+		return nil, errors.Wrap(problems, "failed to load fixtures from "+dir)
+	}
+	if len(services) == 0 {
+		return nil, errors.New("no fixtures found in %s (expected <fixture>/pilum.yaml)", dir)
+	}
+
+	// Every pilum.yaml is a fixture; multi-region services expand within one
+	fixtures := make(map[string][]serviceinfo.ServiceInfo)
+	for _, svc := range services {
+		fixtures[svc.Path] = append(fixtures[svc.Path], svc)
+	}
+	names := make([]string, 0, len(fixtures))
+	for name := range fixtures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		results = append(results, runFixture(recipe, dir, name, fixtures[name], opts))
+	}
+	return results, nil
+}
+
+// runFixture plans a single fixture and checks it against its golden file.
+func runFixture(recipe recepie.RecipeInfo, dir, name string, services []serviceinfo.ServiceInfo, opts Options) Result {
+	result := Result{Fixture: name, Status: StatusFail}
+
+	actual, err := Render(recipe, services)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	path := filepath.Join(dir, name, GoldenFileName)
+	if opts.Update {
+		if err := os.WriteFile(path, actual, 0o644); err != nil { //nolint:gosec // golden files are committed alongside fixtures
+			result.Err = errors.Wrap(err, "failed to write "+path)
+			return result
+		}
+		result.Status = StatusUpdated
+		return result
+	}
+
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		result.Err = errors.New("missing %s - run with --update to create it", path)
+		return result
+	}
+	if err != nil {
+		result.Err = errors.Wrap(err, "failed to read "+path)
+		return result
+	}
+
+	if !bytes.Equal(expected, actual) {
+		result.Diff = Diff(string(expected), string(actual))
+		return result
+	}
+
+	result.Status = StatusPass
+	return result
+}
+
+// Render plans recipe for the fixture's services and returns the golden file contents.
+// Services use the recipe regardless of their own provider or recipe settings.
+func Render(recipe recepie.RecipeInfo, services []serviceinfo.ServiceInfo) ([]byte, error) {
+	ref := recipe.Recipe.Name + "@" + recipe.Recipe.SemVersion().String()
+
+	pinned := make([]serviceinfo.ServiceInfo, 0, len(services))
+	for _, svc := range services {
+		svc.Recipe = ref
+		if svc.Provider == "" {
+			svc.Provider = recipe.Recipe.Provider
+		}
+		if err := recipe.Recipe.ValidateService(&svc); err != nil {
+			return nil, err
+		}
+		pinned = append(pinned, svc)
+	}
+
+	runner := orchestrator.NewRunner(pinned, []recepie.RecipeInfo{recipe}, orchestrator.RunnerOptions{
+		Tag:      Tag,
+		DryRun:   true,
+		Hermetic: true,
+	})
+	plan, err := runner.Plan()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by 'pilum recipe test --update'. Do not edit.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(golden{Recipe: ref, Tag: Tag, Steps: plan}); err != nil {
+		return nil, errors.Wrap(err, "failed to encode plan")
+	}
+	return buf.Bytes(), nil
+}
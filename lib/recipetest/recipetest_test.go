@@ -0,0 +1,139 @@
+package recipetest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/recepie"
+	"github.com/sid-technologies/pilum/lib/recipetest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestEmbeddedRecipeFixtures guards the built-in recipes and ingredients: if this
+// fails after an intended change, run 'go run . recipe test <recipe> --update'.
+func TestEmbeddedRecipeFixtures(t *testing.T) {
+	t.Parallel()
+
+	recipes, err := recepie.LoadEmbeddedRecipes()
+	require.NoError(t, err)
+
+	for _, info := range recipes {
+		dir := filepath.Join("..", "..", "recepies", "testdata", info.Recipe.Name)
+		results, err := recipetest.Run(info, dir, recipetest.Options{})
+		require.NoError(t, err, info.Recipe.Name)
+
+		for _, r := range results {
+			require.NoError(t, r.Err, "%s/%s", info.Recipe.Name, r.Fixture)
+			require.Equal(t, recipetest.StatusPass, r.Status, "%s/%s differs from golden:\n%s", info.Recipe.Name, r.Fixture, r.Diff)
+		}
+	}
+}
+
+func writeFixture(t *testing.T, dir, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, name), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name, "pilum.yaml"), []byte(content), 0o600))
+}
+
+func TestRunUpdateThenCompare(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFixture(t, dir, "basic", "name: api\nregion: us-east-1\n")
+	writeFixture(t, dir, "eu", "name: api\nregion: eu-west-1\n")
+
+	info := recepie.RecipeInfo{
+		Provider: "custom",
+		Recipe: recepie.Recipe{
+			Name:           "custom",
+			Provider:       "custom",
+			RequiredFields: []recepie.Field{{Name: "region", Type: "string"}},
+			Steps: []recepie.RecipeStep{{
+				Name:          "deploy",
+				Command:       []any{"deploy", "${name}:${tag}", "--region", "${region}", "--commit", "${git.short_sha}"},
+				ExecutionMode: "service_dir",
+				EnvVars:       map[string]string{"MODE": "release"},
+			}},
+		},
+	}
+
+	// Without golden files every fixture fails
+	results, err := recipetest.Run(info, dir, recipetest.Options{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	require.Error(t, results[0].Err)
+	require.Contains(t, results[0].Err.Error(), "--update")
+
+	results, err = recipetest.Run(info, dir, recipetest.Options{Update: true})
+	require.NoError(t, err)
+	for _, r := range results {
+		require.Equal(t, recipetest.StatusUpdated, r.Status)
+	}
+
+	golden, err := os.ReadFile(filepath.Join(dir, "eu", recipetest.GoldenFileName))
+	require.NoError(t, err)
+	require.Equal(t, `# Generated by 'pilum recipe test --update'. Do not edit.
+recipe: custom@1.0.0
+tag: test
+steps:
+  - service: api
+    step: deploy
+    command:
+      - deploy
+      - api:test
+      - --region
+      - eu-west-1
+      - --commit
+      - "0000000"
+    cwd: eu
+    env:
+      MODE: release
+`, string(golden))
+
+	results, err = recipetest.Run(info, dir, recipetest.Options{})
+	require.NoError(t, err)
+	for _, r := range results {
+		require.Equal(t, recipetest.StatusPass, r.Status, r.Fixture)
+	}
+
+	// A change in the generated command is reported as a diff
+	info.Recipe.Steps[0].Command = []any{"deploy", "${name}:${tag}", "--region", "${region}"}
+	results, err = recipetest.Run(info, dir, recipetest.Options{})
+	require.NoError(t, err)
+	require.Equal(t, recipetest.StatusFail, results[0].Status)
+	require.Contains(t, results[0].Diff, `-       - --commit`)
+}
+
+func TestRunInvalidFixture(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFixture(t, dir, "missing-region", "name: api\n")
+
+	info := recepie.RecipeInfo{Recipe: recepie.Recipe{
+		Name:           "custom",
+		Provider:       "custom",
+		RequiredFields: []recepie.Field{{Name: "region", Type: "string"}},
+		Steps:          []recepie.RecipeStep{{Name: "deploy", Command: "deploy ${region}"}},
+	}}
+
+	results, err := recipetest.Run(info, dir, recipetest.Options{Update: true})
+	require.NoError(t, err)
+	require.Error(t, results[0].Err)
+	require.Contains(t, results[0].Err.Error(), "region")
+
+	_, err = recipetest.Run(info, filepath.Join(dir, "nope"), recipetest.Options{})
+	require.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	expected := "a\nb\nc\nd\ne\nf\ng\n"
+	actual := "a\nb\nc\nD\ne\nf\ng\n"
+
+	require.Equal(t, "  b\n  c\n- d\n+ D\n  e\n  f\n", recipetest.Diff(expected, actual))
+	require.Empty(t, recipetest.Diff(expected, expected))
+}
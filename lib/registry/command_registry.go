@@ -2,8 +2,10 @@ package registry
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
+	"github.com/sid-technologies/pilum/lib/artifact"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 )
 
@@ -14,32 +16,91 @@ type StepContext struct {
 	Tag          string
 	Registry     string
 	TemplatePath string
+	Params       Params          // The step's with: values, interpolated
+	Artifacts    *artifact.Specs // Artifacts the step produces; nil when they aren't recorded
 }
 
 // StepHandler generates a command for a specific step type.
 // Returns nil if no command should be executed.
 type StepHandler func(ctx StepContext) any
 
-// CommandRegistry maps step patterns to handlers.
+// CommandRegistry maps handler IDs and step names to handlers.
 type CommandRegistry struct {
-	handlers map[string]StepHandler
+	handlers map[string]StepHandler // step name[:provider] -> handler
+	ids      map[string]StepHandler // handler ID -> handler
+	aliases  map[string]string      // step name[:provider] -> handler ID
 }
 
 // NewCommandRegistry creates a new command registry.
 func NewCommandRegistry() *CommandRegistry {
 	return &CommandRegistry{
 		handlers: make(map[string]StepHandler),
+		ids:      make(map[string]StepHandler),
+		aliases:  make(map[string]string),
 	}
 }
 
 // Register adds a step handler to the registry.
-// pattern is matched against step names (case-insensitive, supports partial match).
+// pattern is matched exactly against step names (case-insensitive).
 // provider is optional - if empty, matches all providers.
 func (cr *CommandRegistry) Register(pattern string, provider string, handler StepHandler) {
 	key := cr.buildKey(pattern, provider)
 	cr.handlers[key] = handler
 }
 
+// RegisterID adds a handler under an ID such as "docker/build", for steps
+// that reference it with `uses:`. IDs are case-sensitive.
+func (cr *CommandRegistry) RegisterID(id string, handler StepHandler) {
+	cr.ids[id] = handler
+}
+
+// Alias makes steps named stepName (for provider, or all providers if empty)
+// without `uses:` run the handler registered as id.
+// Panics if no handler is registered as id.
+func (cr *CommandRegistry) Alias(stepName string, provider string, id string) {
+	handler, ok := cr.ids[id]
+	if !ok {
+		panic("registry: alias '" + stepName + "' for unregistered handler '" + id + "'")
+	}
+	cr.Register(stepName, provider, handler)
+	cr.aliases[cr.buildKey(stepName, provider)] = id
+}
+
+// AliasID returns the ID of the handler a step named stepName without
+// `uses:` runs, if its name is an alias.
+func (cr *CommandRegistry) AliasID(stepName string, provider string) (string, bool) {
+	stepLower := strings.ToLower(stepName)
+	if provider != "" {
+		if id, ok := cr.aliases[cr.buildKey(stepLower, provider)]; ok {
+			return id, true
+		}
+	}
+	id, ok := cr.aliases[stepLower]
+	return id, ok
+}
+
+// Handler returns the handler registered as id.
+func (cr *CommandRegistry) Handler(id string) (StepHandler, bool) {
+	handler, ok := cr.ids[id]
+	return handler, ok
+}
+
+// HasHandlerID returns true if a handler is registered as id.
+func (cr *CommandRegistry) HasHandlerID(id string) bool {
+	_, ok := cr.ids[id]
+	return ok
+}
+
+// HandlerIDs returns every registered handler ID, sorted.
+func (cr *CommandRegistry) HandlerIDs() []string {
+	ids := make([]string, 0, len(cr.ids))
+	for id := range cr.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // GetHandler finds the appropriate handler for a step.
 // Uses exact matching on step names for deterministic behavior.
 func (cr *CommandRegistry) GetHandler(stepName string, provider string) (StepHandler, bool) {
@@ -61,6 +122,12 @@ func (cr *CommandRegistry) GetHandler(stepName string, provider string) (StepHan
 	return nil, false
 }
 
+// HasHandler returns true if a handler is registered for the step.
+func (cr *CommandRegistry) HasHandler(stepName string, provider string) bool {
+	_, found := cr.GetHandler(stepName, provider)
+	return found
+}
+
 // buildKey creates a registry key from pattern and provider.
 func (*CommandRegistry) buildKey(pattern string, provider string) string {
 	pattern = strings.ToLower(pattern)
@@ -236,3 +236,36 @@ func TestCommandRegistryNilHandler(t *testing.T) {
 	result := handler(registry.StepContext{})
 	require.Nil(t, result)
 }
+
+func TestCommandRegistryHandlerIDs(t *testing.T) {
+	t.Parallel()
+
+	cr := registry.NewCommandRegistry()
+	cr.RegisterID("docker/push", func(ctx registry.StepContext) any {
+		return []string{"docker", "push", ctx.ImageName}
+	})
+	cr.RegisterID("docker/build", func(ctx registry.StepContext) any {
+		return []string{"docker", "build", "-f", ctx.Params.String("dockerfile", "Dockerfile")}
+	})
+	cr.Alias("publish to registry", "", "docker/push")
+
+	require.Equal(t, []string{"docker/build", "docker/push"}, cr.HandlerIDs())
+	require.True(t, cr.HasHandlerID("docker/build"))
+	require.False(t, cr.HasHandlerID("Docker/Build"))
+
+	handler, found := cr.Handler("docker/build")
+	require.True(t, found)
+	result := handler(registry.StepContext{Params: registry.Params{"dockerfile": "Dockerfile.prod"}})
+	require.Equal(t, []string{"docker", "build", "-f", "Dockerfile.prod"}, result)
+
+	// Aliases route step names to the handler ID
+	handler, found = cr.GetHandler("Publish to Registry", "gcp")
+	require.True(t, found)
+	require.Equal(t, []string{"docker", "push", "img"}, handler(registry.StepContext{ImageName: "img"}))
+
+	// IDs are not step names
+	_, found = cr.GetHandler("docker/build", "")
+	require.False(t, found)
+
+	require.Panics(t, func() { cr.Alias("deploy", "", "gcp/deploy") })
+}
@@ -7,10 +7,13 @@ import (
 	"github.com/sid-technologies/pilum/ingredients/docker"
 	"github.com/sid-technologies/pilum/ingredients/gcp"
 	"github.com/sid-technologies/pilum/ingredients/homebrew"
+	"github.com/sid-technologies/pilum/lib/artifact"
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 )
 
 // RegisterDefaultHandlers registers all built-in step handlers.
-// Handlers are registered with exact step names matching recipe definitions.
+// Recipes reference handlers by ID with `uses:`; steps without `uses:` fall
+// back to exact step names matching the original recipe definitions.
 func RegisterDefaultHandlers(reg *CommandRegistry) {
 	registerGCPCloudRunHandlers(reg)
 	registerHomebrewHandlers(reg)
@@ -20,57 +23,83 @@ func RegisterDefaultHandlers(reg *CommandRegistry) {
 // Step names must match exactly: "build binary", "build docker image", etc.
 func registerGCPCloudRunHandlers(reg *CommandRegistry) {
 	// Step 1: Build binary
-	reg.Register("build binary", "", func(ctx StepContext) any {
+	reg.RegisterID("build/binary", func(ctx StepContext) any {
 		cmd, _ := build.GenerateBuildCommand(ctx.Service, ctx.Registry, ctx.Tag)
 		return cmd
 	})
+	reg.Alias("build binary", "", "build/binary")
 
 	// Step 2: Build Docker image
-	reg.Register("build docker image", "", func(ctx StepContext) any {
-		templatePath := fmt.Sprintf("%s/%s", ctx.TemplatePath, ctx.Service.Template)
-		return docker.GenerateDockerBuildCommand(ctx.Service, ctx.ImageName, templatePath)
+	// with: dockerfile - path to the Dockerfile (default: <template path>/<service template>)
+	reg.RegisterID("docker/build", func(ctx StepContext) any {
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindImage, Image: ctx.ImageName})
+		return docker.GenerateDockerBuildCommand(ctx.Service, ctx.ImageName, Dockerfile(ctx.Service, ctx.TemplatePath, ctx.Params))
 	})
+	reg.Alias("build docker image", "", "docker/build")
 
 	// Step 3: Publish to registry (push Docker image)
-	reg.Register("publish to registry", "", func(ctx StepContext) any {
+	reg.RegisterID("docker/push", func(ctx StepContext) any {
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindImage, Image: ctx.ImageName})
 		return docker.GenerateDockerPushCommand(ctx.ImageName)
 	})
+	reg.Alias("publish to registry", "", "docker/push")
 
 	// Step 4: Deploy to Cloud Run (GCP-specific)
-	reg.Register("deploy to cloud run", "gcp", func(ctx StepContext) any {
+	reg.RegisterID("gcp/cloud-run-deploy", func(ctx StepContext) any {
 		return gcp.GenerateGCPDeployCommand(ctx.Service, ctx.ImageName)
 	})
+	reg.Alias("deploy to cloud run", "gcp", "gcp/cloud-run-deploy")
+}
+
+// Dockerfile returns the Dockerfile a docker/build step builds svc from: its
+// with: dockerfile, or the service's template under templatePath.
+func Dockerfile(svc serviceinfo.ServiceInfo, templatePath string, params Params) string {
+	return params.String("dockerfile", fmt.Sprintf("%s/%s", templatePath, svc.Template))
 }
 
 // registerHomebrewHandlers registers handlers for Homebrew recipe steps.
 // Step names must match exactly: "build binaries", "create archives", etc.
+// Every handler takes with: output_dir (default: dist).
 func registerHomebrewHandlers(reg *CommandRegistry) {
-	const outputDir = "dist"
+	const defaultOutputDir = "dist"
 
 	// Step 1: Build binaries for all platforms
-	reg.Register("build binaries", "homebrew", func(ctx StepContext) any {
+	reg.RegisterID("homebrew/build", func(ctx StepContext) any {
+		outputDir := ctx.Params.String("output_dir", defaultOutputDir)
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindBinary, Path: fmt.Sprintf("%s/%s_%s_*", outputDir, ctx.Service.Name, ctx.Tag)})
 		return homebrew.GenerateBuildCommand(ctx.Service, ctx.Tag, outputDir)
 	})
+	reg.Alias("build binaries", "homebrew", "homebrew/build")
 
 	// Step 2: Create tar.gz archives
-	reg.Register("create archives", "homebrew", func(ctx StepContext) any {
+	reg.RegisterID("homebrew/archive", func(ctx StepContext) any {
+		outputDir := ctx.Params.String("output_dir", defaultOutputDir)
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindArchive, Path: fmt.Sprintf("%s/%s_%s_*.tar.gz", outputDir, ctx.Service.Name, ctx.Tag)})
 		return homebrew.GenerateArchiveCommand(ctx.Service, ctx.Tag, outputDir)
 	})
+	reg.Alias("create archives", "homebrew", "homebrew/archive")
 
 	// Step 3: Generate SHA256 checksums
-	reg.Register("generate checksums", "homebrew", func(_ StepContext) any {
+	reg.RegisterID("homebrew/checksums", func(ctx StepContext) any {
+		outputDir := ctx.Params.String("output_dir", defaultOutputDir)
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindChecksum, Path: outputDir + "/checksums.txt"})
 		return homebrew.GenerateChecksumCommand(outputDir)
 	})
+	reg.Alias("generate checksums", "homebrew", "homebrew/checksums")
 
 	// Step 4: Update Homebrew formula
-	reg.Register("update formula", "homebrew", func(ctx StepContext) any {
+	reg.RegisterID("homebrew/formula", func(ctx StepContext) any {
+		outputDir := ctx.Params.String("output_dir", defaultOutputDir)
 		formulaPath := fmt.Sprintf("%s/%s.rb", outputDir, ctx.Service.Name)
+		ctx.Artifacts.Add(artifact.Spec{Kind: artifact.KindFormula, Path: formulaPath})
 		return homebrew.GenerateFormulaCommand(ctx.Service, ctx.Tag, outputDir, formulaPath)
 	})
+	reg.Alias("update formula", "homebrew", "homebrew/formula")
 
 	// Step 5: Push formula to Homebrew tap
-	reg.Register("push to tap", "homebrew", func(ctx StepContext) any {
-		formulaPath := fmt.Sprintf("%s/%s.rb", outputDir, ctx.Service.Name)
+	reg.RegisterID("homebrew/tap-push", func(ctx StepContext) any {
+		formulaPath := fmt.Sprintf("%s/%s.rb", ctx.Params.String("output_dir", defaultOutputDir), ctx.Service.Name)
 		return homebrew.GenerateTapPushCommand(ctx.Service, ctx.Tag, formulaPath)
 	})
+	reg.Alias("push to tap", "homebrew", "homebrew/tap-push")
 }
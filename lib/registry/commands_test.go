@@ -3,6 +3,7 @@ package registry_test
 import (
 	"testing"
 
+	"github.com/sid-technologies/pilum/lib/artifact"
 	"github.com/sid-technologies/pilum/lib/registry"
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
 
@@ -53,6 +54,91 @@ func TestRegisterDefaultHandlers(t *testing.T) {
 	}
 }
 
+func TestDefaultHandlerIDs(t *testing.T) {
+	t.Parallel()
+
+	reg := registry.NewCommandRegistry()
+	registry.RegisterDefaultHandlers(reg)
+
+	require.Equal(t, []string{
+		"build/binary",
+		"docker/build",
+		"docker/push",
+		"gcp/cloud-run-deploy",
+		"homebrew/archive",
+		"homebrew/build",
+		"homebrew/checksums",
+		"homebrew/formula",
+		"homebrew/tap-push",
+	}, reg.HandlerIDs())
+}
+
+func TestDefaultHandlerParams(t *testing.T) {
+	t.Parallel()
+
+	reg := registry.NewCommandRegistry()
+	registry.RegisterDefaultHandlers(reg)
+
+	dockerBuild, found := reg.Handler("docker/build")
+	require.True(t, found)
+	ctx := registry.StepContext{
+		Service:      serviceinfo.ServiceInfo{Name: "myservice", Template: "gcp-cloud-run"},
+		ImageName:    "gcr.io/project/myservice:latest",
+		TemplatePath: "./_templates",
+	}
+	require.Contains(t, dockerBuild(ctx), "./_templates/gcp-cloud-run")
+
+	ctx.Params = registry.Params{"dockerfile": "services/myservice/Dockerfile"}
+	cmd, ok := dockerBuild(ctx).([]string)
+	require.True(t, ok)
+	require.Contains(t, cmd, "services/myservice/Dockerfile")
+	require.NotContains(t, cmd, "./_templates/gcp-cloud-run")
+
+	checksums, found := reg.Handler("homebrew/checksums")
+	require.True(t, found)
+	result, ok := checksums(registry.StepContext{Params: registry.Params{"output_dir": "release"}}).(string)
+	require.True(t, ok)
+	require.Contains(t, result, "release")
+	require.NotContains(t, result, "dist")
+}
+
+func TestDefaultHandlerArtifacts(t *testing.T) {
+	t.Parallel()
+
+	reg := registry.NewCommandRegistry()
+	registry.RegisterDefaultHandlers(reg)
+
+	tests := []struct {
+		id   string
+		want artifact.Spec
+	}{
+		{id: "docker/push", want: artifact.Spec{Kind: artifact.KindImage, Image: "gcr.io/project/myservice:v1.0.0"}},
+		{id: "homebrew/build", want: artifact.Spec{Kind: artifact.KindBinary, Path: "release/myservice_v1.0.0_*"}},
+		{id: "homebrew/archive", want: artifact.Spec{Kind: artifact.KindArchive, Path: "release/myservice_v1.0.0_*.tar.gz"}},
+		{id: "homebrew/checksums", want: artifact.Spec{Kind: artifact.KindChecksum, Path: "release/checksums.txt"}},
+		{id: "homebrew/formula", want: artifact.Spec{Kind: artifact.KindFormula, Path: "release/myservice.rb"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			t.Parallel()
+
+			handler, found := reg.Handler(tt.id)
+			require.True(t, found)
+
+			var specs artifact.Specs
+			handler(registry.StepContext{
+				Service:   serviceinfo.ServiceInfo{Name: "myservice"},
+				ImageName: "gcr.io/project/myservice:v1.0.0",
+				Tag:       "v1.0.0",
+				Params:    registry.Params{"output_dir": "release"},
+				Artifacts: &specs,
+			})
+			require.Equal(t, []artifact.Spec{tt.want}, specs.List())
+		})
+	}
+}
+
 func TestBuildDockerImageHandlerExecution(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+)
+
+// Params holds a step's `with:` values. Values come from YAML and ${...}
+// expansion, so the typed getters also accept the string form of a value
+// (e.g., "3" for an int) and return the default if the key is missing or
+// can't be converted.
+type Params map[string]any
+
+// String returns the value of key as a string.
+func (p Params) String(key string, def string) string {
+	switch v := p[key].(type) {
+	case string:
+		return v
+	case int, int64, float64, bool:
+		return stringify(v)
+	}
+	return def
+}
+
+// Int returns the value of key as an int.
+func (p Params) Int(key string, def int) int {
+	if s, ok := p[key].(string); ok {
+		if n, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return n
+		}
+		return def
+	}
+	return configutil.GetInt(p, key, def)
+}
+
+// Bool returns the value of key as a bool.
+func (p Params) Bool(key string, def bool) bool {
+	if s, ok := p[key].(string); ok {
+		if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+			return b
+		}
+		return def
+	}
+	return configutil.GetBool(p, key, def)
+}
+
+// Strings returns the value of key as a list of strings. A single string is
+// a list of one.
+func (p Params) Strings(key string) []string {
+	if s, ok := p[key].(string); ok {
+		return []string{s}
+	}
+	return configutil.GetStringSlice(p, key)
+}
+
+func stringify(v any) string {
+	switch v := v.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package registry_test
+
+import (
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/registry"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParams(t *testing.T) {
+	t.Parallel()
+
+	params := registry.Params{
+		"dockerfile": "Dockerfile.prod",
+		"replicas":   3,
+		"workers":    "4",
+		"cpu":        1.5,
+		"debug":      true,
+		"cache":      "false",
+		"platforms":  []any{"linux/amd64", "linux/arm64"},
+		"tag":        "latest",
+		"bad":        "many",
+	}
+
+	require.Equal(t, "Dockerfile.prod", params.String("dockerfile", ""))
+	require.Equal(t, "3", params.String("replicas", ""))
+	require.Equal(t, "1.5", params.String("cpu", ""))
+	require.Equal(t, "x", params.String("missing", "x"))
+
+	require.Equal(t, 3, params.Int("replicas", 0))
+	require.Equal(t, 4, params.Int("workers", 0))
+	require.Equal(t, 1, params.Int("bad", 1))
+	require.Equal(t, 2, params.Int("missing", 2))
+
+	require.True(t, params.Bool("debug", false))
+	require.False(t, params.Bool("cache", true))
+	require.True(t, params.Bool("bad", true))
+
+	require.Equal(t, []string{"linux/amd64", "linux/arm64"}, params.Strings("platforms"))
+	require.Equal(t, []string{"latest"}, params.Strings("tag"))
+	require.Nil(t, params.Strings("missing"))
+
+	// A nil Params returns defaults
+	var empty registry.Params
+	require.Equal(t, "d", empty.String("dockerfile", "d"))
+}
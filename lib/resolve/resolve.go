@@ -0,0 +1,255 @@
+// Package resolve expands ${...} references in pilum.yaml values when a
+// service is loaded:
+//
+//	${GCP_PROJECT}                          environment variable (empty if unset)
+//	${GCP_PROJECT:-acme-dev}                default if unset or empty
+//	${GCP_PROJECT:?set GCP_PROJECT}         error if unset or empty
+//	${file:./version.txt}                   file contents, trimmed
+//	${tfoutput:infra/outputs.json:api_url}  output from `terraform output -json`
+//	${cmd:git rev-parse --short HEAD}       command output, trimmed
+//
+// Environment variable names are upper case; other expressions, such as
+// recipe variables like ${tag}, are left untouched. Resolver paths and
+// commands are relative to the directory of the pilum.yaml. "$${" produces
+// a literal "${".
+package resolve
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Func resolves the argument of a ${name:arg} reference.
+type Func func(arg string, ctx Context) (string, error)
+
+// Context is what a resolver may depend on.
+type Context struct {
+	Dir string // Directory of the pilum.yaml being loaded
+}
+
+var (
+	resolversMu sync.RWMutex
+	resolvers   = map[string]Func{}
+)
+
+// Register adds a resolver for ${name:arg} references, replacing any
+// resolver already registered under name.
+func Register(name string, fn Func) {
+	resolversMu.Lock()
+	defer resolversMu.Unlock()
+	resolvers[name] = fn
+}
+
+// Names returns the registered resolver names, sorted.
+func Names() []string {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	names := make([]string, 0, len(resolvers))
+	for name := range resolvers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func lookupResolver(name string) (Func, bool) {
+	resolversMu.RLock()
+	defer resolversMu.RUnlock()
+	fn, ok := resolvers[name]
+	return fn, ok
+}
+
+var (
+	envPattern      = regexp.MustCompile(`^([A-Z_][A-Z0-9_]*)(?:(:-|:\?)(.*))?$`)
+	resolverPattern = regexp.MustCompile(`^([a-z][a-z0-9_]*):(.*)$`)
+)
+
+// KeyError is the error Config returns for a value that failed to resolve.
+type KeyError struct {
+	Key string // e.g. "cloud_run.env[0]"
+	Err error
+}
+
+func (e *KeyError) Error() string {
+	return e.Key + ": " + e.Err.Error()
+}
+
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// Config returns a copy of config with every string value resolved. Errors
+// are a *KeyError naming the key.
+func Config(config map[string]any, ctx Context) (map[string]any, error) {
+	resolved, err := value(config, "", ctx)
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]any), nil
+}
+
+func value(v any, key string, ctx Context) (any, error) {
+	switch val := v.(type) {
+	case string:
+		s, err := String(val, ctx)
+		if err != nil {
+			return nil, &KeyError{Key: key, Err: err}
+		}
+		return s, nil
+	case map[string]any:
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			r, err := value(item, join(key, k), ctx)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = r
+		}
+		return result, nil
+	case map[any]any:
+		result := make(map[string]any, len(val))
+		for k, item := range val {
+			name, ok := k.(string)
+			if !ok {
+				name = toString(k)
+			}
+			r, err := value(item, join(key, name), ctx)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = r
+		}
+		return result, nil
+	case []any:
+		result := make([]any, len(val))
+		for i, item := range val {
+			r, err := value(item, key+"["+strconv.Itoa(i)+"]", ctx)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = r
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+func join(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func toString(v any) string {
+	switch val := v.(type) {
+	case int:
+		return strconv.Itoa(val)
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		return ""
+	}
+}
+
+// String resolves the references in s.
+func String(s string, ctx Context) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if strings.HasPrefix(s[i:], "$${") {
+			sb.WriteString("${")
+			i += 3
+			continue
+		}
+		if !strings.HasPrefix(s[i:], "${") {
+			sb.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			return "", errors.New("unterminated reference in '%s'", s)
+		}
+
+		expr := s[i+2 : end]
+		resolved, ok, err := reference(expr, ctx)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			// Not ours (e.g. a recipe variable): keep it as written
+			resolved = s[i : end+1]
+		}
+		sb.WriteString(resolved)
+		i = end + 1
+	}
+	return sb.String(), nil
+}
+
+// closingBrace returns the index of the "}" matching the "${" before start,
+// allowing nested references in defaults. Returns -1 if there is none.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// reference resolves a single expression. ok is false if the expression is
+// neither an environment variable nor a registered resolver.
+func reference(expr string, ctx Context) (string, bool, error) {
+	if m := envPattern.FindStringSubmatch(expr); m != nil {
+		name, op, arg := m[1], m[2], m[3]
+		val := os.Getenv(name)
+		if val != "" || op == "" {
+			return val, true, nil
+		}
+		arg, err := String(arg, ctx)
+		if err != nil {
+			return "", true, err
+		}
+		if op == ":?" {
+			if arg == "" {
+				arg = "not set"
+			}
+			return "", true, errors.New("environment variable %s is required: %s", name, arg)
+		}
+		return arg, true, nil
+	}
+
+	if m := resolverPattern.FindStringSubmatch(expr); m != nil {
+		fn, exists := lookupResolver(m[1])
+		if !exists {
+			return "", false, nil
+		}
+		val, err := fn(m[2], ctx)
+		if err != nil {
+			return "", true, errors.Wrap(err, "${"+expr+"}")
+		}
+		return val, true, nil
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,133 @@
+package resolve_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/resolve"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStringEnvironment(t *testing.T) {
+	t.Setenv("PILUM_TEST_PROJECT", "acme-prod")
+	t.Setenv("PILUM_TEST_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "plain", input: "no references", expected: "no references"},
+		{name: "variable", input: "${PILUM_TEST_PROJECT}", expected: "acme-prod"},
+		{name: "embedded", input: "gcr.io/${PILUM_TEST_PROJECT}/api", expected: "gcr.io/acme-prod/api"},
+		{name: "unset", input: "x${PILUM_TEST_UNSET}y", expected: "xy"},
+		{name: "default unused", input: "${PILUM_TEST_PROJECT:-dev}", expected: "acme-prod"},
+		{name: "default", input: "${PILUM_TEST_UNSET:-acme-dev}", expected: "acme-dev"},
+		{name: "default when empty", input: "${PILUM_TEST_EMPTY:-acme-dev}", expected: "acme-dev"},
+		{name: "nested default", input: "${PILUM_TEST_UNSET:-${PILUM_TEST_PROJECT}}", expected: "acme-prod"},
+		{name: "recipe variable kept", input: "${name}-${tag | upper}", expected: "${name}-${tag | upper}"},
+		{name: "escaped", input: "$${PILUM_TEST_PROJECT}", expected: "${PILUM_TEST_PROJECT}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := resolve.String(tt.input, resolve.Context{})
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStringRequired(t *testing.T) {
+	t.Setenv("PILUM_TEST_PROJECT", "acme-prod")
+
+	result, err := resolve.String("${PILUM_TEST_PROJECT:?set it}", resolve.Context{})
+	require.NoError(t, err)
+	require.Equal(t, "acme-prod", result)
+
+	_, err = resolve.String("${PILUM_TEST_UNSET:?export PILUM_TEST_UNSET first}", resolve.Context{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "PILUM_TEST_UNSET is required: export PILUM_TEST_UNSET first")
+
+	_, err = resolve.String("${PILUM_TEST_UNSET", resolve.Context{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "unterminated")
+}
+
+func TestResolvers(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "version.txt"), []byte("1.4.2\n"), 0o644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "infra"), 0o755))
+	outputs := `{
+  "api_url": {"sensitive": false, "type": "string", "value": "https://api.example.com"},
+  "replicas": {"type": "number", "value": 3},
+  "plain": "value"
+}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "infra", "outputs.json"), []byte(outputs), 0o644))
+	ctx := resolve.Context{Dir: dir}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "v${file:./version.txt}", expected: "v1.4.2"},
+		{input: "${tfoutput:infra/outputs.json:api_url}", expected: "https://api.example.com"},
+		{input: "${tfoutput:infra/outputs.json:replicas}", expected: "3"},
+		{input: "${tfoutput:infra/outputs.json:plain}", expected: "value"},
+		{input: "${cmd:cat version.txt}", expected: "1.4.2"},
+	}
+	for _, tt := range tests {
+		result, err := resolve.String(tt.input, ctx)
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.expected, result, tt.input)
+	}
+
+	for _, input := range []string{
+		"${file:missing.txt}",
+		"${tfoutput:infra/outputs.json:missing}",
+		"${tfoutput:infra/outputs.json}",
+		"${cmd:exit 3}",
+	} {
+		_, err := resolve.String(input, ctx)
+		require.Error(t, err, input)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	t.Parallel()
+
+	resolve.Register("pilumtest", func(arg string, _ resolve.Context) (string, error) {
+		return "<" + arg + ">", nil
+	})
+	require.Contains(t, resolve.Names(), "pilumtest")
+
+	result, err := resolve.String("${pilumtest:x} ${unknown:y}", resolve.Context{})
+	require.NoError(t, err)
+	require.Equal(t, "<x> ${unknown:y}", result)
+}
+
+func TestConfigNamesKey(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":  "api",
+		"count": 2,
+		"cloud_run": map[any]any{
+			"flags": []any{"--a", "${file:missing.txt}"},
+		},
+	}
+
+	_, err := resolve.Config(config, resolve.Context{Dir: t.TempDir()})
+	var keyErr *resolve.KeyError
+	require.ErrorAs(t, err, &keyErr)
+	require.Equal(t, "cloud_run.flags[1]", keyErr.Key)
+	require.Contains(t, err.Error(), "cloud_run.flags[1]: ")
+
+	resolved, err := resolve.Config(map[string]any{"count": 2, "build": map[any]any{"version": "1"}}, resolve.Context{})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"count": 2, "build": map[string]any{"version": "1"}}, resolved)
+}
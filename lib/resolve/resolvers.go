@@ -0,0 +1,101 @@
+package resolve
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// CommandTimeout bounds how long a ${cmd:...} reference may run.
+const CommandTimeout = 30 * time.Second
+
+// nolint: gochecknoinits // Built-in resolvers are registered like plugins
+func init() {
+	Register("file", resolveFile)
+	Register("tfoutput", resolveTerraformOutput)
+	Register("cmd", resolveCommand)
+}
+
+// resolveFile returns the trimmed contents of a file.
+func resolveFile(arg string, ctx Context) (string, error) {
+	data, err := os.ReadFile(ctx.path(arg))
+	if err != nil {
+		return "", errors.Wrap(err, "error reading "+arg)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// resolveTerraformOutput reads "<file>:<name>" from a file written by
+// `terraform output -json`. Plain {"name": value} files also work.
+func resolveTerraformOutput(arg string, ctx Context) (string, error) {
+	file, name, ok := strings.Cut(arg, ":")
+	if !ok || file == "" || name == "" {
+		return "", errors.New("expected <file>:<output>, got '%s'", arg)
+	}
+
+	data, err := os.ReadFile(ctx.path(file))
+	if err != nil {
+		return "", errors.Wrap(err, "error reading "+file)
+	}
+
+	var outputs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &outputs); err != nil {
+		return "", errors.Wrap(err, "error parsing "+file)
+	}
+
+	raw, exists := outputs[name]
+	if !exists {
+		return "", errors.New("output '%s' not found in %s", name, file)
+	}
+
+	var wrapped struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &wrapped); err == nil && wrapped.Value != nil {
+		raw = wrapped.Value
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	return string(raw), nil
+}
+
+// resolveCommand runs a shell command and returns its trimmed stdout.
+func resolveCommand(arg string, ctx Context) (string, error) {
+	if strings.TrimSpace(arg) == "" {
+		return "", errors.New("empty command")
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), CommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(timeout, "sh", "-c", arg) //nolint:gosec // Command comes from trusted pilum.yaml
+	cmd.Dir = ctx.Dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Wrap(err, "command failed: "+msg)
+		}
+		return "", errors.Wrap(err, "command failed")
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// path resolves p against the directory of the pilum.yaml.
+func (c Context) path(p string) string {
+	if filepath.IsAbs(p) || c.Dir == "" {
+		return p
+	}
+	return filepath.Join(c.Dir, p)
+}
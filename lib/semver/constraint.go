@@ -0,0 +1,138 @@
+package semver
+
+import (
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Constraint is a set of version comparisons that must all hold.
+//
+// Supported forms, combined with commas or spaces (">=0.4, <1"):
+//
+//	>=1.2  >1.2  <=1.2  <1.2   comparisons
+//	2  2.1  =2.1              prefix match: 2 means >=2.0.0 <3.0.0
+//	^2.1                      compatible: >=2.1.0 <3.0.0 (^0.4 means <0.5.0)
+//	~2.1                      patch updates: >=2.1.0 <2.2.0
+//	*                         any version
+//
+// Pre-release suffixes are ignored when checking, so development builds of
+// a release satisfy the same constraints as the release.
+type Constraint struct {
+	source      string
+	comparisons []comparison
+}
+
+type comparison struct {
+	op string // one of >=, >, <=, <
+	v  Version
+}
+
+// ParseConstraint parses a version constraint. An empty string or "*" matches every version.
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{source: strings.TrimSpace(s)}
+
+	for _, term := range constraintTerms(s) {
+		if term == "*" {
+			continue
+		}
+		comparisons, err := parseTerm(term)
+		if err != nil {
+			return Constraint{}, errors.Wrap(err, "invalid constraint '"+c.source+"'")
+		}
+		c.comparisons = append(c.comparisons, comparisons...)
+	}
+
+	return c, nil
+}
+
+// Check reports whether v satisfies every comparison in the constraint.
+func (c Constraint) Check(v Version) bool {
+	for _, cmp := range c.comparisons {
+		result := v.compareRelease(cmp.v)
+		var ok bool
+		switch cmp.op {
+		case ">=":
+			ok = result >= 0
+		case ">":
+			ok = result > 0
+		case "<=":
+			ok = result <= 0
+		default:
+			ok = result < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the constraint as written.
+func (c Constraint) String() string {
+	if c.source == "" {
+		return "*"
+	}
+	return c.source
+}
+
+// constraintTerms splits a constraint into terms, joining an operator
+// written apart from its version (">= 0.4").
+func constraintTerms(s string) []string {
+	fields := strings.Fields(strings.ReplaceAll(s, ",", " "))
+	var terms []string
+	for i := 0; i < len(fields); i++ {
+		term := fields[i]
+		if strings.Trim(term, "<>=^~") == "" && i+1 < len(fields) {
+			term += fields[i+1]
+			i++
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// parseTerm turns a single term into the comparisons it stands for.
+func parseTerm(term string) ([]comparison, error) {
+	op := ""
+	for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, prefix) {
+			op = prefix
+			break
+		}
+	}
+
+	v, parts, err := parsePartial(strings.TrimPrefix(term, op))
+	if err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case ">=", ">", "<=", "<":
+		return []comparison{{op, v}}, nil
+	case "^":
+		upper := Version{Major: v.Major + 1}
+		if v.Major == 0 && parts > 1 {
+			upper = Version{Minor: v.Minor + 1}
+		}
+		return []comparison{{">=", v}, {"<", upper}}, nil
+	case "~":
+		upper := Version{Major: v.Major, Minor: v.Minor + 1}
+		if parts == 1 {
+			upper = Version{Major: v.Major + 1}
+		}
+		return []comparison{{">=", v}, {"<", upper}}, nil
+	default:
+		// Prefix match on the components that were written
+		var upper Version
+		switch parts {
+		case 1:
+			upper = Version{Major: v.Major + 1}
+		case 2:
+			upper = Version{Major: v.Major, Minor: v.Minor + 1}
+		default:
+			upper = Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+		}
+		return []comparison{{">=", v}, {"<", upper}}, nil
+	}
+}
@@ -0,0 +1,112 @@
+// Package semver parses semantic versions and version constraints such as
+// ">=0.4", "^2.1" or "2", as used by recipe versions and requires_pilum.
+package semver
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// Version is a parsed semantic version. Missing minor and patch numbers are zero.
+type Version struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+}
+
+// Parse parses a version such as "2", "1.4", "v0.4.1" or "1.0.0-rc.1".
+// Build metadata ("+...") is ignored.
+func Parse(s string) (Version, error) {
+	v, _, err := parsePartial(s)
+	return v, err
+}
+
+// MustParse is like Parse but panics on error. Intended for constants and tests.
+func MustParse(s string) Version {
+	v, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// String returns the version as "major.minor.patch[-prerelease]".
+func (v Version) String() string {
+	s := strconv.Itoa(v.Major) + "." + strconv.Itoa(v.Minor) + "." + strconv.Itoa(v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than other.
+// A pre-release sorts before the release it precedes.
+func (v Version) Compare(other Version) int {
+	if c := v.compareRelease(other); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	case v.Prerelease < other.Prerelease:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// compareRelease compares major, minor and patch, ignoring pre-release suffixes.
+func (v Version) compareRelease(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// parsePartial parses a version and also returns how many numeric components
+// were written (1-3), which constraints use for prefix matching.
+func parsePartial(s string) (Version, int, error) {
+	raw := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(s, '+'); i >= 0 {
+		s = s[:i]
+	}
+
+	var v Version
+	if i := strings.IndexByte(s, '-'); i >= 0 {
+		v.Prerelease = s[i+1:]
+		s = s[:i]
+		if v.Prerelease == "" {
+			return Version{}, 0, errors.New("invalid version '%s': empty pre-release", raw)
+		}
+	}
+
+	parts := strings.Split(s, ".")
+	if s == "" || len(parts) > 3 {
+		return Version{}, 0, errors.New("invalid version '%s': expected major[.minor[.patch]]", raw)
+	}
+
+	numbers := [3]int{}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, 0, errors.New("invalid version '%s': '%s' is not a number", raw, p)
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+
+	return v, len(parts), nil
+}
@@ -0,0 +1,87 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2", "2.0.0"},
+		{"1.4", "1.4.0"},
+		{"v0.4.1", "0.4.1"},
+		{"1.0.0-rc.1", "1.0.0-rc.1"},
+		{"1.2.3+build.5", "1.2.3"},
+	}
+
+	for _, tt := range tests {
+		v, err := Parse(tt.input)
+		require.NoError(t, err, tt.input)
+		require.Equal(t, tt.expected, v.String())
+	}
+
+	for _, invalid := range []string{"", "x", "1.2.3.4", "1..2", "1.x", "1.0-", "dev"} {
+		_, err := Parse(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, 0, MustParse("2").Compare(MustParse("2.0.0")))
+	require.Equal(t, -1, MustParse("1.9").Compare(MustParse("1.10")))
+	require.Equal(t, 1, MustParse("2.0.1").Compare(MustParse("2")))
+	require.Equal(t, -1, MustParse("1.0.0-rc.1").Compare(MustParse("1.0.0")))
+	require.Equal(t, -1, MustParse("1.0.0-alpha").Compare(MustParse("1.0.0-beta")))
+}
+
+func TestConstraintCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraint string
+		matches    []string
+		rejects    []string
+	}{
+		{">=0.4", []string{"0.4.0", "0.5", "1.0.0", "0.4.0-3-gabc"}, []string{"0.3.9"}},
+		{">=0.4, <1", []string{"0.4", "0.9.9"}, []string{"1.0.0", "0.3"}},
+		{">= 0.4 < 1", []string{"0.4"}, []string{"1"}},
+		{"2", []string{"2.0.0", "2.9.1"}, []string{"1.9", "3.0.0"}},
+		{"2.1", []string{"2.1.0", "2.1.7"}, []string{"2.2.0", "2.0.9"}},
+		{"=2.1.3", []string{"2.1.3"}, []string{"2.1.4"}},
+		{"^2.1", []string{"2.1.0", "2.9.0"}, []string{"2.0.9", "3.0.0"}},
+		{"^0.4", []string{"0.4.2"}, []string{"0.5.0"}},
+		{"~2.1", []string{"2.1.5"}, []string{"2.2.0"}},
+		{"~2", []string{"2.5.0"}, []string{"3.0.0"}},
+		{">1, <=2", []string{"1.0.1", "2.0.0"}, []string{"1.0.0", "2.0.1"}},
+		{"*", []string{"0.0.1", "99"}, nil},
+		{"", []string{"1"}, nil},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseConstraint(tt.constraint)
+		require.NoError(t, err, tt.constraint)
+		for _, v := range tt.matches {
+			require.True(t, c.Check(MustParse(v)), "%s should match %s", tt.constraint, v)
+		}
+		for _, v := range tt.rejects {
+			require.False(t, c.Check(MustParse(v)), "%s should not match %s", tt.constraint, v)
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	t.Parallel()
+
+	for _, invalid := range []string{">=", ">=x", "^1.2.3.4", ">>1"} {
+		_, err := ParseConstraint(invalid)
+		require.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,227 @@
+package serviceinfo
+
+import (
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/gitignore"
+	"github.com/sid-technologies/pilum/lib/output"
+)
+
+// TemplateDir holds the build templates services select with template:
+// (e.g. _templates/gcp-cloud-run), relative to the project root.
+const TemplateDir = "_templates"
+
+// ChangeDetector finds the services a set of changed files affects
+// directly. Services that depend on them are found separately, from
+// depends_on.
+type ChangeDetector interface {
+	// Name identifies the detector in reports, e.g. "paths".
+	Name() string
+	// Detect maps the name of each affected service to the changed files
+	// that affect it. A detector that can't tell which files did may map a
+	// service to nil.
+	Detect(services []ServiceInfo, changedFiles []string) (map[string][]string, error)
+}
+
+// PathDetectorName is the name of PathDetector.
+const PathDetectorName = "paths"
+
+// PathDetector is the default detector: a service is changed if a file
+// changed in its directory, matches one of its or the workspace's
+// watch_paths, or is a WatchFiles file.
+type PathDetector struct {
+	// WatchPaths are globs every service watches, from the workspace.
+	WatchPaths []string
+	// WatchFiles returns files outside a service's directory that it's built
+	// from, such as its recipe file and Dockerfile.
+	WatchFiles func(svc ServiceInfo) []string
+}
+
+// Name implements ChangeDetector.
+func (PathDetector) Name() string {
+	return PathDetectorName
+}
+
+// Detect implements ChangeDetector.
+func (d PathDetector) Detect(services []ServiceInfo, changedFiles []string) (map[string][]string, error) {
+	shared, err := compileWatchPaths(d.WatchPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := make(map[string][]string)
+	for _, svc := range services {
+		if _, done := changed[svc.Name]; done {
+			continue
+		}
+		watched, err := svc.watchedFiles(changedFiles, shared, d.WatchFiles)
+		if err != nil {
+			return nil, err
+		}
+		if len(watched) > 0 {
+			changed[svc.Name] = watched
+		}
+	}
+	return changed, nil
+}
+
+// Change explains why a service is affected by a set of changed files:
+// either the detectors that found it changed and the files they blamed, or
+// the path of dependencies to a service that changed.
+type Change struct {
+	Service   string   `json:"service"`
+	Files     []string `json:"files,omitempty"`
+	Detectors []string `json:"detectors,omitempty"`
+	// Dependency is the path to a changed service, e.g. [web api db] if web
+	// depends on api, which depends on db.
+	Dependency []string `json:"dependency,omitempty"`
+}
+
+// Direct reports whether the service itself changed, rather than a service
+// it depends on.
+func (c Change) Direct() bool {
+	return len(c.Dependency) == 0
+}
+
+// AffectedServices returns the services affected by changes since the given
+// ref (default: main or master), including uncommitted changes, in the order
+// of services.
+func AffectedServices(services []ServiceInfo, since string, detectors []ChangeDetector) ([]Change, error) {
+	changedFiles, err := git.ChangedFiles(since)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to detect changed files")
+	}
+
+	// Also include uncommitted changes
+	uncommitted, err := git.ChangedFilesUncommitted()
+	if err != nil {
+		output.Debugf("Could not get uncommitted changes: %v", err)
+	} else {
+		changedFiles = append(changedFiles, uncommitted...)
+	}
+
+	output.Debugf("Changed files: %v", changedFiles)
+	return DetectChanges(services, changedFiles, detectors)
+}
+
+// DetectChanges returns the services affected by changedFiles (slash paths
+// relative to the project root), in the order of services. A service is
+// changed if any of the detectors (default: a PathDetector) says so, and
+// affected if it depends on a changed service. Services sharing a name are
+// reported once.
+func DetectChanges(services []ServiceInfo, changedFiles []string, detectors []ChangeDetector) ([]Change, error) {
+	if len(detectors) == 0 {
+		detectors = []ChangeDetector{PathDetector{}}
+	}
+
+	direct := make(map[string]*Change)
+	for _, detector := range detectors {
+		changed, err := detector.Detect(services, changedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, detector.Name()+" change detector")
+		}
+		for name, files := range changed {
+			change, ok := direct[name]
+			if !ok {
+				change = &Change{Service: name}
+				direct[name] = change
+			}
+			change.Detectors = append(change.Detectors, detector.Name())
+			for _, file := range files {
+				if !slices.Contains(change.Files, file) {
+					change.Files = append(change.Files, file)
+				}
+			}
+			output.Debugf("Service %s has direct changes (%s)", name, detector.Name())
+		}
+	}
+
+	changedNames := make(map[string]bool, len(direct))
+	for name := range direct {
+		changedNames[name] = true
+	}
+
+	g := BuildDependencyGraph(services)
+	var changes []Change
+	reported := make(map[string]bool)
+	for _, svc := range services {
+		if reported[svc.Name] {
+			continue
+		}
+		reported[svc.Name] = true
+
+		if change, ok := direct[svc.Name]; ok {
+			changes = append(changes, *change)
+		} else if path := g.DependencyPath(svc.Name, changedNames); path != nil {
+			output.Debugf("Service %s included (depends on changed service %s)", svc.Name, path[len(path)-1])
+			changes = append(changes, Change{Service: svc.Name, Dependency: path})
+		}
+	}
+	return changes, nil
+}
+
+// watchedFiles returns the changed files the service watches.
+func (s ServiceInfo) watchedFiles(changedFiles []string, shared []*regexp.Regexp, watchFiles func(ServiceInfo) []string) ([]string, error) {
+	own, err := compileWatchPaths(s.WatchPaths)
+	if err != nil {
+		return nil, errors.Wrap(err, "service '"+s.Name+"'")
+	}
+
+	var exact []string
+	if watchFiles != nil {
+		for _, file := range watchFiles(s) {
+			exact = append(exact, filepath.ToSlash(filepath.Clean(file)))
+		}
+	}
+
+	var watched []string
+	for _, file := range changedFiles {
+		if git.ServiceHasChanges(s.Path, []string{file}) ||
+			matchesWatchPath(file, own) || matchesWatchPath(file, shared) || withinAny(file, exact) {
+			watched = append(watched, file)
+		}
+	}
+	return watched, nil
+}
+
+// compileWatchPaths compiles watch_paths globs.
+func compileWatchPaths(globs []string) ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		re, err := gitignore.CompileGlob(strings.TrimSuffix(glob, "/"))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid watch_paths")
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns, nil
+}
+
+// matchesWatchPath reports whether file, or a directory containing it,
+// matches one of the patterns, so "libs" watches everything under libs/.
+func matchesWatchPath(file string, patterns []*regexp.Regexp) bool {
+	for p := file; p != "." && p != "/"; p = path.Dir(p) {
+		for _, re := range patterns {
+			if re.MatchString(p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withinAny reports whether file is one of paths or inside one of them.
+func withinAny(file string, paths []string) bool {
+	for _, p := range paths {
+		if file == p || strings.HasPrefix(file, p+"/") {
+			return true
+		}
+	}
+	return false
+}
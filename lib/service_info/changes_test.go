@@ -0,0 +1,153 @@
+package serviceinfo_test
+
+import (
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectChanges(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Path: "services/api", DependsOn: []string{"auth"}, Region: "us-east1"},
+		{Name: "api", Path: "services/api", DependsOn: []string{"auth"}, Region: "europe-west1"},
+		{Name: "auth", Path: "services/auth", WatchPaths: []string{"libs/crypto"}},
+		{Name: "web", Path: "services/web", DependsOn: []string{"api"}},
+		{Name: "worker", Path: "services/worker", Template: "gcp-cloud-run"},
+		{Name: "cli", Path: "tools/cli", Provider: "homebrew"},
+	}
+	detectors := []serviceinfo.ChangeDetector{serviceinfo.PathDetector{
+		WatchPaths: []string{"go.mod"},
+		WatchFiles: func(svc serviceinfo.ServiceInfo) []string {
+			switch {
+			case svc.Provider == "homebrew":
+				return []string{"./recepies/homebrew-recepie.yaml"}
+			case svc.Template != "":
+				return []string{"./_templates/" + svc.Template}
+			}
+			return nil
+		},
+	}}
+	paths := []string{serviceinfo.PathDetectorName}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []serviceinfo.Change
+	}{
+		{
+			name:  "service directory",
+			files: []string{"services/web/index.ts", "services/webhooks/main.go"},
+			expected: []serviceinfo.Change{
+				{Service: "web", Files: []string{"services/web/index.ts"}, Detectors: paths},
+			},
+		},
+		{
+			name:  "own watch path and dependents",
+			files: []string{"libs/crypto/aes/aes.go"},
+			expected: []serviceinfo.Change{
+				{Service: "api", Dependency: []string{"api", "auth"}},
+				{Service: "auth", Files: []string{"libs/crypto/aes/aes.go"}, Detectors: paths},
+				{Service: "web", Dependency: []string{"web", "api", "auth"}},
+			},
+		},
+		{
+			name:  "template and recipe files",
+			files: []string{"_templates/gcp-cloud-run", "recepies/homebrew-recepie.yaml", "recepies/aws-lambda-recepie.yaml"},
+			expected: []serviceinfo.Change{
+				{Service: "worker", Files: []string{"_templates/gcp-cloud-run"}, Detectors: paths},
+				{Service: "cli", Files: []string{"recepies/homebrew-recepie.yaml"}, Detectors: paths},
+			},
+		},
+		{
+			name:  "workspace watch path",
+			files: []string{"go.mod"},
+			expected: []serviceinfo.Change{
+				{Service: "api", Files: []string{"go.mod"}, Detectors: paths},
+				{Service: "auth", Files: []string{"go.mod"}, Detectors: paths},
+				{Service: "web", Files: []string{"go.mod"}, Detectors: paths},
+				{Service: "worker", Files: []string{"go.mod"}, Detectors: paths},
+				{Service: "cli", Files: []string{"go.mod"}, Detectors: paths},
+			},
+		},
+		{
+			name:     "unwatched",
+			files:    []string{"README.md", "libs/log/log.go"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			changes, err := serviceinfo.DetectChanges(services, tt.files, detectors)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, changes)
+		})
+	}
+}
+
+func TestDetectChangesWatchPathGlobs(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Path: "services/api", WatchPaths: []string{"libs/**/*.proto", "shared/"}},
+	}
+	paths := []string{serviceinfo.PathDetectorName}
+
+	changes, err := serviceinfo.DetectChanges(services, []string{"libs/a/b/user.proto", "libs/a/user.go", "shared/x/y.go"}, nil)
+	require.NoError(t, err)
+	require.Equal(t, []serviceinfo.Change{{Service: "api", Files: []string{"libs/a/b/user.proto", "shared/x/y.go"}, Detectors: paths}}, changes)
+
+	_, err = serviceinfo.DetectChanges(services, nil, []serviceinfo.ChangeDetector{
+		serviceinfo.PathDetector{WatchPaths: []string{"[z-a]"}},
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid watch_paths")
+}
+
+// fakeDetector reports fixed changes.
+type fakeDetector struct {
+	name    string
+	changed map[string][]string
+}
+
+func (d fakeDetector) Name() string { return d.name }
+
+func (d fakeDetector) Detect([]serviceinfo.ServiceInfo, []string) (map[string][]string, error) {
+	return d.changed, nil
+}
+
+func TestDetectChangesDetectors(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Path: "services/api"},
+		{Name: "web", Path: "services/web", DependsOn: []string{"api"}},
+		{Name: "worker", Path: "services/worker"},
+	}
+	detectors := []serviceinfo.ChangeDetector{
+		serviceinfo.PathDetector{},
+		fakeDetector{name: "go", changed: map[string][]string{"api": {"libs/db/db.go"}, "worker": {"libs/db/db.go"}}},
+		fakeDetector{name: "command", changed: map[string][]string{"worker": nil}},
+	}
+
+	changes, err := serviceinfo.DetectChanges(services, []string{"services/api/main.go", "libs/db/db.go"}, detectors)
+	require.NoError(t, err)
+	require.Equal(t, []serviceinfo.Change{
+		{Service: "api", Files: []string{"services/api/main.go", "libs/db/db.go"}, Detectors: []string{"paths", "go"}},
+		{Service: "web", Dependency: []string{"web", "api"}},
+		{Service: "worker", Files: []string{"libs/db/db.go"}, Detectors: []string{"go", "command"}},
+	}, changes)
+}
+
+func TestNewServiceInfoWatchPaths(t *testing.T) {
+	t.Parallel()
+
+	svc := serviceinfo.NewServiceInfo(map[string]any{"name": "api", "watch_paths": []any{"libs/**", "go.mod"}}, "services/api")
+	require.Equal(t, []string{"libs/**", "go.mod"}, svc.WatchPaths)
+}
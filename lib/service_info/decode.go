@@ -0,0 +1,418 @@
+package serviceinfo
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/gitignore"
+	"github.com/sid-technologies/pilum/lib/suggest"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is a single error found in a pilum.yaml.
+type Problem struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// String formats the problem as "path:line:col: message".
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Line, p.Column, p.Message)
+}
+
+// Problems is every problem found while loading services. Discovery returns
+// it as the error, alongside the services that loaded cleanly.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CoreKeys are the top-level pilum.yaml keys pilum itself reads. Recipes add
+// the keys of their fields (e.g. cloud_run).
+var CoreKeys = []string{
+	"name", "description", "type", "template", "recipe", "provider",
+	"region", "regions", "project", "license", "registry_name", "depends_on",
+	"build", "runtime", "env_vars", "secrets", "labels", "watch_paths", EnvironmentsKey, TargetsKey,
+}
+
+// ExtensionPrefix marks top-level keys that are never reported as unknown,
+// e.g. for YAML anchors ("x-common: &common").
+const ExtensionPrefix = "x-"
+
+// valueKind is the shape a pilum.yaml key's value must have.
+type valueKind int
+
+const (
+	kindString       valueKind = iota // Scalar
+	kindStringList                    // List of scalars
+	kindStringMap                     // Mapping of scalars
+	kindGlobList                      // List of globs
+	kindMapping                       // Mapping of anything
+	kindBuild                         // The build block
+	kindRuntime                       // The runtime block
+	kindEnvironments                  // Mapping of override mappings
+	kindTargets                       // List of override mappings
+)
+
+var coreKinds = map[string]valueKind{
+	"name":          kindString,
+	"description":   kindString,
+	"type":          kindString,
+	"template":      kindString,
+	"recipe":        kindString,
+	"provider":      kindString,
+	"region":        kindString,
+	"regions":       kindStringList,
+	"project":       kindString,
+	"license":       kindString,
+	"registry_name": kindString,
+	"depends_on":    kindStringList,
+	"build":         kindBuild,
+	"runtime":       kindRuntime,
+	"env_vars":      kindStringMap,
+	"secrets":       kindStringMap,
+	"labels":        kindStringMap,
+	"watch_paths":   kindGlobList,
+	EnvironmentsKey: kindEnvironments,
+	TargetsKey:      kindTargets,
+}
+
+var buildKinds = map[string]valueKind{
+	"language":    kindString,
+	"version":     kindString,
+	"cmd":         kindString,
+	"version_var": kindString,
+	"env_vars":    kindStringMap,
+	"flags":       kindMapping,
+}
+
+// DecodeOptions configures Decode.
+type DecodeOptions struct {
+	// KnownKeys are top-level keys accepted in addition to CoreKeys. If nil,
+	// unknown top-level keys are not reported.
+	KnownKeys []string
+}
+
+// Decode parses a pilum.yaml and checks it against the service schema. It
+// returns the config and every problem found; the config is nil if there
+// are any problems.
+func Decode(data []byte, path string, opts DecodeOptions) (map[string]any, []Problem) {
+	d := &decoder{path: path, opts: opts}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		d.addAt(syntaxErrorLine(err), 1, "error parsing YAML: %s", err.Error())
+		return nil, d.problems
+	}
+	if len(doc.Content) == 0 {
+		d.addAt(1, 1, "file is empty")
+		return nil, d.problems
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		d.add(root, "pilum.yaml must be a mapping of keys to values")
+		return nil, d.problems
+	}
+
+	d.checkService(root)
+	if len(d.problems) > 0 {
+		return nil, d.sorted()
+	}
+
+	var config map[string]any
+	if err := root.Decode(&config); err != nil {
+		d.add(root, "%s", err.Error())
+		return nil, d.problems
+	}
+	return config, nil
+}
+
+// decoder accumulates problems for a single file.
+type decoder struct {
+	path     string
+	opts     DecodeOptions
+	problems []Problem
+}
+
+func (d *decoder) add(node *yaml.Node, msg string, args ...any) {
+	d.addAt(node.Line, node.Column, msg, args...)
+}
+
+func (d *decoder) addAt(line, column int, msg string, args ...any) {
+	d.problems = append(d.problems, Problem{Path: d.path, Line: line, Column: column, Message: fmt.Sprintf(msg, args...)})
+}
+
+func (d *decoder) sorted() []Problem {
+	sort.SliceStable(d.problems, func(i, j int) bool {
+		if d.problems[i].Line != d.problems[j].Line {
+			return d.problems[i].Line < d.problems[j].Line
+		}
+		return d.problems[i].Column < d.problems[j].Column
+	})
+	return d.problems
+}
+
+// checkService checks the top-level mapping of a pilum.yaml.
+func (d *decoder) checkService(root *yaml.Node) {
+	d.checkMapping(root, nil)
+
+	name := mappingValue(root, "name")
+	if name == nil || name.Tag == "!!null" || (name.Kind == yaml.ScalarNode && strings.TrimSpace(name.Value) == "") {
+		d.add(root, "missing required field: name")
+	}
+}
+
+// overlay describes a mapping whose keys override the service's: an
+// environment or a target.
+type overlay struct {
+	prefix   string          // Prefix of its keys in messages, e.g. "environments.prod."
+	reserved map[string]bool // Keys it may not set
+	reason   string          // Why, e.g. "cannot be overridden per environment"
+}
+
+// checkMapping checks the keys of a service mapping, or of an overlay's
+// overrides when ov is set.
+func (d *decoder) checkMapping(node *yaml.Node, ov *overlay) {
+	d.checkDuplicateKeys(node)
+
+	var known []string
+	if d.opts.KnownKeys != nil {
+		for _, key := range append(append([]string{}, CoreKeys...), d.opts.KnownKeys...) {
+			if !slices.Contains(known, key) {
+				known = append(known, key)
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		key := keyNode.Value
+		if ov != nil {
+			key = ov.prefix + keyNode.Value
+		}
+
+		kind, isCore := coreKinds[keyNode.Value]
+		switch {
+		case keyNode.Tag == "!!merge":
+			// "<<: *anchor" merges another mapping
+		case ov != nil && ov.reserved[keyNode.Value]:
+			d.add(keyNode, "%s %s", key, ov.reason)
+		case isCore:
+			d.checkValue(valueNode, kind, key)
+		case known != nil && !strings.HasPrefix(keyNode.Value, ExtensionPrefix) && !slices.Contains(known, keyNode.Value):
+			msg := fmt.Sprintf("unknown key '%s'", key)
+			if suggestion := suggest.FormatSuggestion(keyNode.Value, known); suggestion != "" {
+				msg += " - " + suggestion
+			}
+			d.add(keyNode, "%s", msg)
+		default:
+			d.checkNested(valueNode)
+		}
+	}
+}
+
+// checkValue checks that a value has the shape its key requires.
+func (d *decoder) checkValue(node *yaml.Node, kind valueKind, key string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Tag == "!!null" {
+		return
+	}
+
+	switch kind {
+	case kindString:
+		if node.Kind != yaml.ScalarNode {
+			d.add(node, "%s must be a string", key)
+		}
+	case kindStringList:
+		if node.Kind != yaml.SequenceNode {
+			d.add(node, "%s must be a list of strings", key)
+			return
+		}
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				d.add(item, "%s must be a list of strings", key)
+			}
+		}
+	case kindGlobList:
+		d.checkValue(node, kindStringList, key)
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				continue
+			}
+			if _, err := gitignore.CompileGlob(item.Value); err != nil {
+				d.add(item, "%s[%d] is not a valid glob: '%s'", key, i, item.Value)
+			}
+		}
+	case kindStringMap:
+		if d.requireMapping(node, key) {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				d.checkValue(node.Content[i+1], kindString, key+"."+node.Content[i].Value)
+			}
+		}
+	case kindMapping:
+		if d.requireMapping(node, key) {
+			d.checkNested(node)
+		}
+	case kindBuild:
+		if d.requireMapping(node, key) {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode, valueNode := node.Content[i], node.Content[i+1]
+				if sub, ok := buildKinds[keyNode.Value]; ok {
+					d.checkValue(valueNode, sub, key+"."+keyNode.Value)
+				} else {
+					d.checkNested(valueNode)
+				}
+			}
+		}
+	case kindRuntime:
+		if d.requireMapping(node, key) {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode, valueNode := node.Content[i], node.Content[i+1]
+				if keyNode.Value == "service" {
+					d.checkValue(valueNode, kindString, key+".service")
+				} else {
+					d.checkNested(valueNode)
+				}
+			}
+		}
+	case kindEnvironments:
+		if d.requireMapping(node, key) {
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				keyNode, valueNode := node.Content[i], node.Content[i+1]
+				if valueNode.Tag == "!!null" {
+					continue
+				}
+				if valueNode.Kind != yaml.MappingNode {
+					d.add(valueNode, "%s.%s must be a mapping of overrides", key, keyNode.Value)
+					continue
+				}
+				d.checkMapping(valueNode, &overlay{
+					prefix:   key + "." + keyNode.Value + ".",
+					reserved: map[string]bool{"name": true, EnvironmentsKey: true, TargetsKey: true},
+					reason:   "cannot be overridden per environment",
+				})
+			}
+		}
+	case kindTargets:
+		d.checkTargets(node, key)
+	}
+}
+
+// checkTargets checks a targets list: each entry is a mapping of overrides
+// whose name (given, or derived from its type) is unique.
+func (d *decoder) checkTargets(node *yaml.Node, key string) {
+	if node.Kind != yaml.SequenceNode {
+		d.add(node, "%s must be a list of targets", key)
+		return
+	}
+
+	seen := make(map[string]*yaml.Node)
+	for i, entry := range node.Content {
+		if entry.Kind == yaml.AliasNode {
+			entry = entry.Alias
+		}
+		prefix := fmt.Sprintf("%s[%d]", key, i)
+		if entry.Kind != yaml.MappingNode {
+			d.add(entry, "%s must be a mapping of overrides", prefix)
+			continue
+		}
+		d.checkMapping(entry, &overlay{
+			prefix:   prefix + ".",
+			reserved: map[string]bool{EnvironmentsKey: true, TargetsKey: true},
+			reason:   "cannot be set per target",
+		})
+
+		var fields map[string]any
+		if err := entry.Decode(&fields); err != nil {
+			continue // Reported by checkMapping
+		}
+		name := TargetName(fields)
+		if name == "" {
+			d.add(entry, "%s needs a name or a type", prefix)
+			continue
+		}
+		if first, exists := seen[name]; exists {
+			d.add(entry, "target '%s' already defined at line %d", name, first.Line)
+			continue
+		}
+		seen[name] = entry
+	}
+}
+
+func (d *decoder) requireMapping(node *yaml.Node, key string) bool {
+	if node.Kind != yaml.MappingNode {
+		d.add(node, "%s must be a mapping", key)
+		return false
+	}
+	d.checkDuplicateKeys(node)
+	return true
+}
+
+// checkNested reports duplicate keys anywhere beneath node.
+func (d *decoder) checkNested(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		d.checkDuplicateKeys(node)
+		for i := 1; i < len(node.Content); i += 2 {
+			d.checkNested(node.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			d.checkNested(item)
+		}
+	default:
+	}
+}
+
+// checkDuplicateKeys reports keys that appear more than once in a mapping.
+func (d *decoder) checkDuplicateKeys(node *yaml.Node) {
+	seen := make(map[string]*yaml.Node)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		if first, exists := seen[keyNode.Value]; exists {
+			d.add(keyNode, "key '%s' already defined at line %d", keyNode.Value, first.Line)
+			continue
+		}
+		seen[keyNode.Value] = keyNode
+	}
+}
+
+// mappingValue returns the value node for key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+var syntaxLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// syntaxErrorLine extracts the line number from a YAML syntax error, or 1.
+func syntaxErrorLine(err error) int {
+	if m := syntaxLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		if line, convErr := strconv.Atoi(m[1]); convErr == nil {
+			return line
+		}
+	}
+	return 1
+}
@@ -0,0 +1,186 @@
+package serviceinfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeValid(t *testing.T) {
+	t.Parallel()
+
+	content := `name: api
+provider: gcp
+regions: [us-east1, europe-west1]
+env_vars:
+  PORT: 8080
+build:
+  language: go
+  flags:
+    ldflags: ["-s", "-w"]
+x-common: &common
+  memory: 512Mi
+cloud_run:
+  <<: *common
+  cpu: 1
+environments:
+  prod:
+    project: acme-prod
+`
+	config, problems := serviceinfo.Decode([]byte(content), "pilum.yaml", serviceinfo.DecodeOptions{KnownKeys: []string{"cloud_run"}})
+	require.Empty(t, problems)
+	require.Equal(t, "api", config["name"])
+	require.Equal(t, []any{"us-east1", "europe-west1"}, config["regions"])
+	require.Equal(t, map[string]any{"memory": "512Mi", "cpu": 1}, config["cloud_run"])
+
+	svc := serviceinfo.NewServiceInfo(config, ".")
+	require.NotNil(t, svc)
+	require.Equal(t, []serviceinfo.EnvVars{{Name: "PORT", Value: "8080"}}, svc.EnvVars)
+}
+
+func TestDecodeProblems(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		content  string
+		opts     serviceinfo.DecodeOptions
+		expected []string
+	}{
+		{
+			name:     "syntax error",
+			content:  "name: api\nprovider: [gcp\n",
+			expected: []string{"error parsing YAML: yaml: line"},
+		},
+		{
+			name:     "empty file",
+			content:  "",
+			expected: []string{"pilum.yaml:1:1: file is empty"},
+		},
+		{
+			name:     "not a mapping",
+			content:  "- name: api\n",
+			expected: []string{"pilum.yaml:1:1: pilum.yaml must be a mapping"},
+		},
+		{
+			name:     "missing name",
+			content:  "provider: gcp\n",
+			expected: []string{"pilum.yaml:1:1: missing required field: name"},
+		},
+		{
+			name:     "empty name",
+			content:  "name: \"\"\nprovider: gcp\n",
+			expected: []string{"pilum.yaml:1:1: missing required field: name"},
+		},
+		{
+			name:    "wrong types",
+			content: "name: api\nregion: [us-east1]\ndepends_on: db\nsecrets:\n  TOKEN: {from: vault}\nbuild: go\n",
+			expected: []string{
+				"pilum.yaml:2:9: region must be a string",
+				"pilum.yaml:3:13: depends_on must be a list of strings",
+				"pilum.yaml:5:10: secrets.TOKEN must be a string",
+				"pilum.yaml:6:8: build must be a mapping",
+			},
+		},
+		{
+			name:     "watch paths",
+			content:  "name: api\nwatch_paths: [libs/**, \"libs/[z-a]\"]\n",
+			expected: []string{"pilum.yaml:2:24: watch_paths[1] is not a valid glob: 'libs/[z-a]'"},
+		},
+		{
+			name:     "nested build type",
+			content:  "name: api\nbuild:\n  env_vars: [CGO_ENABLED=0]\n",
+			expected: []string{"pilum.yaml:3:13: build.env_vars must be a mapping"},
+		},
+		{
+			name:     "duplicate keys",
+			content:  "name: api\nregion: a\ncloud_run:\n  cpu: 1\n  cpu: 2\nregion: b\n",
+			expected: []string{"pilum.yaml:5:3: key 'cpu' already defined at line 4", "pilum.yaml:6:1: key 'region' already defined at line 2"},
+		},
+		{
+			name:     "unknown key",
+			content:  "name: api\nregoin: us-east1\nclod_run: {}\n",
+			opts:     serviceinfo.DecodeOptions{KnownKeys: []string{"cloud_run"}},
+			expected: []string{"pilum.yaml:2:1: unknown key 'regoin' - did you mean", "pilum.yaml:3:1: unknown key 'clod_run' - did you mean 'cloud_run'?"},
+		},
+		{
+			name:     "environment overrides",
+			content:  "name: api\nenvironments:\n  prod:\n    name: api-prod\n    region: [a]\n  dev: staging\n",
+			expected: []string{"pilum.yaml:4:5: environments.prod.name cannot be overridden", "pilum.yaml:5:13: environments.prod.region must be a string", "pilum.yaml:6:8: environments.dev must be a mapping"},
+		},
+		{
+			name:    "unknown override keys",
+			content: "name: api\nenvironments:\n  prod:\n    clod_run: {}\ntargets:\n  - type: gcp-cloud-run\n    regoin: us-east1\n",
+			opts:    serviceinfo.DecodeOptions{KnownKeys: []string{"cloud_run"}},
+			expected: []string{
+				"pilum.yaml:4:5: unknown key 'environments.prod.clod_run' - did you mean 'cloud_run'?",
+				"pilum.yaml:7:5: unknown key 'targets[0].regoin' - did you mean",
+			},
+		},
+		{
+			name:     "targets not a list",
+			content:  "name: api\ntargets:\n  lambda: {}\n",
+			expected: []string{"pilum.yaml:3:3: targets must be a list of targets"},
+		},
+		{
+			name:    "invalid targets",
+			content: "name: api\ntargets:\n  - aws-lambda\n  - region: us-east1\n  - type: gcp-cloud-run\n    environments: {}\n  - type: gcp-cloud-run\n    region: [a]\n",
+			expected: []string{
+				"pilum.yaml:3:5: targets[0] must be a mapping of overrides",
+				"pilum.yaml:4:5: targets[1] needs a name or a type",
+				"pilum.yaml:6:5: targets[2].environments cannot be set per target",
+				"pilum.yaml:7:5: target 'cloud-run' already defined at line 5",
+				"pilum.yaml:8:13: targets[3].region must be a string",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			config, problems := serviceinfo.Decode([]byte(tt.content), "pilum.yaml", tt.opts)
+			require.Nil(t, config)
+			require.Len(t, problems, len(tt.expected), "%v", problems)
+			for i, expected := range tt.expected {
+				require.Contains(t, problems[i].String(), expected)
+			}
+		})
+	}
+}
+
+func TestDecodeUnknownKeysOnlyWhenKnown(t *testing.T) {
+	t.Parallel()
+
+	config, problems := serviceinfo.Decode([]byte("name: api\ncustom: value\n"), "pilum.yaml", serviceinfo.DecodeOptions{})
+	require.Empty(t, problems)
+	require.Equal(t, "value", config["custom"])
+}
+
+func TestFindServicesCollectsProblems(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	writeService(t, tmpDir, "good", "name: good\nprovider: gcp\n")
+	writeService(t, tmpDir, "bad1", "provider: gcp\n")
+	writeService(t, tmpDir, "bad2", "name: bad2\nregions: us-east1\n")
+
+	services, err := serviceinfo.FindServices(tmpDir)
+	require.Error(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "good", services[0].Name)
+
+	var problems serviceinfo.Problems
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 2)
+}
+
+func writeService(t *testing.T, root, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, dir), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, dir, "pilum.yaml"), []byte(content), 0644))
+}
@@ -0,0 +1,44 @@
+package serviceinfo
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// GroupPrefix marks a service name argument as a workspace group, e.g. @backend.
+const GroupPrefix = "@"
+
+// ApplyDefaults returns config deep-merged over the workspace defaults, so
+// any value set in pilum.yaml wins. Setting region replaces default regions
+// and vice versa, as do the keys that choose the recipe.
+func ApplyDefaults(config, defaults map[string]any) map[string]any {
+	if len(defaults) == 0 {
+		return config
+	}
+	return mergeOverrides(defaults, config)
+}
+
+// groupMembers returns the members of a workspace group. within lists the
+// groups being resolved, to catch groups that include themselves.
+func groupMembers(group string, groups map[string][]string, within []string) ([]string, error) {
+	members, exists := groups[group]
+	if !exists {
+		known := make([]string, 0, len(groups))
+		for g := range groups {
+			known = append(known, GroupPrefix+g)
+		}
+		sort.Strings(known)
+		if suggestion := suggest.FormatSuggestion(GroupPrefix+group, known); suggestion != "" {
+			return nil, errors.New("group '%s' is not defined in pilum.workspace.yaml - %s", group, suggestion)
+		}
+		return nil, errors.New("group '%s' is not defined in pilum.workspace.yaml", group)
+	}
+	if slices.Contains(within, group) {
+		return nil, errors.New("group '%s' includes itself (%s)", group, strings.Join(append(within, group), " -> "))
+	}
+	return members, nil
+}
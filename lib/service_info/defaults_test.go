@@ -0,0 +1,73 @@
+package serviceinfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDefaults(t *testing.T) {
+	t.Parallel()
+
+	defaults := map[string]any{
+		"project": "acme",
+		"region":  "us-central1",
+		"build":   map[string]any{"language": "go", "version": "1.23"},
+	}
+	config := map[string]any{
+		"name":   "api",
+		"region": "europe-west1",
+		"build":  map[any]any{"version": "1.24"},
+	}
+
+	merged := serviceinfo.ApplyDefaults(config, defaults)
+	require.Equal(t, "acme", merged["project"])
+	require.Equal(t, "europe-west1", merged["region"])
+	require.Equal(t, map[string]any{"language": "go", "version": "1.24"}, merged["build"])
+
+	require.Equal(t, config, serviceinfo.ApplyDefaults(config, nil))
+
+	// region and regions replace each other
+	merged = serviceinfo.ApplyDefaults(config, map[string]any{"regions": []any{"us-central1", "us-east1"}})
+	require.Equal(t, "europe-west1", merged["region"])
+	require.NotContains(t, merged, "regions")
+	merged = serviceinfo.ApplyDefaults(map[string]any{"name": "api", "regions": []any{"us-east1"}}, defaults)
+	require.Equal(t, []any{"us-east1"}, merged["regions"])
+	require.NotContains(t, merged, "region")
+}
+
+func TestFindServicesWithDefaults(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	for name, content := range map[string]string{
+		"api":    "name: api\nprovider: gcp\nregion: europe-west1\n",
+		"worker": "name: worker\nprovider: gcp\n",
+	} {
+		dir := filepath.Join(tmpDir, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "pilum.yaml"), []byte(content), 0644))
+	}
+
+	opts := serviceinfo.FilterOptions{
+		Names:    []string{"@backend"},
+		Defaults: map[string]any{"project": "acme", "region": "us-central1"},
+		Groups:   map[string][]string{"backend": {"api", "worker"}},
+	}
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	byName := map[string]serviceinfo.ServiceInfo{}
+	for _, svc := range services {
+		byName[svc.Name] = svc
+	}
+	require.Equal(t, "acme", byName["api"].Project)
+	require.Equal(t, "europe-west1", byName["api"].Region)
+	require.Equal(t, "us-central1", byName["worker"].Region)
+	require.Equal(t, filepath.Join(tmpDir, "api", "pilum.yaml"), byName["api"].File)
+}
@@ -0,0 +1,79 @@
+package serviceinfo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+)
+
+// CommandDetectorName is the name of CommandDetector.
+const CommandDetectorName = "command"
+
+// CommandDetectorTimeout bounds how long a CommandDetector's command may run.
+const CommandDetectorTimeout = 5 * time.Minute
+
+// CommandDetector asks another tool, such as an Nx or Bazel query, which
+// services changed. The command runs with sh -c in the project root, with
+// the changed files on stdin (one per line) and the ref in $PILUM_SINCE
+// (empty for the default branch), and prints the names of the changed
+// services, one per line. Blank lines, lines starting with # and names that
+// aren't services are ignored.
+type CommandDetector struct {
+	Command string
+	Since   string
+}
+
+// Name implements ChangeDetector.
+func (CommandDetector) Name() string {
+	return CommandDetectorName
+}
+
+// Detect implements ChangeDetector. The command can't say which files
+// changed a service, so services are mapped to nil.
+func (d CommandDetector) Detect(services []ServiceInfo, changedFiles []string) (map[string][]string, error) {
+	if strings.TrimSpace(d.Command) == "" {
+		return nil, errors.New("empty command")
+	}
+
+	timeout, cancel := context.WithTimeout(context.Background(), CommandDetectorTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(timeout, "sh", "-c", d.Command) //nolint:gosec // Command comes from trusted pilum.workspace.yaml
+	cmd.Env = append(os.Environ(), "PILUM_SINCE="+d.Since)
+	cmd.Stdin = strings.NewReader(strings.Join(changedFiles, "\n") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrap(err, "command failed: "+msg)
+		}
+		return nil, errors.Wrap(err, "command failed")
+	}
+
+	known := make(map[string]bool, len(services))
+	for _, svc := range services {
+		known[svc.Name] = true
+	}
+
+	changed := make(map[string][]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		switch {
+		case name == "" || strings.HasPrefix(name, "#"):
+		case !known[name]:
+			output.Debugf("Change detector command reported unknown service %s", name)
+		default:
+			changed[name] = nil
+		}
+	}
+	return changed, nil
+}
@@ -0,0 +1,158 @@
+package serviceinfo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+)
+
+// GoDetectorName is the name of GoDetector.
+const GoDetectorName = "go"
+
+// goModuleFiles change the dependencies of every package in a module.
+var goModuleFiles = []string{"go.mod", "go.sum", "go.work", "go.work.sum"}
+
+// GoDetector follows Go imports, for services built from packages outside
+// their own directory. A changed file changes the package in its directory;
+// a service is changed if one of the main packages in its directory imports
+// a changed package, directly or not. A change to go.mod, go.sum or go.work
+// changes every service with a main package. Test files are ignored.
+type GoDetector struct {
+	// Dir is where go list runs, relative to the project root ("" for the
+	// root). Packages are listed with the ./... pattern.
+	Dir string
+}
+
+// goPackage is the part of go list's output the detector uses.
+type goPackage struct {
+	Dir        string
+	ImportPath string
+	Name       string
+	Deps       []string
+}
+
+// Name implements ChangeDetector.
+func (GoDetector) Name() string {
+	return GoDetectorName
+}
+
+// Detect implements ChangeDetector.
+func (d GoDetector) Detect(services []ServiceInfo, changedFiles []string) (map[string][]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get working directory")
+	}
+
+	packages, err := listGoPackages(d.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range packages {
+		packages[i].Dir = relativeTo(wd, packages[i].Dir)
+	}
+	return goChanges(packages, relativeTo(wd, d.Dir), services, changedFiles), nil
+}
+
+// relativeTo returns p as a slash path relative to dir, if it can be.
+func relativeTo(dir, p string) string {
+	if filepath.IsAbs(p) {
+		if rel, err := filepath.Rel(dir, p); err == nil {
+			p = rel
+		}
+	}
+	return path.Clean(filepath.ToSlash(p))
+}
+
+// listGoPackages runs go list in dir.
+func listGoPackages(dir string) ([]goPackage, error) {
+	cmd := exec.Command("go", "list", "-e", "-json=Dir,ImportPath,Name,Deps", "./...")
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.Wrap(err, "go list failed: "+msg)
+		}
+		return nil, errors.Wrap(err, "go list failed")
+	}
+
+	var packages []goPackage
+	decoder := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var pkg goPackage
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.Wrap(err, "failed to parse go list output")
+		}
+		packages = append(packages, pkg)
+	}
+	return packages, nil
+}
+
+// goChanges maps the changed files to the services whose main packages
+// depend on them. moduleDir is where the module files are (slash path, "."
+// for the root).
+func goChanges(packages []goPackage, moduleDir string, services []ServiceInfo, changedFiles []string) map[string][]string {
+	byDir := make(map[string]goPackage, len(packages))
+	for _, pkg := range packages {
+		byDir[pkg.Dir] = pkg
+	}
+
+	// Files of changed packages, by import path, and changed module files
+	changedPackages := make(map[string][]string)
+	var moduleChanges []string
+	for _, file := range changedFiles {
+		dir, name := path.Split(file)
+		dir = path.Clean(dir)
+		if dir == moduleDir && slices.Contains(goModuleFiles, name) {
+			moduleChanges = append(moduleChanges, file)
+			continue
+		}
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if pkg, ok := byDir[dir]; ok {
+			changedPackages[pkg.ImportPath] = append(changedPackages[pkg.ImportPath], file)
+		}
+	}
+
+	changed := make(map[string][]string)
+	for _, svc := range services {
+		if _, done := changed[svc.Name]; done {
+			continue
+		}
+
+		svcDir := path.Clean(filepath.ToSlash(svc.Path))
+		var files []string
+		for _, pkg := range packages {
+			if pkg.Name != "main" || (svcDir != "." && !withinAny(pkg.Dir, []string{svcDir})) {
+				continue
+			}
+			files = append(files, moduleChanges...)
+			for _, imported := range append([]string{pkg.ImportPath}, pkg.Deps...) {
+				files = append(files, changedPackages[imported]...)
+			}
+		}
+
+		var unique []string
+		for _, file := range files {
+			if !slices.Contains(unique, file) {
+				unique = append(unique, file)
+			}
+		}
+		if len(unique) > 0 {
+			changed[svc.Name] = unique
+		}
+	}
+	return changed
+}
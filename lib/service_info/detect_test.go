@@ -0,0 +1,122 @@
+package serviceinfo_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeFiles writes files (slash paths to contents) under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(file), 0o755))
+		require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+	}
+}
+
+func TestGoDetector(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                      "module example.com/shop\n\ngo 1.21\n",
+		"libs/db/db.go":               "package db\n\nfunc Open() {}\n",
+		"libs/db/db_test.go":          "package db\n",
+		"libs/auth/auth.go":           "package auth\n\nimport \"example.com/shop/libs/db\"\n\nfunc Check() { db.Open() }\n",
+		"libs/log/log.go":             "package log\n\nfunc Print() {}\n",
+		"services/api/main.go":        "package main\n\nimport \"example.com/shop/libs/auth\"\n\nfunc main() { auth.Check() }\n",
+		"services/worker/cmd/main.go": "package main\n\nimport \"example.com/shop/libs/log\"\n\nfunc main() { log.Print() }\n",
+		"services/web/index.ts":       "export {}\n",
+	})
+
+	// Paths are relative to the working directory, as the project root would be
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	rel, err := filepath.Rel(wd, dir)
+	require.NoError(t, err)
+	root := filepath.ToSlash(rel)
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Path: path.Join(root, "services/api")},
+		{Name: "worker", Path: path.Join(root, "services/worker")},
+		{Name: "web", Path: path.Join(root, "services/web")},
+	}
+	detector := serviceinfo.GoDetector{Dir: dir}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected map[string][]string
+	}{
+		{
+			name:     "imported indirectly",
+			files:    []string{"libs/db/db.go", "libs/db/db_test.go"},
+			expected: map[string][]string{"api": {"libs/db/db.go"}},
+		},
+		{
+			name:     "main package",
+			files:    []string{"services/worker/cmd/main.go", "libs/log/README.md"},
+			expected: map[string][]string{"worker": {"services/worker/cmd/main.go", "libs/log/README.md"}},
+		},
+		{
+			name:     "module files",
+			files:    []string{"go.sum", "libs/go.mod"},
+			expected: map[string][]string{"api": {"go.sum"}, "worker": {"go.sum"}},
+		},
+		{
+			name:     "not a package",
+			files:    []string{"services/web/index.ts", "docs/db.go"},
+			expected: map[string][]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			files := make([]string, len(tt.files))
+			for i, file := range tt.files {
+				files[i] = path.Join(root, file)
+			}
+			expected := make(map[string][]string)
+			for name, blamed := range tt.expected {
+				for _, file := range blamed {
+					expected[name] = append(expected[name], path.Join(root, file))
+				}
+			}
+
+			changed, err := detector.Detect(services, files)
+			require.NoError(t, err)
+			require.Equal(t, expected, changed)
+		})
+	}
+}
+
+func TestCommandDetector(t *testing.T) {
+	t.Parallel()
+
+	services := []serviceinfo.ServiceInfo{
+		{Name: "api", Path: "services/api"},
+		{Name: "worker", Path: "services/worker"},
+	}
+
+	// The changed files arrive on stdin, the ref in $PILUM_SINCE
+	detector := serviceinfo.CommandDetector{
+		Command: `printf '# affected since %s\n\n' "$PILUM_SINCE"; grep -q libs/queue && echo worker; echo docs`,
+		Since:   "origin/main",
+	}
+	changed, err := detector.Detect(services, []string{"libs/queue/queue.go"})
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"worker": nil}, changed)
+
+	_, err = serviceinfo.CommandDetector{Command: "echo broken >&2; exit 1"}.Detect(services, nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "broken")
+}
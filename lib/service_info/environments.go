@@ -0,0 +1,93 @@
+package serviceinfo
+
+import (
+	"sort"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// EnvironmentsKey is the pilum.yaml block holding per-environment overrides:
+//
+//	project: acme-dev
+//	environments:
+//	  prod:
+//	    project: acme-prod
+//	    cloud_run:
+//	      min_instances: 2
+const EnvironmentsKey = "environments"
+
+// DeclaredEnvironments returns the environment names in a config's
+// environments block, sorted.
+func DeclaredEnvironments(config map[string]any) []string {
+	block := configutil.MapFromAny(config[EnvironmentsKey])
+	names := make([]string, 0, len(block))
+	for name := range block {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ApplyEnvironment returns the effective config for env: the base config,
+// then the shared overrides for env (from pilum.workspace.yaml), then the
+// service's own overrides, deep-merged in that order. Setting region
+// replaces regions and vice versa, as do the keys that choose the recipe.
+// The environments block is removed. With an empty env, only the block is
+// removed.
+func ApplyEnvironment(config map[string]any, env string, shared map[string]map[string]any) (map[string]any, error) {
+	var block map[string]any
+	switch raw := config[EnvironmentsKey].(type) {
+	case nil:
+	case map[string]any, map[any]any:
+		block = configutil.MapFromAny(raw)
+	default:
+		return nil, errors.New("environments must be a mapping of environment names to overrides")
+	}
+	for name, overrides := range block {
+		switch overrides.(type) {
+		case map[string]any, map[any]any, nil:
+		default:
+			return nil, errors.New("environments.%s must be a mapping of overrides", name)
+		}
+	}
+
+	base := configutil.CloneMap(config)
+	delete(base, EnvironmentsKey)
+	if env == "" {
+		return base, nil
+	}
+
+	merged := mergeOverrides(base, shared[env])
+	merged = mergeOverrides(merged, configutil.MapFromAny(block[env]))
+	delete(merged, EnvironmentsKey)
+	return merged, nil
+}
+
+// checkEnvironment returns an error if env isn't declared by any service or
+// by the shared environments.
+func checkEnvironment(env string, declared map[string]bool, shared map[string]map[string]any) error {
+	if env == "" || declared[env] {
+		return nil
+	}
+	if _, ok := shared[env]; ok {
+		return nil
+	}
+
+	names := make([]string, 0, len(declared)+len(shared))
+	for name := range declared {
+		names = append(names, name)
+	}
+	for name := range shared {
+		if !declared[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	if suggestion := suggest.FormatSuggestion(env, names); suggestion != "" {
+		return errors.New("environment '%s' is not declared in any pilum.yaml or pilum.workspace.yaml - %s", env, suggestion)
+	}
+	return errors.New("environment '%s' is not declared in any pilum.yaml or pilum.workspace.yaml", env)
+}
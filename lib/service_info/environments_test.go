@@ -0,0 +1,178 @@
+package serviceinfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyEnvironment(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":    "api",
+		"project": "acme-dev",
+		"cloud_run": map[string]any{
+			"min_instances": 0,
+			"max_instances": 5,
+		},
+		"environments": map[string]any{
+			"prod": map[string]any{
+				"project":   "acme-prod",
+				"cloud_run": map[string]any{"min_instances": 2},
+			},
+		},
+	}
+	shared := map[string]map[string]any{
+		"prod": {"region": "europe-west1", "project": "shared-prod"},
+	}
+
+	merged, err := serviceinfo.ApplyEnvironment(config, "prod", shared)
+	require.NoError(t, err)
+	require.Equal(t, "acme-prod", merged["project"])
+	require.Equal(t, "europe-west1", merged["region"])
+	require.Equal(t, map[string]any{"min_instances": 2, "max_instances": 5}, merged["cloud_run"])
+	require.NotContains(t, merged, "environments")
+
+	// The input config is left untouched.
+	require.Equal(t, "acme-dev", config["project"])
+
+	base, err := serviceinfo.ApplyEnvironment(config, "", shared)
+	require.NoError(t, err)
+	require.Equal(t, "acme-dev", base["project"])
+	require.NotContains(t, base, "region")
+	require.NotContains(t, base, "environments")
+}
+
+func TestApplyEnvironmentRegion(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":    "api",
+		"regions": []any{"us-central1", "us-east1"},
+		"environments": map[string]any{
+			"dev":  map[string]any{"region": "us-central1"},
+			"prod": map[string]any{},
+		},
+	}
+
+	// An environment's region replaces the base regions
+	merged, err := serviceinfo.ApplyEnvironment(config, "dev", nil)
+	require.NoError(t, err)
+	require.Equal(t, "us-central1", merged["region"])
+	require.NotContains(t, merged, "regions")
+
+	// and shared regions replace the base region
+	config["region"] = "us-central1"
+	delete(config, "regions")
+	merged, err = serviceinfo.ApplyEnvironment(config, "prod", map[string]map[string]any{"prod": {"regions": []any{"europe-west1"}}})
+	require.NoError(t, err)
+	require.Equal(t, []any{"europe-west1"}, merged["regions"])
+	require.NotContains(t, merged, "region")
+}
+
+func TestApplyEnvironmentInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := serviceinfo.ApplyEnvironment(map[string]any{"environments": []any{"prod"}}, "prod", nil)
+	require.Error(t, err)
+
+	_, err = serviceinfo.ApplyEnvironment(map[string]any{"environments": map[string]any{"prod": "acme"}}, "prod", nil)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "environments.prod")
+}
+
+func TestDeclaredEnvironments(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"environments": map[string]any{"staging": nil, "prod": map[string]any{}},
+	}
+	require.Equal(t, []string{"prod", "staging"}, serviceinfo.DeclaredEnvironments(config))
+	require.Empty(t, serviceinfo.DeclaredEnvironments(map[string]any{}))
+}
+
+func TestFindServicesWithEnvironment(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	svcDir := filepath.Join(tmpDir, "api")
+	require.NoError(t, os.MkdirAll(svcDir, 0755))
+	content := `name: api
+provider: gcp
+project: acme-dev
+environments:
+  staging:
+    project: acme-staging
+  prod:
+    project: acme-prod
+`
+	require.NoError(t, os.WriteFile(filepath.Join(svcDir, "pilum.yaml"), []byte(content), 0644))
+
+	opts := serviceinfo.DefaultDiscoveryOptions()
+	opts.Environment = "prod"
+	services, err := serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "acme-prod", services[0].Project)
+	require.Equal(t, "prod", services[0].Environment)
+	require.Equal(t, []string{"prod", "staging"}, services[0].Environments)
+	require.NotContains(t, services[0].Config, "environments")
+
+	services, err = serviceinfo.FindServicesWithOptions(tmpDir, serviceinfo.DefaultDiscoveryOptions())
+	require.NoError(t, err)
+	require.Equal(t, "acme-dev", services[0].Project)
+	require.Empty(t, services[0].Environment)
+}
+
+func TestFindServicesUndeclaredEnvironment(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	content := `name: api
+provider: gcp
+environments:
+  prod:
+    project: acme-prod
+`
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pilum.yaml"), []byte(content), 0644))
+
+	opts := serviceinfo.DefaultDiscoveryOptions()
+	opts.Environment = "prd"
+	_, err := serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "environment 'prd' is not declared")
+	require.Contains(t, err.Error(), "prod")
+
+	// Environments declared only in the workspace are accepted.
+	opts.Environment = "qa"
+	opts.Environments = map[string]map[string]any{"qa": {"project": "acme-qa"}}
+	services, err := serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Equal(t, "acme-qa", services[0].Project)
+}
+
+func TestFindServicesReportsEnvironmentProblems(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"api", "worker"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name, "pilum.yaml"), []byte("name: "+name+"\nprovider: gcp\n"), 0644))
+	}
+
+	// Workspace defaults with a broken environments block fail every
+	// service, as a problem rather than aborting discovery
+	opts := serviceinfo.DefaultDiscoveryOptions()
+	opts.Defaults = map[string]any{"environments": "prod"}
+	services, err := serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.Empty(t, services)
+	var problems serviceinfo.Problems
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 2)
+	require.Equal(t, filepath.Join(tmpDir, "api", "pilum.yaml")+":1:1: environments must be a mapping of environment names to overrides", problems[0].String())
+}
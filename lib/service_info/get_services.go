@@ -1,121 +1,215 @@
 package serviceinfo
 
 import (
-	"bufio"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/sid-technologies/pilum/lib/errors"
 	"github.com/sid-technologies/pilum/lib/git"
+	"github.com/sid-technologies/pilum/lib/gitignore"
 	"github.com/sid-technologies/pilum/lib/graph"
 	"github.com/sid-technologies/pilum/lib/output"
-	"github.com/sid-technologies/pilum/lib/suggest"
-
-	"gopkg.in/yaml.v2"
+	"github.com/sid-technologies/pilum/lib/resolve"
 )
 
 // FilterOptions configures how services are filtered.
 type FilterOptions struct {
-	Names       []string // Service names to filter by
+	Names       []string // Selectors (names, globs, field=value, label:, @group, !negation)
 	OnlyChanged bool     // Only include services with git changes
 	Since       string   // Git ref to compare against (default: main/master)
 	NoGitIgnore bool     // Skip reading .gitignore patterns
+	Environment string   // Environment whose overrides are applied (--env)
+	// Environments holds shared per-environment overrides from pilum.workspace.yaml.
+	Environments map[string]map[string]any
+	// Defaults are the workspace defaults merged beneath every pilum.yaml.
+	Defaults map[string]any
+	// Groups are the workspace service groups that @group names expand to.
+	Groups map[string][]string
+	// KnownKeys are top-level keys accepted besides CoreKeys (e.g. recipe
+	// fields). If nil, unknown keys are not reported.
+	KnownKeys []string
+	// Overrides are --set values, merged over everything else.
+	Overrides map[string]any
+	// Target keeps only services expanded from the targets entry of that name.
+	Target string
+	// MaxDepth is the maximum directory depth to search: 0 for
+	// DefaultMaxDepth, -1 for unlimited.
+	MaxDepth int
+	// Include, Exclude and Filenames are passed to discovery (see DiscoveryOptions).
+	Include   []string
+	Exclude   []string
+	Filenames []string
+	// ChangeDetectors decide which services changed for OnlyChanged
+	// (default: a PathDetector).
+	ChangeDetectors []ChangeDetector
+	// NoResolve leaves ${...} references as written, for commands that only
+	// need names, dependencies and paths.
+	NoResolve bool
 }
 
 func FindAndFilterServices(root string, filter []string) ([]ServiceInfo, error) {
 	return FindAndFilterServicesWithOptions(root, FilterOptions{Names: filter})
 }
 
+// FindAndFilterServicesWithOptions finds services and filters them. Like
+// FindServicesWithOptions, it returns the valid services with a Problems
+// error if any pilum.yaml is invalid.
 func FindAndFilterServicesWithOptions(root string, opts FilterOptions) ([]ServiceInfo, error) {
 	discoveryOpts := DefaultDiscoveryOptions()
 	discoveryOpts.NoGitIgnore = opts.NoGitIgnore
+	discoveryOpts.Environment = opts.Environment
+	discoveryOpts.Environments = opts.Environments
+	discoveryOpts.Defaults = opts.Defaults
+	discoveryOpts.KnownKeys = opts.KnownKeys
+	discoveryOpts.Overrides = opts.Overrides
+	discoveryOpts.Include = opts.Include
+	discoveryOpts.Exclude = opts.Exclude
+	discoveryOpts.Filenames = opts.Filenames
+	if opts.MaxDepth != 0 {
+		discoveryOpts.MaxDepth = opts.MaxDepth
+	}
 
 	services, err := FindServicesWithOptions(root, discoveryOpts)
-	if err != nil {
+	var problems Problems
+	if err != nil && !errors.As(err, &problems) {
 		return nil, errors.Wrap(err, "error finding services")
 	}
 
 	output.Debugf("Found %d services before filtering", len(services))
 
-	// Filter by name if specified
+	// Filter by selectors if specified
 	if len(opts.Names) > 0 {
-		services = FilterServices(opts.Names, services)
-		output.Debugf("Filtered by name to %d services", len(services))
+		services, err = Select(services, opts.Names, opts.Groups)
+		if err != nil {
+			return nil, err
+		}
+		output.Debugf("Selected %d services", len(services))
+	}
+
+	// Filter by target if specified
+	if opts.Target != "" {
+		services, err = FilterByTarget(services, opts.Target)
+		if err != nil {
+			return nil, err
+		}
+		output.Debugf("Filtered by target to %d services", len(services))
 	}
 
 	// Filter by git changes if requested
 	if opts.OnlyChanged {
-		services, err = FilterByChanges(services, opts.Since)
+		services, err = FilterByChanges(services, opts.Since, opts.ChangeDetectors)
 		if err != nil {
 			return nil, err
 		}
 		output.Debugf("Filtered by changes to %d services", len(services))
 	}
 
+	// Resolve references last, so commands and required variables only run
+	// for the services being acted on
+	if !opts.NoResolve {
+		var resolveProblems Problems
+		services, resolveProblems = ResolveServices(services)
+		problems = append(problems, resolveProblems...)
+	}
+
+	if len(problems) > 0 {
+		return services, problems
+	}
 	return services, nil
 }
 
-// FilterByChanges filters services to only those with git changes since the given ref.
-// It also includes services that depend on changed services (transitive dependents).
-func FilterByChanges(services []ServiceInfo, since string) ([]ServiceInfo, error) {
-	if !git.IsGitRepository() {
-		output.Warning("Not a git repository, --only-changed has no effect")
-		return services, nil
+// ResolveServices expands the ${...} references in each service's config
+// (see package resolve) and rebuilds the service from the result. A service
+// whose references fail to resolve is dropped, and reported as a Problem at
+// the failing value in its pilum.yaml.
+func ResolveServices(services []ServiceInfo) ([]ServiceInfo, Problems) {
+	type result struct {
+		config map[string]any
+		failed bool
 	}
+	results := make(map[uintptr]result) // Region instances share one config
+	resolved := make([]ServiceInfo, 0, len(services))
+	var problems Problems
 
-	changedFiles, err := git.ChangedFiles(since)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to detect changed files")
-	}
+	for _, svc := range services {
+		ctx := resolve.Context{Dir: filepath.Dir(svc.File)}
+		id := reflect.ValueOf(svc.Config).Pointer()
+		r, done := results[id]
+		if !done {
+			config, err := resolve.Config(svc.Config, ctx)
+			if err != nil {
+				problems = append(problems, resolveProblem(svc, err))
+			}
+			r = result{config: config, failed: err != nil}
+			results[id] = r
+		}
+		if r.failed {
+			continue
+		}
 
-	// Also include uncommitted changes
-	uncommitted, err := git.ChangedFilesUncommitted()
-	if err != nil {
-		output.Debugf("Could not get uncommitted changes: %v", err)
-	} else {
-		changedFiles = append(changedFiles, uncommitted...)
+		next := NewServiceInfo(r.config, svc.Path)
+		next.File = svc.File
+		next.Target = svc.Target
+		next.Environment = svc.Environment
+		next.Environments = svc.Environments
+		if svc.IsMultiRegion {
+			region, err := resolve.String(svc.Region, ctx)
+			if err != nil {
+				problems = append(problems, resolveProblem(svc, &resolve.KeyError{Key: "regions", Err: err}))
+				continue
+			}
+			next.Region = region
+			next.Regions = nil
+			next.IsMultiRegion = true
+		}
+		resolved = append(resolved, *next)
 	}
+	return resolved, problems
+}
 
-	if len(changedFiles) == 0 {
-		output.Info("No changes detected")
-		return nil, nil
+// resolveProblem reports a reference that failed to resolve at its value in
+// the service's pilum.yaml.
+func resolveProblem(svc ServiceInfo, err error) Problem {
+	problem := Problem{Path: svc.File, Line: 1, Column: 1, Message: err.Error()}
+	var keyErr *resolve.KeyError
+	if errors.As(err, &keyErr) {
+		problem.Line, problem.Column = valuePosition(svc.File, keyErr.Key, svc.Environment, svc.Target)
 	}
+	return problem
+}
 
-	output.Debugf("Changed files: %v", changedFiles)
-
-	// Build dependency graph
-	g := graph.New()
-	serviceMap := make(map[string]ServiceInfo)
-	for _, svc := range services {
-		g.AddNode(svc.Name, svc.DependsOn)
-		serviceMap[svc.Name] = svc
+// FilterByChanges filters services to only those with git changes since the given ref.
+// It also includes services that depend on changed services (transitive dependents).
+func FilterByChanges(services []ServiceInfo, since string, detectors []ChangeDetector) ([]ServiceInfo, error) {
+	if !git.IsGitRepository() {
+		output.Warning("Not a git repository, --only-changed has no effect")
+		return services, nil
 	}
 
-	// Find directly changed services
-	directlyChanged := make(map[string]bool)
-	for _, svc := range services {
-		if git.ServiceHasChanges(svc.Path, changedFiles) {
-			directlyChanged[svc.Name] = true
-			output.Debugf("Service %s has direct changes", svc.DisplayName())
-		}
+	changes, err := AffectedServices(services, since, detectors)
+	if err != nil {
+		return nil, err
 	}
 
-	// Propagate changes to dependents
-	allChanged := g.PropagateChanges(directlyChanged)
-
-	// Log propagated changes
-	for name := range allChanged {
-		if !directlyChanged[name] {
-			output.Debugf("Service %s included (depends on changed service)", name)
+	affected := make(map[string]bool, len(changes))
+	directCount := 0
+	for _, change := range changes {
+		affected[change.Service] = true
+		if change.Direct() {
+			directCount++
 		}
 	}
 
 	// Build result list
 	var changed []ServiceInfo
 	for _, svc := range services {
-		if allChanged[svc.Name] {
+		if affected[svc.Name] {
 			changed = append(changed, svc)
 		}
 	}
@@ -123,13 +217,12 @@ func FilterByChanges(services []ServiceInfo, since string) ([]ServiceInfo, error
 	if len(changed) == 0 {
 		output.Info("No services have changes")
 	} else {
-		directCount := len(directlyChanged)
-		propagatedCount := len(changed) - directCount
+		propagatedCount := len(changes) - directCount
 		if propagatedCount > 0 {
 			output.Info("Found %d service(s) with changes (%d direct, %d via dependencies)",
-				len(changed), directCount, propagatedCount)
+				len(changes), directCount, propagatedCount)
 		} else {
-			output.Info("Found %d service(s) with changes", len(changed))
+			output.Info("Found %d service(s) with changes", len(changes))
 		}
 	}
 
@@ -141,10 +234,30 @@ func FilterByChanges(services []ServiceInfo, since string) ([]ServiceInfo, error
 // the pilum.yaml file itself being one level deeper.
 const DefaultMaxDepth = 4
 
+// DefaultFilenames are the service files discovery looks for in each
+// directory. Paths with a slash are relative to the service directory.
+var DefaultFilenames = []string{"pilum.yaml", "pilum.yml", ".pilum/service.yaml"}
+
 // DiscoveryOptions configures service discovery behavior.
 type DiscoveryOptions struct {
-	MaxDepth    int  // Maximum directory depth (-1 for unlimited)
-	NoGitIgnore bool // Skip reading .gitignore patterns
+	MaxDepth    int    // Maximum directory depth (-1 for unlimited)
+	NoGitIgnore bool   // Skip reading .gitignore patterns
+	Environment string // Environment whose overrides are applied (--env)
+	// Environments holds shared per-environment overrides from pilum.workspace.yaml.
+	Environments map[string]map[string]any
+	// Defaults are the workspace defaults merged beneath every pilum.yaml.
+	Defaults map[string]any
+	// KnownKeys are top-level keys accepted besides CoreKeys (e.g. recipe
+	// fields). If nil, unknown keys are not reported.
+	KnownKeys []string
+	// Overrides are --set values, merged over everything else.
+	Overrides map[string]any
+	// Include keeps only services whose directory or file matches one of
+	// these globs. Exclude drops those matching any of them.
+	Include []string
+	Exclude []string
+	// Filenames are the service files to look for (default DefaultFilenames).
+	Filenames []string
 }
 
 // DefaultDiscoveryOptions returns the default discovery options.
@@ -169,244 +282,263 @@ func FindServicesWithDepth(root string, maxDepth int) ([]ServiceInfo, error) {
 	return FindServicesWithOptions(root, opts)
 }
 
-// FindServicesWithOptions searches for pilum.yaml files with the given options.
+// FindServicesWithOptions searches for service files with the given options.
+// If any file is invalid, the services that loaded are returned along with a
+// Problems error listing every problem in every file. ${...} references are
+// left as written; see ResolveServices.
 func FindServicesWithOptions(root string, opts DiscoveryOptions) ([]ServiceInfo, error) {
 	var services []ServiceInfo
+	declared := make(map[string]bool) // Environments declared by any service
+	var problems Problems             // Invalid pilum.yaml files are reported together
 
-	// Load ignore patterns
-	ignorePatterns := loadIgnorePatterns(root, opts.NoGitIgnore)
-	if len(ignorePatterns) > 0 {
-		output.Debugf("Loaded %d ignore patterns", len(ignorePatterns))
+	filenames := opts.Filenames
+	if len(filenames) == 0 {
+		filenames = DefaultFilenames
+	}
+	include, err := compileGlobs(opts.Include)
+	if err != nil {
+		return nil, errors.Wrap(err, "discovery include")
+	}
+	exclude, err := compileGlobs(opts.Exclude)
+	if err != nil {
+		return nil, errors.Wrap(err, "discovery exclude")
 	}
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	// Load ignore patterns; nested ignore files are added as the walk reaches them
+	ignore, err := loadIgnorePatterns(root, opts.NoGitIgnore)
+	if err != nil {
+		return nil, err
+	}
+	if ignore.Len() > 0 {
+		output.Debugf("Loaded %d ignore patterns", ignore.Len())
+	}
+
+	err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
-			return errors.Wrap(err, "error walking %s", path)
+			return errors.Wrap(err, "error walking "+path)
+		}
+		if !entry.IsDir() {
+			return nil
 		}
 
 		// Get path relative to root for pattern matching and depth calculation
 		relPath, _ := filepath.Rel(root, path)
+		relSlash := filepath.ToSlash(relPath)
 
-		// Calculate current depth (root = 0, immediate children = 1, etc.)
-		depth := 0
 		if relPath != "." {
-			depth = strings.Count(relPath, string(filepath.Separator)) + 1
-		}
-
-		// Skip directories entirely if we've exceeded max depth
-		if info.IsDir() {
-			// Check depth limit first (most common case for skipping)
+			// Calculate current depth (immediate children = 1, etc.)
+			depth := strings.Count(relSlash, "/") + 1
 			if opts.MaxDepth >= 0 && depth > opts.MaxDepth {
 				return filepath.SkipDir
 			}
 
-			if shouldIgnore(relPath, ignorePatterns) {
+			// .git and .pilum hold no services
+			switch entry.Name() {
+			case ".git", ".pilum":
+				return filepath.SkipDir
+			}
+
+			if ignore.Ignored(relSlash, true) {
 				output.Debugf("Ignoring directory: %s", relPath)
 				return filepath.SkipDir
 			}
-			return nil
-		}
+			if matchesAny(exclude, relSlash) {
+				output.Debugf("Excluding directory: %s", relPath)
+				return filepath.SkipDir
+			}
 
-		if filepath.Base(path) != "pilum.yaml" {
-			return nil
+			if err := addIgnoreFiles(ignore, path, relSlash, opts.NoGitIgnore); err != nil {
+				return err
+			}
 		}
 
-		// Check if this pilum.yaml is in an ignored path
-		if shouldIgnore(relPath, ignorePatterns) {
-			output.Debugf("Ignoring service: %s", relPath)
+		file, fileProblems := serviceFile(root, path, filenames, ignore, include, exclude)
+		problems = append(problems, fileProblems...)
+		if file == "" {
 			return nil
 		}
 
-		content, err := os.ReadFile(path)
+		content, err := os.ReadFile(file)
 		if err != nil {
-			return errors.Wrap(err, "error reading %s", path)
+			return errors.Wrap(err, "error reading "+file)
 		}
 
-		var config map[string]any
-		if err := yaml.Unmarshal(content, &config); err != nil {
-			return errors.Wrap(err, "error parsing %s", path)
+		config, fileProblems := Decode(content, file, DecodeOptions{KnownKeys: opts.KnownKeys})
+		if len(fileProblems) > 0 {
+			problems = append(problems, fileProblems...)
+			return nil
 		}
 
-		_, hasName := config["name"]
-
-		if !hasName {
-			return errors.Wrap(err, "error parsing %s", path)
+		environments := DeclaredEnvironments(config)
+		for _, env := range environments {
+			declared[env] = true
 		}
 
-		svcRelPath, _ := filepath.Rel(root, filepath.Dir(path))
-		svc := NewServiceInfo(config, svcRelPath)
+		// Each target is a separate service, sharing the rest of the file
+		for _, target := range ExpandTargets(config) {
+			targetConfig := ApplyDefaults(target.Config, opts.Defaults)
+			targetConfig, err = ApplyEnvironment(targetConfig, opts.Environment, opts.Environments)
+			if err != nil {
+				line, column := valuePosition(file, EnvironmentsKey, "", "")
+				problems = append(problems, Problem{Path: file, Line: line, Column: column, Message: err.Error()})
+				continue
+			}
+			targetConfig = mergeOverrides(targetConfig, opts.Overrides)
+
+			svc := NewServiceInfo(targetConfig, relPath)
+			svc.File = file
+			svc.Target = target.Name
+			svc.Environment = opts.Environment
+			svc.Environments = environments
 
-		// Expand multi-region services into separate instances
-		expanded := ExpandMultiRegion(*svc)
-		services = append(services, expanded...)
+			// Expand multi-region services into separate instances
+			expanded := ExpandMultiRegion(*svc)
+			services = append(services, expanded...)
+		}
 
 		return nil
 	})
 
 	if err != nil {
-		return nil, errors.Wrap(err, "error walking %s", root)
+		return nil, errors.Wrap(err, "error walking "+root)
 	}
 
-	return services, nil
-}
-
-// fallbackIgnorePatterns are used when neither .gitignore nor .pilumignore exist.
-var fallbackIgnorePatterns = []string{
-	"node_modules",
-	".git",
-	"vendor",
-}
-
-// loadIgnorePatterns reads patterns from .gitignore and .pilumignore files.
-// .gitignore provides the base patterns, .pilumignore adds project-specific overrides.
-// If noGitIgnore is true, .gitignore is skipped.
-// Supports:
-// - Directory names: "examples" matches any path containing "examples"
-// - Paths: "examples/" matches the examples directory at root
-// - Globs: "test-*" matches directories starting with "test-"
-// - Comments: lines starting with # are ignored
-// - Blank lines are ignored
-func loadIgnorePatterns(root string, noGitIgnore bool) []string {
-	var patterns []string
-
-	// Load from .gitignore first (base patterns) unless disabled
-	if !noGitIgnore {
-		patterns = append(patterns, loadPatternsFromFile(filepath.Join(root, ".gitignore"))...)
+	if len(services) > 0 {
+		if err := checkEnvironment(opts.Environment, declared, opts.Environments); err != nil {
+			return nil, err
+		}
 	}
 
-	// Load from .pilumignore (overrides/additions)
-	patterns = append(patterns, loadPatternsFromFile(filepath.Join(root, ".pilumignore"))...)
-
-	// If no patterns loaded, use fallback defaults
-	if len(patterns) == 0 {
-		return fallbackIgnorePatterns
+	if len(problems) > 0 {
+		return services, problems
 	}
-
-	return patterns
+	return services, nil
 }
 
-// loadPatternsFromFile reads ignore patterns from a file.
-func loadPatternsFromFile(path string) []string {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil
-	}
-	defer file.Close()
-
-	var patterns []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// Skip empty lines and comments
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		// Skip negation patterns (e.g., !important.log) - we don't support these
-		if strings.HasPrefix(line, "!") {
-			continue
+// serviceFile returns the service file in dir, or "" if it has none or the
+// service is ignored, excluded or not included. A directory with more than
+// one service file is a problem.
+func serviceFile(root, dir string, filenames []string, ignore *gitignore.Matcher, include, exclude []*regexp.Regexp) (string, []Problem) {
+	var found []string
+	for _, name := range filenames {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			found = append(found, file)
 		}
-		patterns = append(patterns, line)
 	}
-	return patterns
+	if len(found) == 0 {
+		return "", nil
+	}
+
+	var problems []Problem
+	for _, extra := range found[1:] {
+		problems = append(problems, Problem{
+			Path:    extra,
+			Line:    1,
+			Column:  1,
+			Message: "another service file, " + found[0] + ", is in the same directory; keep one",
+		})
+	}
+
+	file := found[0]
+	relDir, _ := filepath.Rel(root, dir)
+	relDir = filepath.ToSlash(relDir)
+	relFile, _ := filepath.Rel(root, file)
+	relFile = filepath.ToSlash(relFile)
+
+	switch {
+	case ignore.Ignored(relFile, false):
+		output.Debugf("Ignoring service: %s", relFile)
+		return "", problems
+	case matchesAny(exclude, relDir) || matchesAny(exclude, relFile):
+		output.Debugf("Excluding service: %s", relFile)
+		return "", problems
+	case len(include) > 0 && !matchesAny(include, relDir) && !matchesAny(include, relFile):
+		output.Debugf("Service not included: %s", relFile)
+		return "", problems
+	}
+	return file, problems
 }
 
-// shouldIgnore checks if a path matches any ignore pattern.
-func shouldIgnore(path string, patterns []string) bool {
-	if len(patterns) == 0 {
-		return false
-	}
-
-	// Normalize path separators
-	path = filepath.ToSlash(path)
-
-	for _, pattern := range patterns {
-		pattern = filepath.ToSlash(pattern)
-
-		// Exact directory match (pattern ends with /)
-		if strings.HasSuffix(pattern, "/") {
-			dir := strings.TrimSuffix(pattern, "/")
-			if path == dir || strings.HasPrefix(path, dir+"/") {
-				return true
-			}
-			continue
-		}
-
-		// Check if any path component matches the pattern
-		parts := strings.Split(path, "/")
-		for _, part := range parts {
-			matched, _ := filepath.Match(pattern, part)
-			if matched {
-				return true
-			}
+// compileGlobs compiles discovery include or exclude globs.
+func compileGlobs(globs []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(globs))
+	for _, glob := range globs {
+		re, err := gitignore.CompileGlob(glob)
+		if err != nil {
+			return nil, err
 		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
 
-		// Also try matching the full path
-		matched, _ := filepath.Match(pattern, path)
-		if matched {
+func matchesAny(globs []*regexp.Regexp, path string) bool {
+	for _, re := range globs {
+		if re.MatchString(path) {
 			return true
 		}
 	}
-
 	return false
 }
 
-func FilterServices(names []string, found []ServiceInfo) []ServiceInfo {
-	// Build lookup structures:
-	// - byDisplayName: exact match for "service (region)" format
-	// - byBaseName: all services with that base name (for multi-region matching)
-	byDisplayName := make(map[string]*ServiceInfo)
-	byBaseName := make(map[string][]ServiceInfo)
-	var allNames []string
-
-	for i := range found {
-		svc := &found[i]
-		displayName := svc.DisplayName()
-		byDisplayName[displayName] = svc
-		byBaseName[svc.Name] = append(byBaseName[svc.Name], *svc)
-		allNames = append(allNames, svc.Name)
-		if displayName != svc.Name {
-			allNames = append(allNames, displayName)
-		}
-	}
-
-	var services []ServiceInfo
-	matched := make(map[string]bool) // track what we've added to avoid duplicates
+// fallbackIgnorePatterns are used when no ignore file exists at the root.
+var fallbackIgnorePatterns = []string{
+	"node_modules",
+	"vendor",
+}
 
-	for _, name := range names {
-		foundMatch := false
-
-		// First, try exact display name match (e.g., "global-api (us-central1)")
-		if svc, ok := byDisplayName[name]; ok {
-			key := svc.DisplayName()
-			if !matched[key] {
-				services = append(services, *svc)
-				matched[key] = true
-			}
-			foundMatch = true
+// loadIgnorePatterns reads the ignore files at the root: .git/info/exclude
+// and .gitignore (unless noGitIgnore), then .pilumignore, which takes
+// precedence. Patterns follow git's rules, including ** and !negation. If
+// there are none, fallbackIgnorePatterns are used.
+func loadIgnorePatterns(root string, noGitIgnore bool) (*gitignore.Matcher, error) {
+	ignore := &gitignore.Matcher{}
+	if !noGitIgnore {
+		patterns, err := gitignore.ReadFile(filepath.Join(root, ".git", "info", "exclude"), "")
+		if err != nil {
+			return nil, err
 		}
+		ignore.Add(patterns...)
+	}
+	if err := addIgnoreFiles(ignore, root, "", noGitIgnore); err != nil {
+		return nil, err
+	}
 
-		// Second, try base name match (e.g., "global-api" matches all regions)
-		if instances, ok := byBaseName[name]; ok {
-			for _, svc := range instances {
-				key := svc.DisplayName()
-				if !matched[key] {
-					services = append(services, svc)
-					matched[key] = true
-				}
+	if ignore.Len() == 0 {
+		for _, line := range fallbackIgnorePatterns {
+			if p, ok := gitignore.ParsePattern(line, ""); ok {
+				ignore.Add(p)
 			}
-			foundMatch = true
 		}
+	}
+	return ignore, nil
+}
 
-		if !foundMatch {
-			suggestion := suggest.FormatSuggestion(name, allNames)
-			if suggestion != "" {
-				output.Warning("Service '%s' not found - %s", name, suggestion)
-			} else {
-				output.Warning("Service '%s' not found", name)
-			}
+// addIgnoreFiles adds the .gitignore (unless noGitIgnore) and .pilumignore
+// in dir, whose patterns apply beneath it and override those of its parents.
+func addIgnoreFiles(ignore *gitignore.Matcher, dir, relDir string, noGitIgnore bool) error {
+	names := []string{".pilumignore"}
+	if !noGitIgnore {
+		names = []string{".gitignore", ".pilumignore"}
+	}
+	for _, name := range names {
+		patterns, err := gitignore.ReadFile(filepath.Join(dir, name), relDir)
+		if err != nil {
+			return err
 		}
+		ignore.Add(patterns...)
 	}
+	return nil
+}
 
+// FilterServices returns the services matching names, which are selectors
+// (see Select) without workspace groups. Invalid selectors match nothing.
+func FilterServices(names []string, found []ServiceInfo) []ServiceInfo {
+	services, err := Select(found, names, nil)
+	if err != nil {
+		return nil
+	}
 	return services
 }
 
@@ -462,11 +594,14 @@ func SortByDependencies(services []ServiceInfo) ([]ServiceInfo, error) {
 	return sorted, nil
 }
 
-// BuildDependencyGraph builds a dependency graph from the given services.
+// BuildDependencyGraph builds a dependency graph from the given services,
+// with one node per service name.
 func BuildDependencyGraph(services []ServiceInfo) *graph.Graph {
 	g := graph.New()
 	for _, svc := range services {
-		g.AddNode(svc.Name, svc.DependsOn)
+		if !g.HasNode(svc.Name) {
+			g.AddNode(svc.Name, svc.DependsOn)
+		}
 	}
 	return g
 }
@@ -482,6 +617,14 @@ func ListServices(services []*ServiceInfo) {
 			fmt.Printf("      %sRegion:%s   %s\n", output.Muted, output.Reset, svc.Region)
 		}
 		fmt.Printf("      %sService:%s  %s\n", output.Muted, output.Reset, svc.Runtime.Service)
+		if len(svc.Labels) > 0 {
+			labels := make([]string, 0, len(svc.Labels))
+			for k, v := range svc.Labels {
+				labels = append(labels, k+"="+v)
+			}
+			sort.Strings(labels)
+			fmt.Printf("      %sLabels:%s   %s\n", output.Muted, output.Reset, strings.Join(labels, ", "))
+		}
 		fmt.Println()
 	}
 }
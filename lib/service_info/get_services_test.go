@@ -3,6 +3,7 @@ package serviceinfo_test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
@@ -104,16 +105,14 @@ func TestFindServicesMissingName(t *testing.T) {
 	require.NoError(t, os.MkdirAll(svcDir, 0755))
 
 	// Write YAML without name field
-	// Note: There's a bug in get_services.go:54 where it wraps a nil error
-	// This test documents the current (buggy) behavior - it panics
 	content := `provider: gcp
 region: us-central1
 `
 	require.NoError(t, os.WriteFile(filepath.Join(svcDir, "pilum.yaml"), []byte(content), 0644))
 
-	require.Panics(t, func() {
-		_, _ = serviceinfo.FindServices(tmpDir)
-	})
+	_, err := serviceinfo.FindServices(tmpDir)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), filepath.Join(svcDir, "pilum.yaml")+":1:1: missing required field: name")
 }
 
 func TestFindServicesNonExistentDirectory(t *testing.T) {
@@ -358,6 +357,28 @@ func TestFindServicesIgnoresNonServiceFiles(t *testing.T) {
 	require.Equal(t, "valid", services[0].Name)
 }
 
+func TestFindServicesFindsTestdata(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+
+	fixtureDir := filepath.Join(tmpDir, "recepies", "testdata", "my-recipe", "basic")
+	require.NoError(t, os.MkdirAll(fixtureDir, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(fixtureDir, "pilum.yaml"), []byte("name: fixture\nprovider: gcp\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pilum.yaml"), []byte("name: real\nprovider: gcp\n"), 0644))
+
+	// testdata is an ordinary directory, unless an ignore file says otherwise
+	services, err := serviceinfo.FindServicesWithDepth(tmpDir, -1)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".pilumignore"), []byte("recepies/testdata/\n"), 0644))
+	services, err = serviceinfo.FindServicesWithDepth(tmpDir, -1)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "real", services[0].Name)
+}
+
 func TestDefaultDiscoveryOptions(t *testing.T) {
 	t.Parallel()
 
@@ -568,6 +589,93 @@ func TestFindServicesFallbackIgnorePatterns(t *testing.T) {
 	require.Equal(t, "normal-svc", services[0].Name)
 }
 
+func TestFindServicesGitignoreSemantics(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	gitignore := "examples/*\n!examples/kept\n/build\n**/fixtures\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, ".gitignore"), []byte(gitignore), 0644))
+	for _, dir := range []string{"examples/demo", "examples/kept", "build", "services/build", "services/x/fixtures", "services/api", "services/api/generated"} {
+		writeService(t, tmpDir, dir, "name: "+strings.ReplaceAll(dir, "/", "-")+"\nprovider: gcp\n")
+	}
+
+	// A nested .gitignore applies beneath its directory
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "services", "api", ".gitignore"), []byte("/generated\n"), 0644))
+
+	services, err := serviceinfo.FindServices(tmpDir)
+	require.NoError(t, err)
+
+	var names []string
+	for _, svc := range services {
+		names = append(names, svc.Name)
+	}
+	require.ElementsMatch(t, []string{"examples-kept", "services-build", "services-api"}, names)
+}
+
+func TestFindServicesFilenames(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	writeService(t, tmpDir, "api", "name: api\nprovider: gcp\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "worker"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "worker", "pilum.yml"), []byte("name: worker\nprovider: gcp\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "cli", ".pilum"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "cli", ".pilum", "service.yaml"), []byte("name: cli\nprovider: homebrew\n"), 0644))
+
+	services, err := serviceinfo.FindServices(tmpDir)
+	require.NoError(t, err)
+	byName := map[string]serviceinfo.ServiceInfo{}
+	for _, svc := range services {
+		byName[svc.Name] = svc
+	}
+	require.Len(t, byName, 3)
+	require.Equal(t, "cli", byName["cli"].Path)
+	require.Equal(t, filepath.Join(tmpDir, "cli", ".pilum", "service.yaml"), byName["cli"].File)
+
+	// Only the configured filenames are discovered
+	opts := serviceinfo.DefaultDiscoveryOptions()
+	opts.Filenames = []string{"pilum.yml"}
+	services, err = serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "worker", services[0].Name)
+
+	// Two service files in one directory is a problem
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "api", "pilum.yml"), []byte("name: api2\nprovider: gcp\n"), 0644))
+	_, err = serviceinfo.FindServices(tmpDir)
+	var problems serviceinfo.Problems
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 1)
+	require.Contains(t, problems[0].String(), filepath.Join("api", "pilum.yml")+":1:1: another service file")
+}
+
+func TestFindServicesIncludeExclude(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	for _, dir := range []string{"services/api", "services/legacy", "services/nested/deep", "tools/cli"} {
+		writeService(t, tmpDir, dir, "name: "+filepath.Base(dir)+"\nprovider: gcp\n")
+	}
+
+	opts := serviceinfo.FilterOptions{
+		Include:  []string{"services/**"},
+		Exclude:  []string{"services/legacy/**"},
+		MaxDepth: 2,
+	}
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "api", services[0].Name)
+
+	opts.MaxDepth = -1
+	services, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	_, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Include: []string{"services/[z-a]"}})
+	require.Error(t, err)
+}
+
 func TestFilterOptionsNoGitIgnore(t *testing.T) {
 	t.Parallel()
 
@@ -594,3 +702,54 @@ func TestFilterOptionsNoGitIgnore(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, services, 2)
 }
+
+func TestFindServicesResolvesReferences(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "version.txt"), []byte("1.24\n"), 0644))
+	content := "name: api\nprovider: gcp\nproject: ${PILUM_TEST_UNSET_PROJECT:-acme}\nbuild:\n  version: ${file:version.txt}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "pilum.yaml"), []byte(content), 0644))
+
+	// Discovery leaves references as written
+	services, err := serviceinfo.FindServices(tmpDir)
+	require.NoError(t, err)
+	require.Equal(t, "${PILUM_TEST_UNSET_PROJECT:-acme}", services[0].Project)
+
+	services, err = serviceinfo.FindAndFilterServices(tmpDir, nil)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "acme", services[0].Project)
+	require.Equal(t, "1.24", services[0].BuildConfig.Version)
+	require.Equal(t, filepath.Join(tmpDir, "pilum.yaml"), services[0].File)
+}
+
+func TestFindAndFilterServicesResolvesSelectedOnly(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	files := map[string]string{
+		"api":    "name: api\nprovider: gcp\nproject: acme\n",
+		"worker": "name: worker\nprovider: gcp\nproject: acme\nenvironments:\n  prod:\n    cloud_run:\n      flags: [--a, \"${PILUM_TEST_UNSET_FLAG:?needed for deploys}\"]\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, name), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name, "pilum.yaml"), []byte(content), 0644))
+	}
+	opts := serviceinfo.FilterOptions{Names: []string{"api"}, Environment: "prod"}
+
+	// A reference that can't resolve doesn't stop other services
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+
+	// and is reported at its value when its service is selected
+	opts.Names = nil
+	services, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, opts)
+	require.Len(t, services, 1)
+	require.Equal(t, "api", services[0].Name)
+	var problems serviceinfo.Problems
+	require.ErrorAs(t, err, &problems)
+	require.Len(t, problems, 1)
+	require.Equal(t, filepath.Join(tmpDir, "worker", "pilum.yaml")+":7:20: cloud_run.flags[1]: environment variable PILUM_TEST_UNSET_FLAG is required: needed for deploys", problems[0].String())
+}
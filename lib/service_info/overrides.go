@@ -0,0 +1,44 @@
+package serviceinfo
+
+import (
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseOverrides parses --set values of the form key=value, where key is a
+// dotted config path. Values are read as YAML, so "2" is a number and
+// "[a, b]" a list; anything that isn't valid YAML is kept as a string.
+func ParseOverrides(values []string) (map[string]any, error) {
+	overrides := make(map[string]any)
+	for _, raw := range values {
+		key, value, ok := strings.Cut(raw, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, errors.New("invalid --set '%s' (expected key=value)", raw)
+		}
+
+		path := strings.Split(key, ".")
+		if path[0] == "name" || path[0] == EnvironmentsKey || path[0] == TargetsKey {
+			return nil, errors.New("--set cannot change '%s'", path[0])
+		}
+		for _, part := range path {
+			if part == "" {
+				return nil, errors.New("invalid --set key '%s'", key)
+			}
+		}
+
+		var parsed any
+		if err := yaml.Unmarshal([]byte(value), &parsed); err != nil {
+			parsed = value
+		}
+		if parsed == nil {
+			parsed = value
+		}
+		configutil.SetNested(overrides, parsed, path...)
+	}
+	return overrides, nil
+}
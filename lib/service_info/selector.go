@@ -0,0 +1,248 @@
+package serviceinfo
+
+import (
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// Selector syntax, as accepted by Select:
+//
+//	api              service name or display name ("api [lambda]", "api (us-east1)")
+//	api-*            name glob
+//	provider=gcp     field glob: name, provider, type, region or target
+//	label:team=pay*  label glob (label:team alone matches any value)
+//	@backend         workspace group, whose members are selectors too
+//	!legacy-*        negation: drop what the rest of the selectors (or the
+//	                 group's other members) match
+//
+// Terms joined with commas must all match: provider=gcp,region=europe-*.
+const (
+	NegatePrefix = "!"
+	LabelPrefix  = "label:"
+)
+
+// selectorFields are the fields a key=value term can match.
+var selectorFields = map[string]func(ServiceInfo) string{
+	"name":     func(s ServiceInfo) string { return s.Name },
+	"provider": func(s ServiceInfo) string { return s.Provider },
+	"type":     func(s ServiceInfo) string { return s.RecipeKey() },
+	"region":   func(s ServiceInfo) string { return s.Region },
+	"target":   func(s ServiceInfo) string { return s.Target },
+}
+
+// selector is a parsed selector: terms that must all match.
+type selector struct {
+	raw    string
+	negate bool
+	terms  []term
+}
+
+// term matches one field, label or name against a glob.
+type term struct {
+	field   string // selectorFields key, or "" for the name
+	label   string // Label key, if this is a label term
+	pattern string // Glob; "" for a label term matches any value
+}
+
+// Select returns the services matching any of selectors, in selector order,
+// minus those matching a negated selector. If every selector is negated,
+// they are subtracted from all services. A group is resolved the same way
+// from its own members, so its negations only narrow the group: "@backend
+// api-legacy" selects api-legacy even if backend excludes it. Selectors that
+// match nothing are warned about.
+func Select(services []ServiceInfo, selectors []string, groups map[string][]string) ([]ServiceInfo, error) {
+	selected, err := resolveSelectors(services, selectors, groups, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ServiceInfo, 0, len(selected))
+	for _, i := range selected {
+		result = append(result, services[i])
+	}
+	return result, nil
+}
+
+// resolveSelectors returns the indexes of the services selectors select, in
+// order. within lists the groups being resolved.
+func resolveSelectors(services []ServiceInfo, selectors []string, groups map[string][]string, within []string) ([]int, error) {
+	var selected []int
+	added := make(map[int]bool)
+	excluded := make(map[int]bool)
+	onlyNegated := len(selectors) > 0
+	for _, raw := range selectors {
+		matched, negate, err := resolveSelector(services, raw, groups, within)
+		if err != nil {
+			return nil, err
+		}
+		if negate {
+			for _, i := range matched {
+				excluded[i] = true
+			}
+			continue
+		}
+		onlyNegated = false
+		for _, i := range matched {
+			if !added[i] {
+				added[i] = true
+				selected = append(selected, i)
+			}
+		}
+	}
+	if onlyNegated {
+		for i := range services {
+			selected = append(selected, i)
+		}
+	}
+
+	return slices.DeleteFunc(selected, func(i int) bool { return excluded[i] }), nil
+}
+
+// resolveSelector returns the indexes of the services one selector or group
+// matches, and whether it is negated.
+func resolveSelector(services []ServiceInfo, raw string, groups map[string][]string, within []string) ([]int, bool, error) {
+	negate := strings.HasPrefix(raw, NegatePrefix)
+	if group, ok := strings.CutPrefix(strings.TrimPrefix(raw, NegatePrefix), GroupPrefix); ok {
+		members, err := groupMembers(group, groups, within)
+		if err != nil {
+			return nil, false, err
+		}
+		matched, err := resolveSelectors(services, members, groups, append(slices.Clone(within), group))
+		return matched, negate, err
+	}
+
+	sel, err := parseSelector(raw)
+	if err != nil {
+		return nil, false, err
+	}
+	var matched []int
+	for i, svc := range services {
+		if sel.matches(svc) {
+			matched = append(matched, i)
+		}
+	}
+	if len(matched) == 0 && !sel.negate {
+		warnNoMatch(sel, services)
+	}
+	return matched, sel.negate, nil
+}
+
+// parseSelector parses a single selector.
+func parseSelector(raw string) (selector, error) {
+	sel := selector{raw: raw}
+	expr := raw
+	if rest, ok := strings.CutPrefix(expr, NegatePrefix); ok {
+		sel.negate = true
+		expr = rest
+	}
+	if strings.TrimSpace(expr) == "" {
+		return selector{}, errors.New("empty selector '%s'", raw)
+	}
+
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return selector{}, errors.New("empty term in selector '%s'", raw)
+		}
+
+		if label, ok := strings.CutPrefix(part, LabelPrefix); ok {
+			key, pattern, _ := strings.Cut(label, "=")
+			if key == "" {
+				return selector{}, errors.New("selector '%s' needs a label name, e.g. label:team=payments", raw)
+			}
+			sel.terms = append(sel.terms, term{label: key, pattern: pattern})
+			continue
+		}
+
+		key, pattern, ok := strings.Cut(part, "=")
+		if !ok {
+			sel.terms = append(sel.terms, term{pattern: part})
+			continue
+		}
+		if _, known := selectorFields[key]; !known {
+			keys := make([]string, 0, len(selectorFields))
+			for k := range selectorFields {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			msg := "unknown selector field '" + key + "' in '" + raw + "'"
+			if suggestion := suggest.FormatSuggestion(key, keys); suggestion != "" {
+				msg += " - " + suggestion
+			} else {
+				msg += " (use " + strings.Join(keys, ", ") + " or label:)"
+			}
+			return selector{}, errors.New("%s", msg)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return selector{}, errors.New("invalid pattern in selector '%s'", raw)
+		}
+		sel.terms = append(sel.terms, term{field: key, pattern: pattern})
+	}
+	return sel, nil
+}
+
+// matches reports whether every term matches svc. Negation is left to the caller.
+func (s selector) matches(svc ServiceInfo) bool {
+	for _, t := range s.terms {
+		if !t.matches(svc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) matches(svc ServiceInfo) bool {
+	switch {
+	case t.label != "":
+		value, ok := svc.Labels[t.label]
+		return ok && (t.pattern == "" || globMatch(t.pattern, value))
+	case t.field != "":
+		return globMatch(t.pattern, selectorFields[t.field](svc))
+	default:
+		// A name matches the service, its target or a single instance
+		names := []string{svc.Name, svc.DisplayName()}
+		if svc.Target != "" {
+			names = append(names, svc.Name+" ["+svc.Target+"]")
+		}
+		return slices.ContainsFunc(names, func(name string) bool { return globMatch(t.pattern, name) })
+	}
+}
+
+// globMatch matches value against a glob. Exact matches are checked first,
+// since display names contain brackets.
+func globMatch(pattern, value string) bool {
+	if pattern == value {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// warnNoMatch warns that a selector matched no service, suggesting a name
+// for plain names.
+func warnNoMatch(sel selector, services []ServiceInfo) {
+	if len(sel.terms) != 1 || sel.terms[0].field != "" || sel.terms[0].label != "" || strings.ContainsAny(sel.raw, "*?[") {
+		output.Warning("No services match '%s'", sel.raw)
+		return
+	}
+
+	var names []string
+	for _, svc := range services {
+		for _, name := range []string{svc.Name, svc.DisplayName()} {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	if suggestion := suggest.FormatSuggestion(sel.raw, names); suggestion != "" {
+		output.Warning("Service '%s' not found - %s", sel.raw, suggestion)
+	} else {
+		output.Warning("Service '%s' not found", sel.raw)
+	}
+}
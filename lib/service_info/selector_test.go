@@ -0,0 +1,153 @@
+package serviceinfo_test
+
+import (
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func selectorServices() []serviceinfo.ServiceInfo {
+	return []serviceinfo.ServiceInfo{
+		{Name: "api-gateway", Provider: "gcp", Region: "europe-west1", Labels: map[string]string{"team": "payments"}},
+		{Name: "api-users", Provider: "gcp", Region: "us-central1", Labels: map[string]string{"team": "identity"}},
+		{Name: "worker", Provider: "aws", Region: "eu-west-1", Target: "lambda", Labels: map[string]string{"team": "payments"}},
+		{Name: "legacy-billing", Provider: "gcp", Region: "europe-west1"},
+		{Name: "cli", Template: "homebrew"},
+	}
+}
+
+func selectedNames(services []serviceinfo.ServiceInfo) []string {
+	names := make([]string, 0, len(services))
+	for _, svc := range services {
+		names = append(names, svc.Name)
+	}
+	return names
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	groups := map[string][]string{
+		"backend": {"api-*", "worker"},
+		"all":     {"@backend", "cli"},
+		"legacy":  {"legacy-*"},
+	}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		expected  []string
+	}{
+		{name: "exact name", selectors: []string{"worker"}, expected: []string{"worker"}},
+		{name: "name glob", selectors: []string{"api-*"}, expected: []string{"api-gateway", "api-users"}},
+		{name: "provider", selectors: []string{"provider=aws"}, expected: []string{"worker"}},
+		{name: "type", selectors: []string{"type=homebrew"}, expected: []string{"cli"}},
+		{name: "region glob", selectors: []string{"region=europe-*"}, expected: []string{"api-gateway", "legacy-billing"}},
+		{name: "target", selectors: []string{"target=lambda"}, expected: []string{"worker"}},
+		{name: "target display name", selectors: []string{"worker [lambda]"}, expected: []string{"worker"}},
+		{name: "label", selectors: []string{"label:team=payments"}, expected: []string{"api-gateway", "worker"}},
+		{name: "label glob", selectors: []string{"label:team=pay*"}, expected: []string{"api-gateway", "worker"}},
+		{name: "label present", selectors: []string{"label:team"}, expected: []string{"api-gateway", "api-users", "worker"}},
+		{name: "terms are ANDed", selectors: []string{"provider=gcp,region=europe-*"}, expected: []string{"api-gateway", "legacy-billing"}},
+		{name: "selectors are unioned in order", selectors: []string{"cli", "api-users", "cli"}, expected: []string{"cli", "api-users"}},
+		{name: "negation", selectors: []string{"provider=gcp", "!legacy-*"}, expected: []string{"api-gateway", "api-users"}},
+		{name: "only negation", selectors: []string{"!api-*", "!label:team"}, expected: []string{"legacy-billing", "cli"}},
+		{name: "group", selectors: []string{"@backend"}, expected: []string{"api-gateway", "api-users", "worker"}},
+		{name: "nested group", selectors: []string{"@all", "!api-users"}, expected: []string{"api-gateway", "worker", "cli"}},
+		{name: "negated group", selectors: []string{"provider=gcp", "!@legacy"}, expected: []string{"api-gateway", "api-users"}},
+		{name: "no match", selectors: []string{"nothing-*"}, expected: []string{}},
+		{name: "no selectors", selectors: nil, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			selected, err := serviceinfo.Select(selectorServices(), tt.selectors, groups)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, selectedNames(selected))
+		})
+	}
+}
+
+func TestSelectGroups(t *testing.T) {
+	t.Parallel()
+
+	groups := map[string][]string{
+		"api":     {"api-*", "!api-users"},
+		"backend": {"@api", "worker"},
+		"all":     {"@backend", "cli", "!legacy-*"},
+		"current": {"!legacy-*"},
+	}
+
+	tests := []struct {
+		name      string
+		selectors []string
+		expected  []string
+	}{
+		{name: "members in order", selectors: []string{"@backend", "cli", "@api"}, expected: []string{"api-gateway", "worker", "cli"}},
+		{name: "group negations narrow the group", selectors: []string{"@api"}, expected: []string{"api-gateway"}},
+		{name: "group negations don't drop named services", selectors: []string{"@api", "api-users"}, expected: []string{"api-gateway", "api-users"}},
+		{name: "nested group negations", selectors: []string{"@backend", "api-users", "legacy-billing"}, expected: []string{"api-gateway", "worker", "api-users", "legacy-billing"}},
+		{name: "negated group", selectors: []string{"provider=gcp", "!@api"}, expected: []string{"api-users", "legacy-billing"}},
+		{name: "negated nested group", selectors: []string{"!@all"}, expected: []string{"api-users", "legacy-billing"}},
+		{name: "only negated members", selectors: []string{"@current"}, expected: []string{"api-gateway", "api-users", "worker", "cli"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			selected, err := serviceinfo.Select(selectorServices(), tt.selectors, groups)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, selectedNames(selected))
+		})
+	}
+}
+
+func TestSelectInvalid(t *testing.T) {
+	t.Parallel()
+
+	groups := map[string][]string{
+		"a":       {"@b"},
+		"b":       {"@a"},
+		"backend": {"api-*"},
+	}
+
+	tests := []struct {
+		selector string
+		expected string
+	}{
+		{selector: "provder=gcp", expected: "unknown selector field 'provder'"},
+		{selector: "owner=me", expected: "name, provider, region, target, type"},
+		{selector: "region=[", expected: "invalid pattern in selector 'region=['"},
+		{selector: "label:=x", expected: "needs a label name"},
+		{selector: "!", expected: "empty selector '!'"},
+		{selector: "api,", expected: "empty term in selector 'api,'"},
+		{selector: "@a", expected: "group 'a' includes itself (a -> b -> a)"},
+		{selector: "@backnd", expected: "group 'backnd' is not defined in pilum.workspace.yaml - did you mean '@backend'?"},
+	}
+
+	for _, tt := range tests {
+		_, err := serviceinfo.Select(selectorServices(), []string{tt.selector}, groups)
+		require.Error(t, err, tt.selector)
+		require.Contains(t, err.Error(), tt.expected, tt.selector)
+	}
+}
+
+func TestFindServicesWithLabels(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	writeService(t, tmpDir, "api", "name: api\nprovider: gcp\nlabels:\n  team: payments\n  tier: 1\n")
+	writeService(t, tmpDir, "worker", "name: worker\nprovider: gcp\n")
+
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{
+		Names: []string{"label:tier=1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "1"}, services[0].Labels)
+}
@@ -1,6 +1,9 @@
 package serviceinfo
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/sid-technologies/pilum/lib/configutil"
 	"github.com/sid-technologies/pilum/lib/errors"
 )
@@ -34,31 +37,44 @@ type RuntimeConfig struct {
 }
 
 type ServiceInfo struct {
-	Name          string         `yaml:"name"`
-	Description   string         `yaml:"description"`
-	Template      string         `yaml:"template"`
-	Path          string         `yaml:"-"`
-	Config        map[string]any `yaml:"-"`
-	BuildConfig   BuildConfig    `yaml:"build"`
-	Runtime       RuntimeConfig  `yaml:"runtime"`
-	EnvVars       []EnvVars      `yaml:"env_vars"`
-	Secrets       []Secrets      `yaml:"secrets"`
-	Region        string         `yaml:"region"`
-	Regions       []string       `yaml:"regions"` // For multi-region deployments
-	IsMultiRegion bool           `yaml:"-"`       // True if this was expanded from a multi-region config
-	Project       string         `yaml:"project"`
-	License       string         `yaml:"license"`
-	Provider      string         `yaml:"provider"`
-	RegistryName  string         `yaml:"registry_name"`
-	DependsOn     []string       `yaml:"depends_on"` // Services this service depends on
+	Name          string            `yaml:"name"`
+	Description   string            `yaml:"description"`
+	Template      string            `yaml:"template"`
+	Recipe        string            `yaml:"recipe"` // Pinned recipe reference, e.g. "gcp-cloud-run@2"
+	Path          string            `yaml:"-"`
+	File          string            `yaml:"-"` // The pilum.yaml the service was read from
+	Config        map[string]any    `yaml:"-"`
+	BuildConfig   BuildConfig       `yaml:"build"`
+	Runtime       RuntimeConfig     `yaml:"runtime"`
+	EnvVars       []EnvVars         `yaml:"env_vars"`
+	Secrets       []Secrets         `yaml:"secrets"`
+	Region        string            `yaml:"region"`
+	Regions       []string          `yaml:"regions"` // For multi-region deployments
+	IsMultiRegion bool              `yaml:"-"`       // True if this was expanded from a multi-region config
+	Target        string            `yaml:"-"`       // Name of the targets entry this was expanded from
+	Project       string            `yaml:"project"`
+	License       string            `yaml:"license"`
+	Provider      string            `yaml:"provider"`
+	RegistryName  string            `yaml:"registry_name"`
+	DependsOn     []string          `yaml:"depends_on"`  // Services this service depends on
+	Labels        map[string]string `yaml:"labels"`      // Free-form key/values, selected with label:key=value
+	WatchPaths    []string          `yaml:"watch_paths"` // Globs outside the service directory that also change it
+	Environment   string            `yaml:"-"`           // Active environment (--env), applied to Config
+	Environments  []string          `yaml:"-"`           // Environments declared in the service's environments block
 }
 
-// DisplayName returns the service name with region suffix for multi-region deployments.
+// DisplayName returns the service name with a target suffix for services
+// expanded from targets, and a region suffix for multi-region deployments,
+// e.g. "api [cloud-run] (us-central1)".
 func (s *ServiceInfo) DisplayName() string {
+	name := s.Name
+	if s.Target != "" {
+		name += " [" + s.Target + "]"
+	}
 	if s.IsMultiRegion && s.Region != "" {
-		return s.Name + " (" + s.Region + ")"
+		name += " (" + s.Region + ")"
 	}
-	return s.Name
+	return name
 }
 
 // RecipeKey returns the recipe lookup key for this service.
@@ -97,29 +113,37 @@ func NewServiceInfo(config map[string]any, path string) *ServiceInfo {
 	evs := configutil.MapFromAny(config["env_vars"])
 	var envVars []EnvVars
 	for k, v := range evs {
-		key := k
-		val, ok := v.(string)
-		if !ok {
-			return nil
-		}
-		envVars = append(envVars, EnvVars{Name: key, Value: val})
+		envVars = append(envVars, EnvVars{Name: k, Value: scalarString(v)})
 	}
 
 	// secrets conversion
 	secrets := configutil.MapFromAny(config["secrets"])
 	var secretVars []Secrets
 	for k, v := range secrets {
-		secretVars = append(secretVars, Secrets{Name: k, Value: v.(string)})
+		secretVars = append(secretVars, Secrets{Name: k, Value: scalarString(v)})
+	}
+
+	// labels conversion
+	var labels map[string]string
+	if raw := configutil.MapFromAny(config["labels"]); len(raw) > 0 {
+		labels = make(map[string]string, len(raw))
+		for k, v := range raw {
+			labels[k] = scalarString(v)
+		}
 	}
 
 	// Parse build config
 	buildConfig := parseBuildConfig(config)
 
-	// Template can be specified as "template" or "type"
+	// Template can be specified as "template" or "type", or taken from the recipe name
+	recipe := configutil.GetString(config, "recipe", "")
 	template := configutil.GetString(config, "template", "")
 	if template == "" {
 		template = configutil.GetString(config, "type", "")
 	}
+	if template == "" && recipe != "" {
+		template, _, _ = strings.Cut(recipe, "@")
+	}
 
 	// Provider can be explicit or derived from type
 	provider := configutil.GetString(config, "provider", "")
@@ -143,6 +167,7 @@ func NewServiceInfo(config map[string]any, path string) *ServiceInfo {
 		Name:         configutil.GetString(config, "name", ""),
 		Description:  configutil.GetString(config, "description", ""),
 		Template:     template,
+		Recipe:       recipe,
 		Path:         path,
 		Config:       config,
 		BuildConfig:  buildConfig,
@@ -154,6 +179,8 @@ func NewServiceInfo(config map[string]any, path string) *ServiceInfo {
 		Provider:     provider,
 		RegistryName: configutil.GetString(config, "registry_name", ""),
 		DependsOn:    configutil.GetStringSlice(config, "depends_on"),
+		Labels:       labels,
+		WatchPaths:   configutil.GetStringSlice(config, "watch_paths"),
 		EnvVars:      envVars,
 		Secrets:      secretVars,
 	}
@@ -181,6 +208,18 @@ func ExpandMultiRegion(svc ServiceInfo) []ServiceInfo {
 	return expanded
 }
 
+// scalarString renders a scalar config value (e.g. PORT: 8080) as a string.
+func scalarString(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
 func parseBuildConfig(config map[string]any) BuildConfig {
 	buildMap := configutil.MapFromAny(config["build"])
 	if len(buildMap) == 0 {
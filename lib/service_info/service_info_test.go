@@ -219,6 +219,21 @@ func TestNewServiceInfoExplicitProviderOverridesDerivation(t *testing.T) {
 	require.Equal(t, "custom", svc.Provider)
 }
 
+func TestNewServiceInfoRecipePin(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":   "myservice",
+		"recipe": "gcp-cloud-run@2",
+	}
+
+	svc := serviceinfo.NewServiceInfo(config, "/path")
+
+	require.Equal(t, "gcp-cloud-run@2", svc.Recipe)
+	require.Equal(t, "gcp-cloud-run", svc.Template)
+	require.Equal(t, "gcp", svc.Provider)
+}
+
 func TestNewServiceInfoWithBuildConfig(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,245 @@
+package serviceinfo
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of Source, from highest to lowest precedence.
+const (
+	SourceMatrix      = "matrix"      // Set by a regions expansion
+	SourceSet         = "set"         // --set on the command line
+	SourceEnvironment = "environment" // An environments block (service or workspace)
+	SourceTarget      = "target"      // The service's targets entry
+	SourceFile        = "file"        // The service's pilum.yaml
+	SourceDefaults    = "defaults"    // Workspace defaults
+	SourceRecipe      = "recipe"      // A recipe field default
+)
+
+// Source is where an effective config value came from.
+type Source struct {
+	Kind        string `json:"kind"`
+	File        string `json:"file,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Column      int    `json:"column,omitempty"`
+	Environment string `json:"environment,omitempty"`
+	Target      string `json:"target,omitempty"`
+}
+
+// String formats the source for humans, e.g. "services/api/pilum.yaml:4:9"
+// or "pilum.workspace.yaml:12:7 (environment prod)".
+func (s Source) String() string {
+	location := s.File
+	if s.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", s.File, s.Line, s.Column)
+	}
+
+	switch s.Kind {
+	case SourceMatrix:
+		return "matrix (regions)"
+	case SourceSet:
+		return "--set"
+	case SourceEnvironment:
+		return location + " (environment " + s.Environment + ")"
+	case SourceTarget:
+		return location + " (target " + s.Target + ")"
+	case SourceDefaults:
+		return location + " (defaults)"
+	case SourceRecipe:
+		return "recipe default"
+	default:
+		return location
+	}
+}
+
+// SourceOptions describes the layers a service's config was built from.
+type SourceOptions struct {
+	WorkspaceFile string         // pilum.workspace.yaml, if there is one
+	Environment   string         // The --env the service was loaded with
+	Overrides     map[string]any // --set values
+}
+
+// ConfigSources maps each leaf of the service's effective config, by dotted
+// path, to the layer that set it. Keys no layer sets are recipe defaults.
+func ConfigSources(svc ServiceInfo, opts SourceOptions) (map[string]Source, error) {
+	service, err := filePositions(svc.File)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := filePositions(opts.WorkspaceFile)
+	if err != nil {
+		return nil, err
+	}
+	overrides := configutil.Flatten(opts.Overrides)
+
+	config := svc.Config
+	if svc.IsMultiRegion {
+		config = configutil.CloneMap(config)
+		config["region"] = svc.Region
+	}
+
+	sources := make(map[string]Source)
+	for key := range configutil.Flatten(config) {
+		sources[key] = sourceOf(key, svc, opts, service, shared, overrides)
+	}
+	return sources, nil
+}
+
+// sourceOf returns the highest-precedence layer that sets key.
+func sourceOf(key string, svc ServiceInfo, opts SourceOptions, service, shared map[string]position, overrides map[string]any) Source {
+	if svc.IsMultiRegion && key == "region" {
+		return Source{Kind: SourceMatrix}
+	}
+	if _, ok := lookupPath(overrides, "", key); ok {
+		return Source{Kind: SourceSet}
+	}
+
+	if env := opts.Environment; env != "" {
+		prefix := EnvironmentsKey + "." + env + "."
+		if pos, ok := lookupPath(service, prefix, key); ok {
+			return Source{Kind: SourceEnvironment, File: svc.File, Line: pos.line, Column: pos.column, Environment: env}
+		}
+		if pos, ok := lookupPath(shared, prefix, key); ok {
+			return Source{Kind: SourceEnvironment, File: opts.WorkspaceFile, Line: pos.line, Column: pos.column, Environment: env}
+		}
+	}
+
+	if svc.Target != "" {
+		if pos, ok := lookupPath(service, TargetsKey+"."+svc.Target+".", key); ok {
+			return Source{Kind: SourceTarget, File: svc.File, Line: pos.line, Column: pos.column, Target: svc.Target}
+		}
+	}
+	if pos, ok := lookupPath(service, "", key); ok {
+		return Source{Kind: SourceFile, File: svc.File, Line: pos.line, Column: pos.column}
+	}
+	if pos, ok := lookupPath(shared, "defaults.", key); ok {
+		return Source{Kind: SourceDefaults, File: opts.WorkspaceFile, Line: pos.line, Column: pos.column}
+	}
+	return Source{Kind: SourceRecipe}
+}
+
+// valuePosition returns the line and column of key (e.g. "cloud_run.env[0]")
+// in a service's pilum.yaml, looking in the environment's and the target's
+// overrides first (either may be empty). It's the top of the file if the
+// file doesn't set key.
+func valuePosition(file, key, env, target string) (int, int) {
+	positions, err := filePositions(file)
+	if err != nil {
+		return 1, 1
+	}
+
+	var prefixes []string
+	if env != "" {
+		prefixes = append(prefixes, EnvironmentsKey+"."+env+".")
+	}
+	if target != "" {
+		prefixes = append(prefixes, TargetsKey+"."+target+".")
+	}
+	for _, prefix := range append(prefixes, "") {
+		if pos, ok := lookupPath(positions, prefix, key); ok {
+			return pos.line, pos.column
+		}
+	}
+	return 1, 1
+}
+
+// position is where a value appears in a YAML file.
+type position struct {
+	line, column int
+}
+
+// filePositions returns the position of every leaf value in a YAML file, by
+// dotted path. A missing or empty path yields no positions.
+func filePositions(path string) (map[string]position, error) {
+	positions := make(map[string]position)
+	if path == "" {
+		return positions, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return positions, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading "+path)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(err, "error parsing "+path)
+	}
+	if len(doc.Content) > 0 {
+		collectPositions(doc.Content[0], "", positions)
+	}
+	return positions, nil
+}
+
+func collectPositions(node *yaml.Node, prefix string, positions map[string]position) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind != yaml.MappingNode || len(node.Content) == 0 {
+		if prefix != "" {
+			positions[prefix] = position{line: node.Line, column: node.Column}
+		}
+		if node.Kind == yaml.SequenceNode && prefix != "" {
+			for i, item := range node.Content {
+				collectPositions(item, prefix+"["+strconv.Itoa(i)+"]", positions)
+			}
+		}
+		return
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valueNode := node.Content[i], node.Content[i+1]
+		if keyNode.Tag == "!!merge" {
+			collectPositions(valueNode, prefix, positions)
+			continue
+		}
+		key := keyNode.Value
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if key == TargetsKey && valueNode.Kind == yaml.SequenceNode {
+			collectTargetPositions(valueNode, positions)
+			continue
+		}
+		collectPositions(valueNode, key, positions)
+	}
+}
+
+// collectTargetPositions collects the positions of each targets entry's
+// values under "targets.<target name>".
+func collectTargetPositions(node *yaml.Node, positions map[string]position) {
+	for _, entry := range node.Content {
+		var fields map[string]any
+		if err := entry.Decode(&fields); err != nil {
+			continue
+		}
+		collectPositions(entry, TargetsKey+"."+TargetName(fields), positions)
+	}
+}
+
+// lookupPath finds prefix+key, or prefix plus the nearest parent of key, in
+// a flattened map, since a non-map value replaces everything beneath it.
+// List items are keyed by index, e.g. "cloud_run.flags[1]".
+func lookupPath[V any](flat map[string]V, prefix, key string) (V, bool) {
+	for {
+		if v, ok := flat[prefix+key]; ok {
+			return v, true
+		}
+		i := strings.LastIndexAny(key, ".[")
+		if i < 0 {
+			var zero V
+			return zero, false
+		}
+		key = key[:i]
+	}
+}
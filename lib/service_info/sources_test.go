@@ -0,0 +1,123 @@
+package serviceinfo_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSources(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	workspaceFile := filepath.Join(tmpDir, "pilum.workspace.yaml")
+	require.NoError(t, os.WriteFile(workspaceFile, []byte(`defaults:
+  project: acme
+  build:
+    language: go
+environments:
+  prod:
+    project: acme-prod
+`), 0644))
+	writeService(t, tmpDir, "api", `name: api
+provider: gcp
+regions: [us-east1, europe-west1]
+build:
+  version: "1.24"
+environments:
+  prod:
+    cloud_run:
+      min_instances: 2
+`)
+
+	overrides, err := serviceinfo.ParseOverrides([]string{"cloud_run.memory=1Gi"})
+	require.NoError(t, err)
+
+	opts := serviceinfo.DefaultDiscoveryOptions()
+	opts.Defaults = map[string]any{"project": "acme", "build": map[string]any{"language": "go"}}
+	opts.Environment = "prod"
+	opts.Environments = map[string]map[string]any{"prod": {"project": "acme-prod"}}
+	opts.Overrides = overrides
+	services, err := serviceinfo.FindServicesWithOptions(tmpDir, opts)
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	svc := services[0]
+	svc.Config["cloud_run"].(map[string]any)["cpu"] = "1" // as a recipe default would
+	require.Equal(t, "1Gi", svc.Config["cloud_run"].(map[string]any)["memory"])
+
+	sources, err := serviceinfo.ConfigSources(svc, serviceinfo.SourceOptions{
+		WorkspaceFile: workspaceFile,
+		Environment:   "prod",
+		Overrides:     overrides,
+	})
+	require.NoError(t, err)
+
+	file := filepath.Join(tmpDir, "api", "pilum.yaml")
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceFile, File: file, Line: 1, Column: 7}, sources["name"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceFile, File: file, Line: 5, Column: 12}, sources["build.version"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceDefaults, File: workspaceFile, Line: 4, Column: 15}, sources["build.language"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceEnvironment, File: workspaceFile, Line: 7, Column: 14, Environment: "prod"}, sources["project"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceEnvironment, File: file, Line: 9, Column: 22, Environment: "prod"}, sources["cloud_run.min_instances"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceSet}, sources["cloud_run.memory"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceMatrix}, sources["region"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceRecipe}, sources["cloud_run.cpu"])
+
+	require.Equal(t, file+":9:22 (environment prod)", sources["cloud_run.min_instances"].String())
+	require.Equal(t, workspaceFile+":4:15 (defaults)", sources["build.language"].String())
+}
+
+func TestConfigSourcesTarget(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	writeService(t, tmpDir, "api", `name: api
+project: acme
+region: us-central1
+targets:
+  - type: gcp-cloud-run
+  - type: aws-lambda
+    region: us-east-1
+`)
+
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Target: "lambda"})
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+
+	sources, err := serviceinfo.ConfigSources(services[0], serviceinfo.SourceOptions{})
+	require.NoError(t, err)
+
+	file := filepath.Join(tmpDir, "api", "pilum.yaml")
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceTarget, File: file, Line: 7, Column: 13, Target: "lambda"}, sources["region"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceTarget, File: file, Line: 6, Column: 11, Target: "lambda"}, sources["type"])
+	require.Equal(t, serviceinfo.Source{Kind: serviceinfo.SourceFile, File: file, Line: 2, Column: 10}, sources["project"])
+	require.Equal(t, file+":7:13 (target lambda)", sources["region"].String())
+}
+
+func TestParseOverrides(t *testing.T) {
+	t.Parallel()
+
+	overrides, err := serviceinfo.ParseOverrides([]string{
+		"region=europe-west1",
+		"cloud_run.min_instances=2",
+		"cloud_run.flags=[--a, --b]",
+		"description=",
+		"message=a: b: c",
+	})
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{
+		"region":      "europe-west1",
+		"cloud_run":   map[string]any{"min_instances": 2, "flags": []any{"--a", "--b"}},
+		"description": "",
+		"message":     "a: b: c",
+	}, overrides)
+
+	for _, invalid := range []string{"region", "=x", "name=api", "environments.prod.project=x", "targets=[]", "cloud_run..cpu=1"} {
+		_, err := serviceinfo.ParseOverrides([]string{invalid})
+		require.Error(t, err, invalid)
+	}
+}
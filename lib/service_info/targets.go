@@ -0,0 +1,134 @@
+package serviceinfo
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/configutil"
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/suggest"
+)
+
+// TargetsKey is the pilum.yaml list of deployment targets. Each entry sets a
+// type (or provider/recipe) plus overrides, and becomes its own service:
+//
+//	name: api
+//	build:
+//	  cmd: go build -o dist/api .
+//	targets:
+//	  - type: gcp-cloud-run
+//	    region: us-central1
+//	  - type: aws-lambda
+//	    region: us-east-1
+const TargetsKey = "targets"
+
+// recipeKeys are the keys that choose a service's recipe. An override of any
+// of them replaces all of them, so e.g. a base provider doesn't outlive a
+// target's type.
+var recipeKeys = []string{"type", "template", "recipe", "provider"}
+
+// regionKeys are the keys that choose where a service deploys. An override
+// of either replaces both, so e.g. a base regions list doesn't outlive an
+// environment's region.
+var regionKeys = []string{"region", "regions"}
+
+// exclusiveKeys are the groups of keys that are overridden together.
+var exclusiveKeys = [][]string{recipeKeys, regionKeys}
+
+// mergeOverrides returns base with overrides deep-merged on top, except that
+// overriding any key of one of the exclusiveKeys groups first drops the rest
+// of the group from base.
+func mergeOverrides(base, overrides map[string]any) map[string]any {
+	base = configutil.CloneMap(base)
+	for _, group := range exclusiveKeys {
+		if slices.ContainsFunc(group, func(key string) bool { _, ok := overrides[key]; return ok }) {
+			for _, key := range group {
+				delete(base, key)
+			}
+		}
+	}
+	return configutil.DeepMerge(base, overrides)
+}
+
+// TargetConfig is the config of one entry of a targets list.
+type TargetConfig struct {
+	Name   string
+	Config map[string]any
+}
+
+// ExpandTargets returns one config per entry of the config's targets list:
+// the config without the list, with the entry merged over it. A config
+// without targets is returned as the only entry, with no name.
+func ExpandTargets(config map[string]any) []TargetConfig {
+	entries, _ := config[TargetsKey].([]any)
+	if len(entries) == 0 {
+		base := configutil.CloneMap(config)
+		delete(base, TargetsKey)
+		return []TargetConfig{{Config: base}}
+	}
+
+	targets := make([]TargetConfig, 0, len(entries))
+	for _, raw := range entries {
+		entry := configutil.MapFromAny(raw)
+
+		base := configutil.CloneMap(config)
+		delete(base, TargetsKey)
+
+		overrides := configutil.CloneMap(entry)
+		delete(overrides, "name")
+		targets = append(targets, TargetConfig{
+			Name:   TargetName(entry),
+			Config: mergeOverrides(base, overrides),
+		})
+	}
+	return targets
+}
+
+// TargetName returns the name of a targets entry: its name, or else its type
+// without the cloud prefix (gcp-cloud-run is "cloud-run"), recipe or provider.
+func TargetName(entry map[string]any) string {
+	if name := configutil.GetString(entry, "name", ""); name != "" {
+		return name
+	}
+
+	kind := configutil.GetString(entry, "type", "")
+	if kind == "" {
+		kind = configutil.GetString(entry, "template", "")
+	}
+	if kind == "" {
+		kind, _, _ = strings.Cut(configutil.GetString(entry, "recipe", ""), "@")
+	}
+	if kind == "" {
+		return configutil.GetString(entry, "provider", "")
+	}
+	for _, prefix := range []string{"gcp-", "aws-", "azure-"} {
+		if name, ok := strings.CutPrefix(kind, prefix); ok {
+			return name
+		}
+	}
+	return kind
+}
+
+// FilterByTarget keeps the services deploying to target. It's an error if no
+// service has that target.
+func FilterByTarget(services []ServiceInfo, target string) ([]ServiceInfo, error) {
+	var filtered []ServiceInfo
+	var names []string
+	for _, svc := range services {
+		if svc.Target == target {
+			filtered = append(filtered, svc)
+		}
+		if svc.Target != "" && !slices.Contains(names, svc.Target) {
+			names = append(names, svc.Target)
+		}
+	}
+
+	if len(filtered) == 0 {
+		msg := "no service has target '" + target + "'"
+		if suggestion := suggest.FormatSuggestion(target, names); suggestion != "" {
+			msg += " - " + suggestion
+		}
+		return nil, errors.New("%s", msg)
+	}
+	return filtered, nil
+}
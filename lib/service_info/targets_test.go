@@ -0,0 +1,121 @@
+package serviceinfo_test
+
+import (
+	"testing"
+
+	serviceinfo "github.com/sid-technologies/pilum/lib/service_info"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		entry    map[string]any
+		expected string
+	}{
+		{entry: map[string]any{"name": "edge", "type": "gcp-cloud-run"}, expected: "edge"},
+		{entry: map[string]any{"type": "gcp-cloud-run"}, expected: "cloud-run"},
+		{entry: map[string]any{"type": "aws-lambda"}, expected: "lambda"},
+		{entry: map[string]any{"type": "homebrew"}, expected: "homebrew"},
+		{entry: map[string]any{"recipe": "azure-container-apps@2"}, expected: "container-apps"},
+		{entry: map[string]any{"provider": "docker"}, expected: "docker"},
+		{entry: map[string]any{"region": "us-east1"}, expected: ""},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, serviceinfo.TargetName(tt.entry), "%v", tt.entry)
+	}
+}
+
+func TestExpandTargets(t *testing.T) {
+	t.Parallel()
+
+	config := map[string]any{
+		"name":      "api",
+		"provider":  "gcp",
+		"project":   "acme",
+		"cloud_run": map[string]any{"cpu": 1, "memory": "512Mi"},
+		"targets": []any{
+			map[string]any{"type": "gcp-cloud-run", "cloud_run": map[string]any{"cpu": 2}},
+			map[string]any{"name": "fn", "type": "aws-lambda", "region": "us-east-1"},
+		},
+	}
+
+	targets := serviceinfo.ExpandTargets(config)
+	require.Len(t, targets, 2)
+
+	require.Equal(t, "cloud-run", targets[0].Name)
+	require.Equal(t, "gcp-cloud-run", targets[0].Config["type"])
+	require.Equal(t, map[string]any{"cpu": 2, "memory": "512Mi"}, targets[0].Config["cloud_run"])
+	require.NotContains(t, targets[0].Config, "targets")
+
+	require.Equal(t, "fn", targets[1].Name)
+	require.Equal(t, "api", targets[1].Config["name"], "a target's name doesn't rename the service")
+	require.NotContains(t, targets[1].Config, "provider", "the target's type replaces the base provider")
+
+	svc := serviceinfo.NewServiceInfo(targets[1].Config, ".")
+	require.Equal(t, "aws", svc.Provider)
+	require.Equal(t, "acme", svc.Project)
+
+	// A target's region replaces the base regions
+	regional := serviceinfo.ExpandTargets(map[string]any{
+		"name":    "api",
+		"regions": []any{"us-central1", "us-east1"},
+		"targets": []any{map[string]any{"type": "aws-lambda", "region": "us-east-1"}},
+	})
+	require.Equal(t, "us-east-1", regional[0].Config["region"])
+	require.NotContains(t, regional[0].Config, "regions")
+
+	untargeted := serviceinfo.ExpandTargets(map[string]any{"name": "worker"})
+	require.Equal(t, []serviceinfo.TargetConfig{{Config: map[string]any{"name": "worker"}}}, untargeted)
+}
+
+func TestFindServicesWithTargets(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	writeService(t, tmpDir, "api", `name: api
+project: acme
+regions: [us-central1, europe-west1]
+targets:
+  - type: gcp-cloud-run
+  - type: aws-lambda
+    regions: [us-east-1]
+environments:
+  prod:
+    project: acme-prod
+`)
+	writeService(t, tmpDir, "worker", "name: worker\nprovider: gcp\n")
+
+	services, err := serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Environment: "prod"})
+	require.NoError(t, err)
+
+	var names []string
+	for _, svc := range services {
+		names = append(names, svc.DisplayName())
+		if svc.Name == "api" {
+			require.Equal(t, "acme-prod", svc.Project)
+		}
+	}
+	require.ElementsMatch(t, []string{
+		"api [cloud-run] (us-central1)",
+		"api [cloud-run] (europe-west1)",
+		"api [lambda] (us-east-1)",
+		"worker",
+	}, names)
+
+	services, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Names: []string{"api"}, Target: "lambda"})
+	require.NoError(t, err)
+	require.Len(t, services, 1)
+	require.Equal(t, "aws", services[0].Provider)
+
+	services, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Names: []string{"api [cloud-run]"}})
+	require.NoError(t, err)
+	require.Len(t, services, 2)
+
+	_, err = serviceinfo.FindAndFilterServicesWithOptions(tmpDir, serviceinfo.FilterOptions{Target: "lamda"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no service has target 'lamda'")
+	require.Contains(t, err.Error(), "lambda")
+}
@@ -40,8 +40,7 @@ func CommandWorker(taskInfo *TaskInfo) (bool, error) {
 		case "service_dir":
 			workingDir = taskInfo.Cwd
 		default:
-			output.Debugf("Invalid execution mode: %s", taskInfo.ExecutionMode)
-			return false, nil
+			return false, errors.New("invalid execution mode '%s' for %s", taskInfo.ExecutionMode, taskInfo.ServiceName)
 		}
 
 		// Prepare command
@@ -154,7 +154,8 @@ func TestCommandWorkerInvalidExecutionMode(t *testing.T) {
 	success, err := workerqueue.CommandWorker(taskInfo)
 
 	require.False(t, success)
-	require.NoError(t, err)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid_mode")
 }
 
 func TestCommandWorkerServiceDirMode(t *testing.T) {
@@ -0,0 +1,319 @@
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/output"
+)
+
+// FetchOptions configures Fetch.
+type FetchOptions struct {
+	// Update re-resolves git refs and re-downloads tarballs instead of
+	// fetching exactly what pilum.lock records.
+	Update bool
+	// HTTPClient is used for tarball downloads (default: 60s timeout).
+	HTTPClient *http.Client
+	// MaxTarballSize is the largest tarball Fetch downloads, in bytes
+	// (default: DefaultMaxTarballSize).
+	MaxTarballSize int64
+}
+
+// DefaultMaxTarballSize caps tarball downloads.
+const DefaultMaxTarballSize = 256 << 20
+
+// Fetch downloads every recipe source into the cache and writes pilum.lock.
+// Without Update, sources already in the lockfile are fetched at their locked
+// commit and their checksums must match.
+func Fetch(root string, cfg *Config, opts FetchOptions) (*Lock, error) {
+	previous, err := ReadLock(root)
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		previous = &Lock{}
+	}
+
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	if opts.MaxTarballSize <= 0 {
+		opts.MaxTarballSize = DefaultMaxTarballSize
+	}
+
+	lock := &Lock{}
+	for _, src := range cfg.RecipeSources {
+		var locked *LockedSource
+		if entry, found := previous.Find(src.Name); found && !opts.Update && entry.matches(src) {
+			locked = &entry
+		}
+
+		entry, err := fetchSource(root, src, locked, opts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to fetch recipe source '"+src.Name+"'")
+		}
+		lock.Sources = append(lock.Sources, entry)
+	}
+
+	if err := WriteLock(root, lock); err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+func fetchSource(root string, src RecipeSource, locked *LockedSource, opts FetchOptions) (LockedSource, error) {
+	dest := SourceDir(root, src.Name)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return LockedSource{}, errors.Wrap(err, "failed to create recipe cache")
+	}
+
+	// Fetch into a staging directory so a failed fetch leaves the cache intact
+	staging, err := os.MkdirTemp(filepath.Dir(dest), "."+src.Name+"-")
+	if err != nil {
+		return LockedSource{}, errors.Wrap(err, "failed to create staging directory")
+	}
+	defer os.RemoveAll(staging)
+
+	entry := LockedSource{Name: src.Name, Git: src.Git, Ref: src.Ref, URL: src.URL}
+
+	if src.Kind() == "git" {
+		rev := src.Ref
+		if locked != nil {
+			rev = locked.Commit
+		}
+		output.Debugf("Fetching %s from %s at %s", src.Name, src.Git, rev)
+		entry.Commit, err = fetchGit(src.Git, rev, staging)
+	} else {
+		expected := src.SHA256
+		if locked != nil {
+			expected = locked.SHA256
+		}
+		output.Debugf("Fetching %s from %s", src.Name, src.URL)
+		entry.SHA256, err = fetchTarball(opts.HTTPClient, src.URL, expected, opts.MaxTarballSize, staging)
+	}
+	if err != nil {
+		return LockedSource{}, err
+	}
+
+	if src.Path != "" {
+		if info, err := os.Stat(filepath.Join(staging, src.Path)); err != nil || !info.IsDir() {
+			return LockedSource{}, errors.New("path '%s' is not a directory in the source", src.Path)
+		}
+	}
+
+	entry.Digest, err = DirDigest(staging)
+	if err != nil {
+		return LockedSource{}, err
+	}
+	if locked != nil && locked.Digest != entry.Digest {
+		return LockedSource{}, errors.New("contents do not match %s (got %s, locked %s)", LockFileName, entry.Digest, locked.Digest)
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return LockedSource{}, errors.Wrap(err, "failed to clear "+dest)
+	}
+	if err := os.Rename(staging, dest); err != nil {
+		return LockedSource{}, errors.Wrap(err, "failed to move source into "+dest)
+	}
+
+	return entry, nil
+}
+
+// fetchGit clones repo, checks out rev (HEAD if empty) into dest without the
+// .git directory, and returns the resolved commit.
+func fetchGit(repo, rev, dest string) (string, error) {
+	clone, err := os.MkdirTemp("", "pilum-git-")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temp directory")
+	}
+	defer os.RemoveAll(clone)
+
+	if err := runGit("", "clone", "--quiet", "--no-checkout", "--", repo, clone); err != nil {
+		return "", err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	commit, err := resolveCommit(clone, rev)
+	if err != nil {
+		return "", err
+	}
+	if err := runGit(clone, "checkout", "--quiet", "--detach", commit); err != nil {
+		return "", err
+	}
+
+	if err := os.RemoveAll(filepath.Join(clone, ".git")); err != nil {
+		return "", errors.Wrap(err, "failed to remove .git directory")
+	}
+	if err := copyTree(clone, dest); err != nil {
+		return "", err
+	}
+
+	return commit, nil
+}
+
+// resolveCommit resolves rev to a commit in the clone at dir. Branches other
+// than the default one only exist as remote-tracking branches in a fresh clone.
+func resolveCommit(dir, rev string) (string, error) {
+	if strings.HasPrefix(rev, "-") {
+		return "", errors.New("invalid ref '%s'", rev)
+	}
+	for _, candidate := range []string{rev, "origin/" + rev} {
+		out, err := exec.Command("git", "-C", dir, "rev-parse", "--verify", "--quiet", candidate+"^{commit}").Output()
+		if err == nil {
+			return strings.TrimSpace(string(out)), nil
+		}
+	}
+	return "", errors.New("ref '%s' is not a commit in the repository", rev)
+}
+
+func runGit(dir string, args ...string) error {
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrap(err, "git "+strings.Join(args, " ")+": "+strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// fetchTarball downloads a .tar.gz of at most maxSize bytes, verifies it
+// against expected (if set), extracts it into dest, and returns its sha256.
+func fetchTarball(client *http.Client, url, expected string, maxSize int64, dest string) (string, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid url")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "download failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("download failed: %s returned %s", url, resp.Status)
+	}
+
+	if resp.ContentLength > maxSize {
+		return "", errors.New("%s is larger than %d bytes", url, maxSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return "", errors.Wrap(err, "download failed")
+	}
+	if int64(len(data)) > maxSize {
+		return "", errors.New("%s is larger than %d bytes", url, maxSize)
+	}
+
+	sum := sha256.Sum256(data)
+	checksum := hex.EncodeToString(sum[:])
+	if expected != "" && !strings.EqualFold(expected, checksum) {
+		return "", errors.New("checksum mismatch for %s: expected %s, got %s", url, expected, checksum)
+	}
+
+	if err := extractTarGz(bytes.NewReader(data), dest); err != nil {
+		return "", err
+	}
+	return checksum, nil
+}
+
+// extractTarGz extracts regular files and directories from a gzipped tarball.
+// Entries that would escape dest are rejected; links and devices are skipped.
+func extractTarGz(r io.Reader, dest string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return errors.Wrap(err, "invalid gzip archive")
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "invalid tar archive")
+		}
+
+		name := filepath.FromSlash(strings.TrimPrefix(header.Name, "./"))
+		if name == "" || name == "." {
+			continue
+		}
+		if !filepath.IsLocal(name) {
+			return errors.New("archive entry '%s' escapes the destination", header.Name)
+		}
+		target := filepath.Join(dest, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return errors.Wrap(err, "failed to create "+target)
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, header.FileInfo().Mode().Perm()); err != nil {
+				return err
+			}
+		default:
+			output.Debugf("Skipping archive entry %s (type %c)", header.Name, header.Typeflag)
+		}
+	}
+}
+
+// copyTree copies regular files and directories from src into dest.
+func copyTree(src, dest string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case info.Mode().IsRegular():
+			f, err := os.Open(path)
+			if err != nil {
+				return errors.Wrap(err, "failed to read "+path)
+			}
+			defer f.Close()
+			return writeFile(target, f, info.Mode().Perm())
+		default:
+			return nil
+		}
+	})
+}
+
+func writeFile(path string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Wrap(err, "failed to create "+filepath.Dir(path))
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0o600)
+	if err != nil {
+		return errors.Wrap(err, "failed to create "+path)
+	}
+	if _, err := io.Copy(f, r); err != nil { //nolint:gosec // archive size is bounded by the download
+		_ = f.Close()
+		return errors.Wrap(err, "failed to write "+path)
+	}
+	return f.Close()
+}
@@ -0,0 +1,329 @@
+package workspace_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRecipe = `name: shared-recipe
+provider: custom
+steps:
+  - name: deploy
+    command: echo deploy
+`
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+	)
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+	return string(bytes.TrimSpace(out))
+}
+
+// bareRepo creates a bare git repository with one commit containing
+// recipes/shared.yaml and returns its path and the commit.
+func bareRepo(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	work := t.TempDir()
+	git(t, work, "init", "--quiet", "--initial-branch=main")
+	require.NoError(t, os.MkdirAll(filepath.Join(work, "recipes"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(work, "recipes", "shared.yaml"), []byte(testRecipe), 0o644))
+	git(t, work, "add", ".")
+	git(t, work, "commit", "--quiet", "-m", "initial")
+	git(t, work, "tag", "v1")
+
+	bare := filepath.Join(t.TempDir(), "recipes.git")
+	git(t, work, "clone", "--quiet", "--bare", work, bare)
+	return bare, git(t, work, "rev-parse", "HEAD")
+}
+
+// pushCommit adds a commit to the bare repository and returns it.
+func pushCommit(t *testing.T, bare, content string) string {
+	t.Helper()
+
+	work := t.TempDir()
+	git(t, work, "clone", "--quiet", bare, ".")
+	require.NoError(t, os.WriteFile(filepath.Join(work, "recipes", "shared.yaml"), []byte(content), 0o644))
+	git(t, work, "commit", "--quiet", "-am", "update")
+	git(t, work, "push", "--quiet", "origin", "HEAD:main")
+	return git(t, work, "rev-parse", "HEAD")
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestFetchGitSource(t *testing.T) {
+	t.Parallel()
+
+	bare, commit := bareRepo(t)
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "main", Path: "recipes"},
+	}}
+
+	lock, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.NoError(t, err)
+	require.Len(t, lock.Sources, 1)
+	require.Equal(t, commit, lock.Sources[0].Commit)
+	require.NotEmpty(t, lock.Sources[0].Digest)
+
+	data, err := os.ReadFile(filepath.Join(workspace.SourceDir(root, "platform"), "recipes", "shared.yaml"))
+	require.NoError(t, err)
+	require.Equal(t, testRecipe, string(data))
+	require.NoDirExists(t, filepath.Join(workspace.SourceDir(root, "platform"), ".git"))
+
+	// Lockfile round-trips and verifies offline
+	written, err := workspace.ReadLock(root)
+	require.NoError(t, err)
+	require.Equal(t, lock.Sources, written.Sources)
+
+	dirs, err := workspace.RecipeDirs(root, cfg)
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(workspace.SourceDir(root, "platform"), "recipes")}, dirs)
+}
+
+func TestFetchGitHonorsLock(t *testing.T) {
+	t.Parallel()
+
+	bare, first := bareRepo(t)
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "main"},
+	}}
+
+	_, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.NoError(t, err)
+
+	second := pushCommit(t, bare, testRecipe+"# updated\n")
+
+	// Without --update the locked commit is fetched again
+	lock, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, first, lock.Sources[0].Commit)
+
+	// With --update the ref is re-resolved
+	lock, err = workspace.Fetch(root, cfg, workspace.FetchOptions{Update: true})
+	require.NoError(t, err)
+	require.Equal(t, second, lock.Sources[0].Commit)
+}
+
+func TestFetchGitTag(t *testing.T) {
+	t.Parallel()
+
+	bare, commit := bareRepo(t)
+	pushCommit(t, bare, "name: later\n")
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "v1"},
+	}}
+
+	lock, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, commit, lock.Sources[0].Commit)
+}
+
+func TestFetchGitBadRef(t *testing.T) {
+	t.Parallel()
+
+	bare, _ := bareRepo(t)
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "does-not-exist"},
+	}}
+
+	_, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "platform")
+	require.NoFileExists(t, filepath.Join(root, workspace.LockFileName))
+}
+
+func TestFetchGitRejectsOptionRef(t *testing.T) {
+	t.Parallel()
+
+	bare, _ := bareRepo(t)
+	root := t.TempDir()
+	marker := filepath.Join(t.TempDir(), "marker")
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "--orphan=" + marker},
+	}}
+
+	_, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.ErrorContains(t, err, "invalid ref")
+	require.NoFileExists(t, marker)
+}
+
+func TestFetchGitBranch(t *testing.T) {
+	t.Parallel()
+
+	bare, _ := bareRepo(t)
+	work := t.TempDir()
+	git(t, work, "clone", "--quiet", bare, ".")
+	git(t, work, "checkout", "--quiet", "-b", "feature")
+	require.NoError(t, os.WriteFile(filepath.Join(work, "recipes", "shared.yaml"), []byte(testRecipe+"# feature\n"), 0o644))
+	git(t, work, "commit", "--quiet", "-am", "feature")
+	git(t, work, "push", "--quiet", "origin", "feature")
+	commit := git(t, work, "rev-parse", "HEAD")
+
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "platform", Git: bare, Ref: "feature"},
+	}}
+
+	lock, err := workspace.Fetch(root, cfg, workspace.FetchOptions{})
+	require.NoError(t, err)
+	require.Equal(t, commit, lock.Sources[0].Commit)
+}
+
+func TestFetchTarballSource(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGz(t, map[string]string{"recipes/shared.yaml": testRecipe})
+	sum := sha256.Sum256(archive)
+	checksum := hex.EncodeToString(sum[:])
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/recipes.tar.gz" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{
+		{Name: "shared", URL: server.URL + "/recipes.tar.gz", SHA256: checksum, Path: "recipes"},
+	}}
+
+	lock, err := workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client()})
+	require.NoError(t, err)
+	require.Equal(t, checksum, lock.Sources[0].SHA256)
+	require.FileExists(t, filepath.Join(workspace.SourceDir(root, "shared"), "recipes", "shared.yaml"))
+
+	dirs, err := workspace.RecipeDirs(root, cfg)
+	require.NoError(t, err)
+	require.Len(t, dirs, 1)
+
+	// A wrong checksum is rejected
+	cfg.RecipeSources[0].SHA256 = "0000"
+	_, err = workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client(), Update: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+
+	// Missing archives are reported
+	cfg.RecipeSources[0] = workspace.RecipeSource{Name: "shared", URL: server.URL + "/missing.tar.gz"}
+	_, err = workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client(), Update: true})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404")
+}
+
+func TestFetchTarballRejectsLargeArchives(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGz(t, map[string]string{"shared.yaml": testRecipe})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{{Name: "shared", URL: server.URL}}}
+
+	_, err := workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client(), MaxTarballSize: int64(len(archive) - 1)})
+	require.ErrorContains(t, err, "is larger than")
+
+	_, err = workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client(), MaxTarballSize: int64(len(archive))})
+	require.NoError(t, err)
+}
+
+func TestFetchTarballRejectsTraversal(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGz(t, map[string]string{"../evil.yaml": "x"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{{Name: "evil", URL: server.URL}}}
+
+	_, err := workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client()})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "escapes the destination")
+	require.NoFileExists(t, filepath.Join(root, ".pilum", "evil.yaml"))
+}
+
+func TestRecipeDirsVerification(t *testing.T) {
+	t.Parallel()
+
+	archive := tarGz(t, map[string]string{"shared.yaml": testRecipe})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	}))
+	defer server.Close()
+
+	root := t.TempDir()
+	cfg := &workspace.Config{RecipeSources: []workspace.RecipeSource{{Name: "shared", URL: server.URL}}}
+
+	_, err := workspace.RecipeDirs(root, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "pilum.lock is missing")
+
+	_, err = workspace.Fetch(root, cfg, workspace.FetchOptions{HTTPClient: server.Client()})
+	require.NoError(t, err)
+
+	// Tampering with the cache is detected
+	cached := filepath.Join(workspace.SourceDir(root, "shared"), "shared.yaml")
+	require.NoError(t, os.WriteFile(cached, []byte("name: tampered\n"), 0o644))
+	_, err = workspace.RecipeDirs(root, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match")
+
+	// A missing cache is detected
+	require.NoError(t, os.RemoveAll(workspace.SourceDir(root, "shared")))
+	_, err = workspace.RecipeDirs(root, cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not in the local cache")
+
+	// Changing the source in the workspace config requires --update
+	changed := &workspace.Config{RecipeSources: []workspace.RecipeSource{{Name: "shared", URL: server.URL + "/v2"}}}
+	_, err = workspace.RecipeDirs(root, changed)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "has changed")
+}
@@ -0,0 +1,190 @@
+package workspace
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the lockfile recording exactly what each recipe source resolved to.
+const LockFileName = "pilum.lock"
+
+// CacheDir is where fetched recipe sources are stored, relative to the workspace root.
+var CacheDir = filepath.Join(".pilum", "recipes")
+
+const lockVersion = 1
+
+// Lock is the contents of pilum.lock.
+type Lock struct {
+	Version int            `yaml:"version"`
+	Sources []LockedSource `yaml:"recipe_sources"`
+}
+
+// LockedSource records what a recipe source resolved to when it was fetched.
+type LockedSource struct {
+	Name   string `yaml:"name"`
+	Git    string `yaml:"git,omitempty"`
+	Ref    string `yaml:"ref,omitempty"`
+	Commit string `yaml:"commit,omitempty"` // Resolved commit for git sources
+	URL    string `yaml:"url,omitempty"`
+	SHA256 string `yaml:"sha256,omitempty"` // Archive checksum for tarball sources
+	Digest string `yaml:"digest"`           // Checksum of the cached files
+}
+
+// Find returns the locked entry for a source name.
+func (l *Lock) Find(name string) (LockedSource, bool) {
+	for _, s := range l.Sources {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return LockedSource{}, false
+}
+
+// matches reports whether the locked entry was produced from src.
+func (s LockedSource) matches(src RecipeSource) bool {
+	return s.Git == src.Git && s.Ref == src.Ref && s.URL == src.URL &&
+		(src.SHA256 == "" || strings.EqualFold(src.SHA256, s.SHA256))
+}
+
+// ReadLock reads pilum.lock from dir. Returns nil if it doesn't exist.
+func ReadLock(dir string) (*Lock, error) {
+	path := filepath.Join(dir, LockFileName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+path)
+	}
+
+	var lock Lock
+	if err := yaml.Unmarshal(data, &lock); err != nil {
+		return nil, errors.Wrap(err, "failed to parse "+path)
+	}
+	return &lock, nil
+}
+
+// WriteLock writes pilum.lock to dir.
+func WriteLock(dir string, lock *Lock) error {
+	lock.Version = lockVersion
+	sort.Slice(lock.Sources, func(i, j int) bool {
+		return lock.Sources[i].Name < lock.Sources[j].Name
+	})
+
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by 'pilum recipe fetch'. Do not edit.\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(lock); err != nil {
+		return errors.Wrap(err, "failed to encode lockfile")
+	}
+
+	path := filepath.Join(dir, LockFileName)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil { //nolint:gosec // lockfile is meant to be committed and readable
+		return errors.Wrap(err, "failed to write "+path)
+	}
+	return nil
+}
+
+// SourceDir returns the cache directory for a fetched source.
+func SourceDir(root, name string) string {
+	return filepath.Join(root, CacheDir, name)
+}
+
+// RecipeDirs verifies every recipe source against pilum.lock and the local
+// cache, and returns the recipe directory of each. It never touches the network,
+// so loading works offline once sources are fetched.
+func RecipeDirs(root string, cfg *Config) ([]string, error) {
+	if len(cfg.RecipeSources) == 0 {
+		return nil, nil
+	}
+
+	lock, err := ReadLock(root)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil {
+		return nil, errors.New("%s declares recipe_sources but %s is missing - run 'pilum recipe fetch'", FileName, LockFileName)
+	}
+
+	dirs := make([]string, 0, len(cfg.RecipeSources))
+	for _, src := range cfg.RecipeSources {
+		locked, found := lock.Find(src.Name)
+		if !found || !locked.matches(src) {
+			return nil, errors.New("recipe source '%s' has changed since %s was written - run 'pilum recipe fetch --update'", src.Name, LockFileName)
+		}
+
+		dir := SourceDir(root, src.Name)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, errors.New("recipe source '%s' is not in the local cache - run 'pilum recipe fetch'", src.Name)
+		}
+		digest, err := DirDigest(dir)
+		if err != nil {
+			return nil, err
+		}
+		if digest != locked.Digest {
+			return nil, errors.New("recipe source '%s' in %s does not match %s (got %s, locked %s) - run 'pilum recipe fetch'",
+				src.Name, dir, LockFileName, digest, locked.Digest)
+		}
+
+		dirs = append(dirs, filepath.Join(dir, src.Path))
+	}
+
+	return dirs, nil
+}
+
+// DirDigest returns a checksum of every regular file under dir, covering
+// relative paths and contents. It is independent of file timestamps.
+func DirDigest(dir string) (string, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", errors.New("%s is not a directory", dir)
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to walk "+dir)
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, rel := range files {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(rel)))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read "+rel)
+		}
+		fileHash := sha256.New()
+		_, err = io.Copy(fileHash, f)
+		_ = f.Close()
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read "+rel)
+		}
+		_, _ = io.WriteString(h, rel+"\x00"+hex.EncodeToString(fileHash.Sum(nil))+"\n")
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,269 @@
+// Package workspace reads repository-wide pilum settings from pilum.workspace.yaml.
+package workspace
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sid-technologies/pilum/lib/errors"
+	"github.com/sid-technologies/pilum/lib/gitignore"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the workspace config file, read from the repository root.
+const FileName = "pilum.workspace.yaml"
+
+// DefaultCacheTokenEnv is the environment variable the remote cache token is
+// read from when cache.remote.token_env is not set.
+const DefaultCacheTokenEnv = "PILUM_CACHE_TOKEN"
+
+// Config is the workspace configuration.
+type Config struct {
+	// Defaults are merged beneath every service's pilum.yaml, so values the
+	// services share (project, region, registry_name, build) live in one place.
+	Defaults map[string]any `yaml:"defaults"`
+	// Groups name sets of selectors (which may be other @groups), selected on
+	// the command line as @group.
+	Groups        map[string][]string `yaml:"groups"`
+	RecipeSources []RecipeSource      `yaml:"recipe_sources"`
+	Cache         CacheConfig         `yaml:"cache"`
+	// Environments holds overrides every service gets in an environment
+	// (--env), beneath the service's own environments block.
+	Environments map[string]map[string]any `yaml:"environments"`
+	Discovery    Discovery                 `yaml:"discovery"`
+	// WatchPaths are globs, relative to the root, whose changes affect every
+	// service with --only-changed (e.g. go.mod, libs/**).
+	WatchPaths      []string        `yaml:"watch_paths"`
+	ChangeDetection ChangeDetection `yaml:"change_detection"`
+}
+
+// Change detectors, see ChangeDetection.
+const (
+	DetectorPaths   = "paths"
+	DetectorGo      = "go"
+	DetectorCommand = "command"
+)
+
+// ChangeDetection configures how --only-changed decides which services
+// changed. A service is changed if any of the detectors says so.
+type ChangeDetection struct {
+	// Detectors are "paths" (the service's directory and watch_paths), "go"
+	// (Go imports of the service's main packages) and "command". Default:
+	// paths.
+	Detectors []string `yaml:"detectors"`
+	// Command prints the names of the changed services, for the command
+	// detector. It gets the changed files on stdin and the ref in $PILUM_SINCE.
+	Command string `yaml:"command"`
+	// GoDir is the Go module or workspace directory the go detector lists
+	// packages in (default: the root).
+	GoDir string `yaml:"go_dir"`
+}
+
+// DetectorNames returns the configured detectors, or paths if none are.
+func (c ChangeDetection) DetectorNames() []string {
+	if len(c.Detectors) == 0 {
+		return []string{DetectorPaths}
+	}
+	return c.Detectors
+}
+
+// Discovery configures where services are looked for. Ignore files
+// (.gitignore, .pilumignore) apply in addition.
+type Discovery struct {
+	// Include keeps only services whose directory or service file matches
+	// one of these globs, e.g. "services/**". Exclude drops those matching
+	// any of them.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	// MaxDepth is the deepest directory searched: 0 for the default (4),
+	// -1 for unlimited.
+	MaxDepth int `yaml:"max_depth"`
+	// Filenames are the service files looked for in each directory, in
+	// order of preference (default pilum.yaml, pilum.yml, .pilum/service.yaml).
+	Filenames []string `yaml:"filenames"`
+}
+
+// CacheConfig configures the step cache.
+type CacheConfig struct {
+	Remote *RemoteCache `yaml:"remote,omitempty"`
+}
+
+// RemoteCache is a shared HTTP cache that step results are fetched from and
+// uploaded to, in addition to the local cache.
+type RemoteCache struct {
+	URL      string `yaml:"url"`
+	TokenEnv string `yaml:"token_env,omitempty"` // Env var holding the bearer token (default PILUM_CACHE_TOKEN)
+	ReadOnly bool   `yaml:"read_only,omitempty"` // Only fetch; never upload
+}
+
+// Token returns the bearer token from the configured environment variable, if set.
+func (c RemoteCache) Token() string {
+	env := c.TokenEnv
+	if env == "" {
+		env = DefaultCacheTokenEnv
+	}
+	return os.Getenv(env)
+}
+
+// RecipeSource is a remote location recipes are fetched from: either a git
+// repository at a ref, or an HTTPS tarball.
+type RecipeSource struct {
+	Name   string `yaml:"name"`
+	Git    string `yaml:"git,omitempty"`    // Repository URL or path
+	Ref    string `yaml:"ref,omitempty"`    // Branch, tag or commit (default: HEAD)
+	URL    string `yaml:"url,omitempty"`    // .tar.gz URL
+	SHA256 string `yaml:"sha256,omitempty"` // Expected tarball checksum (optional)
+	Path   string `yaml:"path,omitempty"`   // Recipe directory inside the source (default: root)
+}
+
+// Kind returns "git" or "tarball".
+func (s RecipeSource) Kind() string {
+	if s.Git != "" {
+		return "git"
+	}
+	return "tarball"
+}
+
+// Load reads the workspace config from dir. A missing file yields an empty config.
+func Load(dir string) (*Config, error) {
+	path := filepath.Join(dir, FileName)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read "+path)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to parse "+path)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid "+path)
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) validate() error {
+	seen := make(map[string]bool)
+	for i, src := range c.RecipeSources {
+		switch {
+		case src.Name == "":
+			return errors.New("recipe_sources entry %d has no name", i+1)
+		case !isSourceName(src.Name):
+			return errors.New("recipe source name '%s' must be a single directory name", src.Name)
+		case seen[src.Name]:
+			return errors.New("recipe source '%s' is defined more than once", src.Name)
+		case src.Git != "" && src.URL != "":
+			return errors.New("recipe source '%s' sets both git and url", src.Name)
+		case src.Git == "" && src.URL == "":
+			return errors.New("recipe source '%s' needs either git or url", src.Name)
+		case src.URL != "" && src.Ref != "":
+			return errors.New("recipe source '%s': ref only applies to git sources", src.Name)
+		case strings.HasPrefix(src.Ref, "-"):
+			return errors.New("recipe source '%s': ref cannot start with '-'", src.Name)
+		case !isLocalPath(src.Path):
+			return errors.New("recipe source '%s': path must be relative and stay inside the source", src.Name)
+		}
+		seen[src.Name] = true
+	}
+
+	for _, key := range []string{"name", "environments", "targets"} {
+		if _, ok := c.Defaults[key]; ok {
+			return errors.New("defaults cannot set '%s'", key)
+		}
+	}
+
+	if err := c.Discovery.validate(); err != nil {
+		return err
+	}
+
+	for _, glob := range c.WatchPaths {
+		if _, err := gitignore.CompileGlob(glob); err != nil {
+			return errors.Wrap(err, "watch_paths")
+		}
+	}
+
+	if err := c.ChangeDetection.validate(); err != nil {
+		return err
+	}
+
+	for name, members := range c.Groups {
+		switch {
+		case name == "" || strings.ContainsAny(name, "@!, "):
+			return errors.New("invalid group name '%s'", name)
+		case len(members) == 0:
+			return errors.New("group '%s' has no services", name)
+		case slices.Contains(members, ""):
+			return errors.New("group '%s' has an empty service name", name)
+		}
+	}
+
+	if remote := c.Cache.Remote; remote != nil {
+		u, err := url.Parse(remote.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("cache.remote.url must be an http or https URL")
+		}
+	}
+	return nil
+}
+
+func (d Discovery) validate() error {
+	for key, globs := range map[string][]string{"include": d.Include, "exclude": d.Exclude} {
+		for _, glob := range globs {
+			if _, err := gitignore.CompileGlob(glob); err != nil {
+				return errors.Wrap(err, "discovery."+key)
+			}
+		}
+	}
+
+	if d.MaxDepth < -1 {
+		return errors.New("discovery.max_depth must be -1 (unlimited) or more")
+	}
+
+	for _, name := range d.Filenames {
+		if name == "" || strings.HasSuffix(name, "/") || !filepath.IsLocal(name) {
+			return errors.New("discovery.filenames: '%s' must be a file path relative to the service directory", name)
+		}
+	}
+	return nil
+}
+
+func (d ChangeDetection) validate() error {
+	known := []string{DetectorPaths, DetectorGo, DetectorCommand}
+	for _, name := range d.Detectors {
+		if !slices.Contains(known, name) {
+			return errors.New("change_detection.detectors: unknown detector '%s' (use %s)", name, strings.Join(known, ", "))
+		}
+	}
+
+	hasCommand := slices.Contains(d.Detectors, DetectorCommand)
+	switch {
+	case hasCommand && strings.TrimSpace(d.Command) == "":
+		return errors.New("change_detection.command is required by the command detector")
+	case !hasCommand && d.Command != "":
+		return errors.New("change_detection.command is set but the command detector isn't enabled")
+	case !isLocalPath(d.GoDir):
+		return errors.New("change_detection.go_dir must be relative and stay inside the workspace")
+	}
+	return nil
+}
+
+// isSourceName reports whether name is a single plain path component, so
+// the source's cache directory stays inside the recipe cache.
+func isSourceName(name string) bool {
+	return filepath.IsLocal(name) && !strings.ContainsAny(name, `/\`) && name != "." && name != ".."
+}
+
+// isLocalPath reports whether p is empty or a relative path that doesn't escape its root.
+func isLocalPath(p string) bool {
+	return p == "" || filepath.IsLocal(p)
+}
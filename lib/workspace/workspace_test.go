@@ -0,0 +1,200 @@
+package workspace_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sid-technologies/pilum/lib/workspace"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeWorkspace(t *testing.T, dir, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, workspace.FileName), []byte(content), 0o644))
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := workspace.Load(t.TempDir())
+	require.NoError(t, err)
+	require.Empty(t, cfg.RecipeSources)
+}
+
+func TestLoadRecipeSources(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+recipe_sources:
+  - name: platform
+    git: https://github.com/acme/pilum-recipes.git
+    ref: v1.2.0
+    path: recipes
+  - name: tarball
+    url: https://example.com/recipes.tar.gz
+    sha256: abc123
+`)
+
+	cfg, err := workspace.Load(dir)
+	require.NoError(t, err)
+	require.Len(t, cfg.RecipeSources, 2)
+	require.Equal(t, "git", cfg.RecipeSources[0].Kind())
+	require.Equal(t, "v1.2.0", cfg.RecipeSources[0].Ref)
+	require.Equal(t, "recipes", cfg.RecipeSources[0].Path)
+	require.Equal(t, "tarball", cfg.RecipeSources[1].Kind())
+}
+
+func TestLoadRemoteCache(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+cache:
+  remote:
+    url: https://cache.example.com
+    token_env: CI_CACHE_TOKEN
+    read_only: true
+`)
+
+	cfg, err := workspace.Load(dir)
+	require.NoError(t, err)
+	require.NotNil(t, cfg.Cache.Remote)
+	require.Equal(t, "https://cache.example.com", cfg.Cache.Remote.URL)
+	require.True(t, cfg.Cache.Remote.ReadOnly)
+
+	t.Setenv("CI_CACHE_TOKEN", "secret")
+	require.Equal(t, "secret", cfg.Cache.Remote.Token())
+
+	t.Setenv(workspace.DefaultCacheTokenEnv, "default")
+	require.Equal(t, "default", workspace.RemoteCache{}.Token())
+}
+
+func TestLoadEnvironments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+environments:
+  prod:
+    project: acme-prod
+    cloud_run:
+      min_instances: 2
+`)
+
+	cfg, err := workspace.Load(dir)
+	require.NoError(t, err)
+	require.Equal(t, "acme-prod", cfg.Environments["prod"]["project"])
+	require.Equal(t, map[string]any{"min_instances": 2}, cfg.Environments["prod"]["cloud_run"])
+}
+
+func TestLoadDefaultsAndGroups(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+defaults:
+  project: acme
+  build:
+    language: go
+groups:
+  backend: [api, worker]
+`)
+
+	cfg, err := workspace.Load(dir)
+	require.NoError(t, err)
+	require.Equal(t, "acme", cfg.Defaults["project"])
+	require.Equal(t, map[string]any{"language": "go"}, cfg.Defaults["build"])
+	require.Equal(t, []string{"api", "worker"}, cfg.Groups["backend"])
+}
+
+func TestLoadDiscovery(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+discovery:
+  include: ["services/**"]
+  exclude: ["services/legacy-*"]
+  max_depth: -1
+  filenames: [pilum.yml, .pilum/service.yaml]
+`)
+
+	cfg, err := workspace.Load(dir)
+	require.NoError(t, err)
+	require.Equal(t, workspace.Discovery{
+		Include:   []string{"services/**"},
+		Exclude:   []string{"services/legacy-*"},
+		MaxDepth:  -1,
+		Filenames: []string{"pilum.yml", ".pilum/service.yaml"},
+	}, cfg.Discovery)
+}
+
+func TestLoadChangeDetection(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := workspace.Load(t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, []string{workspace.DetectorPaths}, cfg.ChangeDetection.DetectorNames())
+
+	dir := t.TempDir()
+	writeWorkspace(t, dir, `
+change_detection:
+  detectors: [go, command]
+  command: nx show projects --affected --base=$PILUM_SINCE
+  go_dir: backend
+`)
+
+	cfg, err = workspace.Load(dir)
+	require.NoError(t, err)
+	require.Equal(t, []string{workspace.DetectorGo, workspace.DetectorCommand}, cfg.ChangeDetection.DetectorNames())
+	require.Equal(t, "nx show projects --affected --base=$PILUM_SINCE", cfg.ChangeDetection.Command)
+	require.Equal(t, "backend", cfg.ChangeDetection.GoDir)
+}
+
+func TestLoadInvalidRecipeSources(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		msg     string
+	}{
+		{name: "missing name", content: "recipe_sources:\n  - git: x\n", msg: "has no name"},
+		{name: "duplicate", content: "recipe_sources:\n  - {name: a, git: x}\n  - {name: a, git: y}\n", msg: "more than once"},
+		{name: "both kinds", content: "recipe_sources:\n  - {name: a, git: x, url: y}\n", msg: "both git and url"},
+		{name: "no location", content: "recipe_sources:\n  - {name: a}\n", msg: "either git or url"},
+		{name: "ref on tarball", content: "recipe_sources:\n  - {name: a, url: y, ref: main}\n", msg: "ref only applies"},
+		{name: "option ref", content: "recipe_sources:\n  - {name: a, git: x, ref: --upload-pack=touch}\n", msg: "ref cannot start with '-'"},
+		{name: "escaping path", content: "recipe_sources:\n  - {name: a, git: x, path: ../etc}\n", msg: "path must be relative"},
+		{name: "remote cache without url", content: "cache:\n  remote:\n    read_only: true\n", msg: "http or https URL"},
+		{name: "defaults name", content: "defaults:\n  name: api\n", msg: "defaults cannot set 'name'"},
+		{name: "discovery max depth", content: "discovery:\n  max_depth: -2\n", msg: "max_depth"},
+		{name: "discovery filename", content: "discovery:\n  filenames: [../pilum.yaml]\n", msg: "relative to the service directory"},
+		{name: "defaults targets", content: "defaults:\n  targets: []\n", msg: "defaults cannot set 'targets'"},
+		{name: "empty group", content: "groups:\n  backend: []\n", msg: "has no services"},
+		{name: "watch paths", content: "watch_paths: ['libs/[z-a]']\n", msg: "watch_paths"},
+		{name: "unknown detector", content: "change_detection:\n  detectors: [nx]\n", msg: "unknown detector 'nx'"},
+		{name: "detector command", content: "change_detection:\n  detectors: [paths, command]\n", msg: "change_detection.command is required"},
+		{name: "unused detector command", content: "change_detection:\n  command: nx show projects --affected\n", msg: "command detector isn't enabled"},
+		{name: "source name dot", content: "recipe_sources:\n  - name: .\n    git: https://example.com/r.git\n", msg: "must be a single directory name"},
+		{name: "source name parent", content: "recipe_sources:\n  - name: ../../foo\n    git: https://example.com/r.git\n", msg: "must be a single directory name"},
+		{name: "source name backslash", content: "recipe_sources:\n  - name: 'a\\b'\n    git: https://example.com/r.git\n", msg: "must be a single directory name"},
+		{name: "group name", content: "groups:\n  '@backend': [api]\n", msg: "invalid group name"},
+		{name: "negated group name", content: "groups:\n  '!legacy': [api]\n", msg: "invalid group name"},
+		{name: "remote cache scheme", content: "cache:\n  remote:\n    url: s3://bucket\n", msg: "http or https URL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			writeWorkspace(t, dir, tt.content)
+
+			_, err := workspace.Load(dir)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}